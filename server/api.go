@@ -18,6 +18,15 @@ func (p *Plugin) InitAPI() *mux.Router {
 	r := mux.NewRouter()
 	r.HandleFunc("/triggerBuild", p.handleBuildTrigger).Methods("POST")
 	r.HandleFunc("/createJob", p.handleJobCreation).Methods("POST")
+	r.HandleFunc("/createJob/template", p.handleTemplateJobCreation).Methods("POST")
+	r.HandleFunc("/config", p.handleConfigSubmission).Methods("POST")
+	r.HandleFunc("/lint", p.handleLintSubmission).Methods("POST")
+	r.HandleFunc("/tail/stop", p.handleStopLogTail).Methods("POST")
+	r.HandleFunc("/queue/cancel", p.handleQueueCancel).Methods("POST")
+	r.HandleFunc("/replay", p.handleReplaySubmission).Methods("POST")
+	r.HandleFunc("/input/submit", p.handleInputSubmission).Methods("POST")
+	r.HandleFunc("/input/{action:proceed|abort}", p.handleInputButton).Methods("POST")
+	r.HandleFunc("/confirm/{decision:confirm|cancel}", p.handleConfirmationButton).Methods("POST")
 	r.HandleFunc("/assets/jenkins.png", p.handleProfileImage).Methods("GET")
 	return r
 }
@@ -91,6 +100,247 @@ func (p *Plugin) handleJobCreation(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (p *Plugin) handleTemplateJobCreation(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	if userID == "" {
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	var request model.SubmitDialogRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		p.API.LogError("failed to decode request", "err", err.Error())
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	response := &model.SubmitDialogResponse{}
+	msg, err := p.createJobFromTemplate(userID, request.ChannelId, request.State, request.Submission)
+	if err != nil {
+		p.API.LogError("Error creating job from template", "template", request.State, "err", err.Error())
+		if msg == "" {
+			msg = "Encountered an error while creating the job."
+		}
+	}
+	response.Error = msg
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func (p *Plugin) handleConfigSubmission(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	if userID == "" {
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	var request model.SubmitDialogRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		p.API.LogError("failed to decode request", "err", err.Error())
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	response := &model.SubmitDialogResponse{}
+	configXML, _ := request.Submission["ConfigXml"].(string)
+	msg, err := p.askConfigUpdateConfirmation(userID, request.ChannelId, request.State, configXML)
+	if err != nil {
+		p.API.LogError("Error comparing job configuration", "job_name", request.State, "err", err.Error())
+		if msg == "" {
+			msg = "Encountered an error while comparing the configuration."
+		}
+	}
+	if msg != "" {
+		response.Errors = map[string]string{"ConfigXml": msg}
+	}
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func (p *Plugin) handleLintSubmission(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	if userID == "" {
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	var request model.SubmitDialogRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		p.API.LogError("failed to decode request", "err", err.Error())
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	response := &model.SubmitDialogResponse{}
+	script, _ := request.Submission["Jenkinsfile"].(string)
+	if err := p.lintJenkinsfile(userID, request.ChannelId, "Jenkinsfile", script); err != nil {
+		p.API.LogError("Error validating Jenkinsfile", "err", err.Error())
+		response.Error = "Encountered an error while validating the Jenkinsfile."
+	}
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func (p *Plugin) handleStopLogTail(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	if userID == "" {
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	var request model.PostActionIntegrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		p.API.LogError("failed to decode request", "err", err.Error())
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	tailID, _ := request.Context["tail_id"].(string)
+	if tailID == "" {
+		http.Error(w, "Missing tail ID", http.StatusBadRequest)
+		return
+	}
+
+	startedBy, _ := request.Context["started_by"].(string)
+	response := &model.PostActionIntegrationResponse{}
+	msg, err := p.stopLogTail(tailID, startedBy, userID)
+	if err != nil {
+		p.API.LogError("Error stopping log tail", "err", err.Error())
+		msg = "Encountered an error while stopping to follow the log."
+	}
+	response.EphemeralText = msg
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func (p *Plugin) handleInputButton(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	if userID == "" {
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	var request model.PostActionIntegrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		p.API.LogError("failed to decode request", "err", err.Error())
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	response := &model.PostActionIntegrationResponse{}
+	msg, err := p.handleInputAction(userID, mux.Vars(r)["action"], &request)
+	if err != nil {
+		p.API.LogError("Error responding to input", "err", err.Error())
+		if msg == "" {
+			msg = "Encountered an error while responding to the input."
+		}
+	}
+	response.EphemeralText = msg
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func (p *Plugin) handleInputSubmission(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	if userID == "" {
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	var request model.SubmitDialogRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		p.API.LogError("failed to decode request", "err", err.Error())
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	response := &model.SubmitDialogResponse{}
+	msg, err := p.submitInput(userID, &request)
+	if err != nil {
+		p.API.LogError("Error submitting input", "err", err.Error())
+		if msg == "" {
+			msg = "Encountered an error while submitting the input."
+		}
+	}
+	response.Error = msg
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func (p *Plugin) handleQueueCancel(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	if userID == "" {
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	var request model.PostActionIntegrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		p.API.LogError("failed to decode request", "err", err.Error())
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	queueID, _ := request.Context["queue_id"].(string)
+	if queueID == "" {
+		http.Error(w, "Missing queue ID", http.StatusBadRequest)
+		return
+	}
+
+	response := &model.PostActionIntegrationResponse{}
+	msg, err := p.cancelQueueItems(userID, queueID)
+	if err != nil {
+		p.API.LogError("Error cancelling queue item", "queue_id", queueID, "err", err.Error())
+		msg = "Encountered an error while cancelling the queue item."
+	}
+	response.EphemeralText = msg
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func (p *Plugin) handleReplaySubmission(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	if userID == "" {
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	var request model.SubmitDialogRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		p.API.LogError("failed to decode request", "err", err.Error())
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	var state replayState
+	if err := json.Unmarshal([]byte(request.State), &state); err != nil {
+		p.API.LogError("failed to decode dialog state", "err", err.Error())
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	response := &model.SubmitDialogResponse{}
+	if err := p.replayBuild(userID, request.ChannelId, &state, request.Submission); err != nil {
+		p.API.LogError("Error replaying build", "job_name", state.JobName, "err", err.Error())
+		response.Error = "Encountered an error while replaying the build."
+	}
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func (p *Plugin) handleConfirmationButton(w http.ResponseWriter, r *http.Request) {
+	userID := r.Header.Get("Mattermost-User-ID")
+	if userID == "" {
+		http.Error(w, "Not authorized", http.StatusUnauthorized)
+		return
+	}
+
+	var request model.PostActionIntegrationRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		p.API.LogError("failed to decode request", "err", err.Error())
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+
+	update, err := p.handleConfirmation(userID, mux.Vars(r)["decision"], &request)
+	if err != nil {
+		p.API.LogError("Error running confirmed action", "err", err.Error())
+	}
+	_ = json.NewEncoder(w).Encode(&model.PostActionIntegrationResponse{Update: update})
+}
+
 func (p *Plugin) handleProfileImage(w http.ResponseWriter, r *http.Request) {
 	config := p.getConfiguration()
 
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/waseem18/gojenkins"
+)
+
+const (
+	bulkJobs = "bulk"
+
+	// bulkConcurrency is the number of jobs a bulk operation runs on at the same time.
+	bulkConcurrency = 4
+	// bulkFolderDepth is the number of nested folders searched for jobs matching a bulk operation.
+	bulkFolderDepth = 8
+	// maxBulkJobsListed is the maximum number of jobs listed in the confirmation of a bulk operation.
+	maxBulkJobsListed = 50
+)
+
+var bulkOperations = []string{"enable", "disable", "delete", "build"}
+
+// errBulkStopped is the error of the jobs a bulk operation didn't run on as the plugin has been deactivated.
+var errBulkStopped = errors.New("the plugin has been deactivated")
+
+// jenkinsItem is a job or folder with the items it contains.
+type jenkinsItem struct {
+	FullName string        `json:"fullName"`
+	Color    string        `json:"color"`
+	Jobs     []jenkinsItem `json:"jobs"`
+}
+
+// bulkResult is the outcome of a bulk operation on a job.
+type bulkResult struct {
+	JobName string
+	Err     error
+	Note    string
+}
+
+// jobsTreeQuery returns the tree query fetching the jobs of the given number of nested folders.
+func jobsTreeQuery(depth int) string {
+	query := "jobs[fullName,color]"
+	for i := 1; i < depth; i++ {
+		query = fmt.Sprintf("jobs[fullName,color,%s]", query)
+	}
+	return query
+}
+
+// collectJobNames returns the full names of the jobs matching the glob pattern, sorted by name.
+// Folders aren't jobs, they have no color.
+func collectJobNames(items []jenkinsItem, pattern string) []string {
+	names := []string{}
+	var collect func(items []jenkinsItem)
+	collect = func(items []jenkinsItem) {
+		for _, item := range items {
+			if item.Color != "" && matchJobGlob(pattern, item.FullName) {
+				names = append(names, item.FullName)
+			}
+			collect(item.Jobs)
+		}
+	}
+	collect(items)
+	sort.Strings(names)
+	return names
+}
+
+// formatBulkConfirmation renders the list of jobs a bulk operation is about to run on.
+func formatBulkConfirmation(operation, pattern string, jobNames []string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d job(s) match `%s`:\n", len(jobNames), pattern)
+	for i, name := range jobNames {
+		if i == maxBulkJobsListed {
+			fmt.Fprintf(&sb, "* ... and %d more\n", len(jobNames)-maxBulkJobsListed)
+			break
+		}
+		fmt.Fprintf(&sb, "* %s\n", name)
+	}
+	if operation != "" {
+		fmt.Fprintf(&sb, "\n**%s** these jobs?", strings.ToUpper(operation[:1])+operation[1:])
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// formatBulkSummary renders the summary post of a bulk operation. If there are too many jobs
+// to list them all, only the failures are listed.
+func formatBulkSummary(operation, pattern string, results []bulkResult) string {
+	failed := 0
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+		}
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Bulk %s of the jobs matching `%s`: %d succeeded, %d failed.\n", operation, pattern, len(results)-failed, failed)
+	onlyFailures := len(results) > maxBulkJobsListed
+	listed := 0
+	for _, r := range results {
+		if onlyFailures && r.Err == nil {
+			continue
+		}
+		if listed == maxBulkJobsListed {
+			fmt.Fprintf(&sb, "* ... and %d more failures\n", failed-listed)
+			break
+		}
+		listed++
+		switch {
+		case r.Err != nil:
+			fmt.Fprintf(&sb, "* :x: %s - %s\n", r.JobName, r.Err.Error())
+		case r.Note != "":
+			fmt.Fprintf(&sb, "* :white_check_mark: %s - %s\n", r.JobName, r.Note)
+		default:
+			fmt.Fprintf(&sb, "* :white_check_mark: %s\n", r.JobName)
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// runBulk runs the function on the jobs with at most bulkConcurrency jobs at the same time.
+// Once stop is closed, no more jobs are started and the remaining ones fail with errBulkStopped.
+// The results are in the order of the jobs.
+func runBulk(jobNames []string, stop <-chan struct{}, run func(jobName string) (string, error)) []bulkResult {
+	results := make([]bulkResult, len(jobNames))
+	sem := make(chan struct{}, bulkConcurrency)
+	var wg sync.WaitGroup
+	for i, name := range jobNames {
+		select {
+		case sem <- struct{}{}:
+		case <-stop:
+		}
+		select {
+		case <-stop:
+			// A slot may have been acquired at the same time as stop was closed.
+			results[i] = bulkResult{JobName: name, Err: errBulkStopped}
+			continue
+		default:
+		}
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			note, err := run(name)
+			results[i] = bulkResult{JobName: name, Note: note, Err: err}
+		}(i, name)
+	}
+	wg.Wait()
+	return results
+}
+
+// runBulkOperation runs a single operation of a bulk command on a job.
+func runBulkOperation(jenkins *gojenkins.Jenkins, operation, jobName string) (note string, err error) {
+	defer recoverJenkinsPanic(&err)
+	job := &gojenkins.Job{Jenkins: jenkins, Raw: new(gojenkins.JobResponse), Base: "/job/" + jenkinsJobPath(jobName)}
+	switch operation {
+	case "enable":
+		_, err = job.Enable()
+	case "disable":
+		_, err = job.Disable()
+	case "delete":
+		_, err = job.Delete()
+	case "build":
+		var queueID int64
+		queueID, err = job.InvokeSimple(nil)
+		if err == nil && queueID == 0 {
+			return "a build is already in the queue", nil
+		}
+	default:
+		err = errors.Errorf("unknown operation %q", operation)
+	}
+	return "", err
+}
+
+// getMatchingJobs fetches the jobs across nested folders matching the glob pattern.
+func getMatchingJobs(jenkins *gojenkins.Jenkins, pattern string) ([]string, error) {
+	root := jenkinsItem{}
+	resp, err := jenkins.Requester.GetJSON("/", &root, map[string]string{"tree": jobsTreeQuery(bulkFolderDepth)})
+	if err != nil {
+		return nil, errors.Wrap(err, "Error fetching jobs")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("Error fetching jobs: %s", resp.Status)
+	}
+	return collectJobNames(root.Jobs, pattern), nil
+}
+
+// askBulkConfirmation lists the jobs matching a bulk command and, unless it's a dry run,
+// asks the user to confirm the operation.
+func (p *Plugin) askBulkConfirmation(userID, channelID, operation, pattern string, dryRun bool) error {
+	jenkins, err := p.getJenkinsClient(userID)
+	if err != nil {
+		return errors.Wrap(err, "Error creating Jenkins client")
+	}
+	jobNames, err := getMatchingJobs(jenkins, pattern)
+	if err != nil {
+		return err
+	}
+	if len(jobNames) == 0 {
+		p.createEphemeralPost(userID, channelID, fmt.Sprintf("No jobs match `%s`.", pattern))
+		return nil
+	}
+	if dryRun {
+		p.createEphemeralPost(userID, channelID, "Dry run. "+formatBulkConfirmation("", pattern, jobNames))
+		return nil
+	}
+
+	p.askConfirmation(userID, channelID, &confirmationRequest{
+		Message: formatBulkConfirmation(operation, pattern, jobNames),
+		Action:  bulkJobs,
+		Args: map[string]string{
+			"operation": operation,
+			"pattern":   pattern,
+			"jobs":      strings.Join(jobNames, "\n"),
+		},
+	})
+	return nil
+}
+
+// runBulkJobs runs a confirmed bulk operation in the background and posts its summary once it has finished.
+// Returns a message describing the outcome.
+func (p *Plugin) runBulkJobs(userID, channelID, operation, pattern string, jobNames []string) (string, error) {
+	if !containsString(bulkOperations, operation) {
+		return "", errors.Errorf("unknown operation %q", operation)
+	}
+	jenkins, err := p.getJenkinsClient(userID)
+	if err != nil {
+		return "", errors.Wrap(err, "Error creating Jenkins client")
+	}
+
+	go func() {
+		results := runBulk(jobNames, p.stopped(), func(jobName string) (string, error) {
+			note, err := runBulkOperation(jenkins, operation, jobName)
+			if err != nil {
+				p.API.LogWarn("Error running bulk operation", "operation", operation, "job_name", jobName, "err", err.Error())
+			}
+			return note, err
+		})
+		select {
+		case <-p.stopped():
+			return
+		default:
+		}
+		p.createPost(userID, channelID, formatBulkSummary(operation, pattern, results))
+	}()
+	return fmt.Sprintf("Running %s on %d job(s)...", operation, len(jobNames)), nil
+}
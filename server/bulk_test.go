@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/waseem18/gojenkins"
+)
+
+const testJobsTree = `{"jobs": [
+	{"fullName": "build-app", "color": "blue"},
+	{"fullName": "team", "jobs": [
+		{"fullName": "team/deploy-prod", "color": "disabled"},
+		{"fullName": "team/services", "jobs": [
+			{"fullName": "team/services/deploy-api", "color": "red"},
+			{"fullName": "team/services/lint", "color": "notbuilt"}
+		]},
+		{"fullName": "team/app", "jobs": [
+			{"fullName": "team/app/main", "color": "blue_anime"}
+		]}
+	]}
+]}`
+
+func TestJobsTreeQuery(t *testing.T) {
+	assert.Equal(t, "jobs[fullName,color]", jobsTreeQuery(1))
+	assert.Equal(t, "jobs[fullName,color,jobs[fullName,color,jobs[fullName,color]]]", jobsTreeQuery(3))
+}
+
+func TestCollectJobNames(t *testing.T) {
+	var root jenkinsItem
+	require.Nil(t, json.Unmarshal([]byte(testJobsTree), &root))
+
+	for pattern, expected := range map[string][]string{
+		"*":             {"build-app"},
+		"team/*":        {"team/deploy-prod"},
+		"team/**":       {"team/app/main", "team/deploy-prod", "team/services/deploy-api", "team/services/lint"},
+		"**/deploy-*":   {"team/deploy-prod", "team/services/deploy-api"},
+		"other/**":      {},
+		"team/app/mai?": {"team/app/main"},
+	} {
+		assert.Equal(t, expected, collectJobNames(root.Jobs, pattern), pattern)
+	}
+}
+
+func TestFormatBulkConfirmation(t *testing.T) {
+	assert.Equal(t, "2 job(s) match `team/*`:\n* team/a\n* team/b\n\n**Disable** these jobs?",
+		formatBulkConfirmation("disable", "team/*", []string{"team/a", "team/b"}))
+
+	names := []string{}
+	for i := 0; i < maxBulkJobsListed+5; i++ {
+		names = append(names, fmt.Sprintf("job-%d", i))
+	}
+	msg := formatBulkConfirmation("", "*", names)
+	assert.Contains(t, msg, "* job-49\n* ... and 5 more")
+	assert.NotContains(t, msg, "these jobs?")
+}
+
+func TestFormatBulkSummary(t *testing.T) {
+	results := []bulkResult{
+		{JobName: "team/a"},
+		{JobName: "team/b", Err: errors.New("403")},
+		{JobName: "team/c", Note: "a build is already in the queue"},
+	}
+	assert.Equal(t, "Bulk build of the jobs matching `team/*`: 2 succeeded, 1 failed.\n"+
+		"* :white_check_mark: team/a\n* :x: team/b - 403\n* :white_check_mark: team/c - a build is already in the queue",
+		formatBulkSummary("build", "team/*", results))
+
+	results = []bulkResult{}
+	for i := 0; i < maxBulkJobsListed+1; i++ {
+		results = append(results, bulkResult{JobName: fmt.Sprintf("job-%d", i)})
+	}
+	results[3].Err = errors.New("404")
+	assert.Equal(t, "Bulk delete of the jobs matching `*`: 50 succeeded, 1 failed.\n* :x: job-3 - 404", formatBulkSummary("delete", "*", results))
+}
+
+func TestRunBulk(t *testing.T) {
+	var running, maxRunning int32
+	names := []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"}
+	results := runBulk(names, nil, func(jobName string) (string, error) {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			m := atomic.LoadInt32(&maxRunning)
+			if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		if jobName == "c" {
+			return "", errors.New("failed")
+		}
+		return "", nil
+	})
+
+	require.Len(t, results, len(names))
+	for i, r := range results {
+		assert.Equal(t, names[i], r.JobName)
+	}
+	assert.NotNil(t, results[2].Err)
+	assert.LessOrEqual(t, maxRunning, int32(bulkConcurrency))
+
+	stop := make(chan struct{})
+	close(stop)
+	results = runBulk(names, stop, func(string) (string, error) { return "", nil })
+	require.Len(t, results, len(names))
+	for i, r := range results {
+		assert.Equal(t, names[i], r.JobName)
+		assert.Equal(t, errBulkStopped, r.Err)
+	}
+}
+
+func TestRunBulkOperationUnreachable(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	testServer.Close()
+	jenkins := gojenkins.CreateJenkins(nil, testServer.URL)
+
+	for _, operation := range bulkOperations {
+		_, err := runBulkOperation(jenkins, operation, "app")
+		assert.Error(t, err, operation)
+	}
+}
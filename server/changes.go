@@ -0,0 +1,250 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+	"github.com/waseem18/gojenkins"
+)
+
+const (
+	// maxChangesListed is the maximum number of commits listed in the changes of a build.
+	maxChangesListed = 20
+	// shortCommitIDLength is the length of the abbreviated commit IDs.
+	shortCommitIDLength = 7
+
+	// changesTreeQuery selects the changes and culprits of a build. Pipeline builds report
+	// changeSets, freestyle builds of older Jenkins versions only report changeSet.
+	changesTreeQuery = "number,changeSets[kind,items[commitId,msg,authorEmail,author[fullName,absoluteUrl],affectedPaths]]," +
+		"changeSet[kind,items[commitId,msg,authorEmail,author[fullName,absoluteUrl],affectedPaths]]," +
+		"culprits[fullName,absoluteUrl]"
+)
+
+// jenkinsUser is a user of Jenkins, such as the author of a commit or a culprit of a build.
+type jenkinsUser struct {
+	FullName    string `json:"fullName"`
+	AbsoluteURL string `json:"absoluteUrl"`
+}
+
+// ID returns the ID of the user, which is the last segment of its URL.
+func (u jenkinsUser) ID() string {
+	if u.AbsoluteURL == "" {
+		return ""
+	}
+	id, err := url.PathUnescape(path.Base(strings.TrimSuffix(u.AbsoluteURL, "/")))
+	if err != nil {
+		return ""
+	}
+	return id
+}
+
+// changeSetItem is a commit built by a build.
+type changeSetItem struct {
+	CommitID      string      `json:"commitId"`
+	Msg           string      `json:"msg"`
+	AuthorEmail   string      `json:"authorEmail"`
+	Author        jenkinsUser `json:"author"`
+	AffectedPaths []string    `json:"affectedPaths"`
+}
+
+// changeSet is the list of commits of a repository built by a build.
+type changeSet struct {
+	Kind  string          `json:"kind"`
+	Items []changeSetItem `json:"items"`
+}
+
+// buildChanges are the changes and culprits of a build.
+type buildChanges struct {
+	Number     int64         `json:"number"`
+	ChangeSets []changeSet   `json:"changeSets"`
+	ChangeSet  changeSet     `json:"changeSet"`
+	Culprits   []jenkinsUser `json:"culprits"`
+}
+
+// commits returns the commits of all the repositories built by the build.
+func (c *buildChanges) commits() []changeSetItem {
+	if len(c.ChangeSets) == 0 {
+		return c.ChangeSet.Items
+	}
+	commits := []changeSetItem{}
+	for _, set := range c.ChangeSets {
+		commits = append(commits, set.Items...)
+	}
+	return commits
+}
+
+// userMentioner returns the mention of the Mattermost user matching a Jenkins user and email,
+// or an empty string if there is no matching user.
+type userMentioner func(user jenkinsUser, email string) string
+
+// parseUserMapping parses the configured mapping of Jenkins users to Mattermost usernames,
+// one jenkins-user=mattermost-username per line. Jenkins users are matched by ID or full name.
+func parseUserMapping(mapping string) (map[string]string, error) {
+	users := map[string]string{}
+	for _, line := range strings.Split(mapping, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		jenkinsUser, username, ok := strings.Cut(line, "=")
+		jenkinsUser = strings.TrimSpace(jenkinsUser)
+		username = strings.TrimPrefix(strings.TrimSpace(username), "@")
+		if !ok || jenkinsUser == "" || username == "" {
+			return nil, errors.Errorf("invalid user mapping %q", line)
+		}
+		users[jenkinsUser] = username
+	}
+	return users, nil
+}
+
+// shortCommitID abbreviates a commit ID. Revision numbers are kept as they are.
+func shortCommitID(commitID string) string {
+	if len(commitID) > shortCommitIDLength {
+		return commitID[:shortCommitIDLength]
+	}
+	return commitID
+}
+
+// formatUser returns the mention of a Jenkins user, or its full name if it has no matching Mattermost user.
+func formatUser(user jenkinsUser, email string, mention userMentioner) string {
+	if m := mention(user, email); m != "" {
+		return m
+	}
+	if user.FullName != "" {
+		return user.FullName
+	}
+	return email
+}
+
+// formatChanges renders the commits and culprits of a build.
+func formatChanges(changes *buildChanges, mention userMentioner) string {
+	commits := changes.commits()
+	var sb strings.Builder
+	if len(commits) == 0 {
+		sb.WriteString("No changes.\n")
+	} else {
+		fmt.Fprintf(&sb, "%d change(s):\n", len(commits))
+	}
+	for i, commit := range commits {
+		if i == maxChangesListed {
+			fmt.Fprintf(&sb, "* ... and %d more\n", len(commits)-maxChangesListed)
+			break
+		}
+		message, _, _ := strings.Cut(strings.TrimSpace(commit.Msg), "\n")
+		fmt.Fprintf(&sb, "* `%s` %s - %s (%d path(s))\n", shortCommitID(commit.CommitID), message,
+			formatUser(commit.Author, commit.AuthorEmail, mention), len(commit.AffectedPaths))
+	}
+
+	if len(changes.Culprits) > 0 {
+		culprits := make([]string, 0, len(changes.Culprits))
+		for _, culprit := range changes.Culprits {
+			culprits = append(culprits, formatUser(culprit, "", mention))
+		}
+		fmt.Fprintf(&sb, "Culprits: %s\n", strings.Join(culprits, ", "))
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// getBuildChanges fetches the changes and culprits of a build.
+func getBuildChanges(build *gojenkins.Build) (*buildChanges, error) {
+	changes := &buildChanges{}
+	resp, err := build.Jenkins.Requester.GetJSON(build.Base, changes, map[string]string{"tree": changesTreeQuery})
+	if err != nil {
+		return nil, errors.Wrap(err, "Error fetching the changes of the build")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("Error fetching the changes of the build: %s", resp.Status)
+	}
+	return changes, nil
+}
+
+// getJenkinsUserEmail fetches the email address a Jenkins user has set in its profile.
+func getJenkinsUserEmail(jenkins *gojenkins.Jenkins, userID string) (string, error) {
+	user := struct {
+		Property []struct {
+			Address string `json:"address"`
+		} `json:"property"`
+	}{}
+	resp, err := jenkins.Requester.GetJSON("/user/"+url.PathEscape(userID), &user, map[string]string{"tree": "property[address]"})
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.Errorf("Error fetching the Jenkins user: %s", resp.Status)
+	}
+	for _, property := range user.Property {
+		if property.Address != "" {
+			return property.Address, nil
+		}
+	}
+	return "", nil
+}
+
+// findMattermostUser returns the Mattermost user matching a Jenkins user, or nil if there is none.
+// Users are matched with the configured user mapping, then with the Jenkins accounts they connected
+// or linked with /jenkins link-user, then by email.
+// Users without an email are looked up in Jenkins for the address of their profile.
+func (p *Plugin) findMattermostUser(jenkins *gojenkins.Jenkins, user jenkinsUser, email string) *model.User {
+	mapping := p.getConfiguration().userMapping
+	for _, name := range []string{user.ID(), user.FullName} {
+		if username, ok := mapping[name]; ok && name != "" {
+			if mmUser, appErr := p.API.GetUserByUsername(username); appErr == nil {
+				return mmUser
+			}
+		}
+	}
+	if mmUser := p.getLinkedUser(user.ID()); mmUser != nil {
+		return mmUser
+	}
+	if email == "" && user.ID() != "" {
+		var err error
+		if email, err = getJenkinsUserEmail(jenkins, user.ID()); err != nil {
+			p.API.LogWarn("Error fetching the email of a Jenkins user", "user", user.ID(), "err", err.Error())
+		}
+	}
+	if email != "" {
+		if mmUser, appErr := p.API.GetUserByEmail(email); appErr == nil {
+			return mmUser
+		}
+	}
+	return nil
+}
+
+// newUserMentioner returns a userMentioner mentioning the Mattermost users matching Jenkins users,
+// unless they have opted out of mentions. The results are cached for the lifetime of the mentioner.
+func (p *Plugin) newUserMentioner(jenkins *gojenkins.Jenkins) userMentioner {
+	cache := map[string]string{}
+	return func(user jenkinsUser, email string) string {
+		key := user.ID() + "\n" + user.FullName + "\n" + email
+		if mention, ok := cache[key]; ok {
+			return mention
+		}
+		mention := ""
+		if mmUser := p.findMattermostUser(jenkins, user, email); mmUser != nil && !p.hasOptedOutOfMentions(mmUser.Id) {
+			mention = "@" + mmUser.Username
+		}
+		cache[key] = mention
+		return mention
+	}
+}
+
+// postBuildChanges posts the changes and culprits of a build.
+// If build number is not specified, the changes of the last build of the job are posted.
+func (p *Plugin) postBuildChanges(userID, channelID, jobName, buildID string) error {
+	build, err := p.getBuild(jobName, userID, buildID)
+	if err != nil {
+		return err
+	}
+	changes, err := getBuildChanges(build)
+	if err != nil {
+		return err
+	}
+	message := fmt.Sprintf("Changes of job '%s' - #%d\n%s", jobName, build.GetBuildNumber(), formatChanges(changes, p.newUserMentioner(build.Jenkins)))
+	p.createPost(userID, channelID, message)
+	return nil
+}
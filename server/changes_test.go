@@ -0,0 +1,116 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/waseem18/gojenkins"
+)
+
+const testBuildChanges = `{
+  "number": 42,
+  "changeSets": [
+    {
+      "kind": "git",
+      "items": [
+        {
+          "commitId": "3f2a9c1d8e7b6a5f4e3d2c1b0a9f8e7d6c5b4a39",
+          "msg": "Fix the login redirect",
+          "authorEmail": "alice@example.com",
+          "author": {"fullName": "Alice Doe", "absoluteUrl": "https://jenkins.example.com/user/alice"},
+          "affectedPaths": ["src/login.go", "src/login_test.go"]
+        }
+      ]
+    },
+    {
+      "kind": "git",
+      "items": [
+        {
+          "commitId": "b4c5d6e7f8a9b0c1d2e3f4a5b6c7d8e9f0a1b2c3",
+          "msg": "Bump the version",
+          "authorEmail": "bob@example.com",
+          "author": {"fullName": "Bob Smith", "absoluteUrl": "https://jenkins.example.com/user/bob.smith"},
+          "affectedPaths": ["VERSION"]
+        }
+      ]
+    }
+  ],
+  "culprits": [
+    {"fullName": "Alice Doe", "absoluteUrl": "https://jenkins.example.com/user/alice"},
+    {"fullName": "Bob Smith", "absoluteUrl": "https://jenkins.example.com/user/bob.smith"}
+  ]
+}`
+
+func TestJenkinsUserID(t *testing.T) {
+	assert.Equal(t, "alice", jenkinsUser{AbsoluteURL: "https://jenkins.example.com/user/alice"}.ID())
+	assert.Equal(t, "john doe", jenkinsUser{AbsoluteURL: "https://jenkins.example.com/user/john%20doe/"}.ID())
+	assert.Equal(t, "", jenkinsUser{FullName: "noreply"}.ID())
+}
+
+func TestParseUserMapping(t *testing.T) {
+	mapping, err := parseUserMapping("alice = alice.doe\n\n  Bob Smith=@bob\n")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"alice": "alice.doe", "Bob Smith": "bob"}, mapping)
+
+	mapping, err = parseUserMapping("")
+	require.NoError(t, err)
+	assert.Empty(t, mapping)
+
+	for _, invalid := range []string{"alice", "=alice", "alice=", "alice=@"} {
+		_, err = parseUserMapping(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestShortCommitID(t *testing.T) {
+	assert.Equal(t, "3f2a9c1", shortCommitID("3f2a9c1d8e7b6a5f4e3d2c1b0a9f8e7d6c5b4a39"))
+	assert.Equal(t, "1234", shortCommitID("1234"))
+}
+
+func TestFormatChanges(t *testing.T) {
+	changes := &buildChanges{}
+	require.NoError(t, json.Unmarshal([]byte(testBuildChanges), changes))
+	mention := func(user jenkinsUser, email string) string {
+		if user.ID() == "alice" || email == "alice@example.com" {
+			return "@alice.doe"
+		}
+		return ""
+	}
+
+	assert.Equal(t, "2 change(s):\n"+
+		"* `3f2a9c1` Fix the login redirect - @alice.doe (2 path(s))\n"+
+		"* `b4c5d6e` Bump the version - Bob Smith (1 path(s))\n"+
+		"Culprits: @alice.doe, Bob Smith", formatChanges(changes, mention))
+
+	// Freestyle builds of older Jenkins versions only report changeSet.
+	changes = &buildChanges{ChangeSet: changeSet{Kind: "svn", Items: []changeSetItem{
+		{CommitID: "1234", Msg: "Update the docs\n\nDetails", AuthorEmail: "carol@example.com", AffectedPaths: []string{"README"}},
+	}}}
+	assert.Equal(t, "1 change(s):\n* `1234` Update the docs - carol@example.com (1 path(s))", formatChanges(changes, mention))
+
+	assert.Equal(t, "No changes.", formatChanges(&buildChanges{}, mention))
+}
+
+func TestFormatChangesTruncated(t *testing.T) {
+	changes := &buildChanges{}
+	for i := 0; i < maxChangesListed+3; i++ {
+		changes.ChangeSet.Items = append(changes.ChangeSet.Items, changeSetItem{
+			CommitID: fmt.Sprintf("%040d", i),
+			Msg:      fmt.Sprintf("Change %d", i),
+			Author:   jenkinsUser{FullName: "Alice Doe"},
+		})
+	}
+	formatted := formatChanges(changes, func(jenkinsUser, string) string { return "" })
+	assert.Contains(t, formatted, "23 change(s):\n")
+	assert.Contains(t, formatted, "Change 19 - Alice Doe (0 path(s))\n* ... and 3 more")
+	assert.NotContains(t, formatted, "Change 20")
+}
+
+func TestFormatBuildCompletion(t *testing.T) {
+	build := &gojenkins.BuildResponse{Number: 42, Result: "FAILURE", Duration: 185000, URL: "https://jenkins.example.com/job/app/42/"}
+	assert.Equal(t, "Job 'app' - #42 has finished: FAILURE in 3m 5s\nBuild URL : https://jenkins.example.com/job/app/42/",
+		formatBuildCompletion("app", build))
+}
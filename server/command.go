@@ -14,9 +14,20 @@ const helpText = `
 ###### Connect and disconnect with Jenkins server
 * |/jenkins connect username APIToken| - Connect your Mattermost account to Jenkins.
 * |/jenkins disconnect| - Disconnect your Mattermost account with Jenkins.
+* |/jenkins link-user jenkins-id| - Link your connected Jenkins account to your account, so you are mentioned for its changes. System admins can link any Jenkins user ID.
+* |/jenkins unlink-user jenkins-id| - Remove the link of a Jenkins user ID.
+* |/jenkins mentions on/off| - Choose whether you are mentioned in the posts of failed builds and in the changes of builds.
 
 ###### Interact with Jenkins jobs
 * |/jenkins createjob| - Create a job using config.xml.
+* |/jenkins createjob --template <name>| - Create a job from a config.xml template, filling in its variables in a dialog.
+* |/jenkins new-pipeline <folder/name> <git-url> [--branch main] [--script-path Jenkinsfile] [--credentials id] [--build]| - Create a Pipeline job running the Jenkinsfile of a Git repository.
+  * Missing folders are created. |--credentials| is the ID of the Jenkins credentials used to check out the repository, and |--build| triggers the first build.
+* |/jenkins template list| - List the config.xml templates with their variables.
+* |/jenkins template add <name> <post link>| - Add the config.xml attached to a post as template, or replace the template with the same name.
+  * Templates use Go template placeholders, e.g. |{{.RepoURL}}| or |{{.Branch}}|. Values are escaped for XML.
+* |/jenkins template remove <name>| - Remove a template.
+  * Only system admins can add and remove templates.
 * |/jenkins build jobname| - Trigger a build for the given job.
   * If the job resides in a folder, specify the job as |folder1/jobname|. Note the slash character.
   * If the folder name or job name has spaces in it, wrap the jobname in double quotes as |"job name with space"| or |"folder with space/jobname"|.
@@ -27,16 +38,78 @@ const helpText = `
 * |/jenkins enable jobname| - Enanble a given job.
 * |/jenkins disable jobname| - Disable a given job.
 * |/jenkins delete jobname| - Deletes a given job.
+* |/jenkins config get jobname| - Upload the config.xml of the given job as a file.
+* |/jenkins config edit jobname| - Edit the config.xml of the given job in a dialog.
+  * The changes are shown as a diff to be confirmed before the job is updated. Errors returned by Jenkins are shown in the confirmation post.
+* |/jenkins copy source destination| - Copy a job. The copy is disabled only if the source is disabled.
+* |/jenkins rename jobname newname| - Rename a job within its folder.
+* |/jenkins move jobname folder| - Move a job to another folder. Use |/| as folder to move it to the top level.
+  * Wrap the names in double quotes if they have spaces in them, e.g. |/jenkins copy "folder/job name" "other folder/job name"|. Missing destination folders are created.
+* |/jenkins bulk <operation> glob [--dry-run]| - Run an operation on all jobs matching a glob pattern. The operation is one of |enable|, |disable|, |delete| or |build|.
+  * |*| matches a name within a folder and |**| any number of nested folders, e.g. |team/**| or |"**/deploy-*"|.
+  * The matching jobs are listed with a button to confirm the operation. |--dry-run| only lists them.
 * |/jenkins get-artifacts jobname| - Get artifacts of the last build of the given job.
-* |/jenkins test-results jobname| - Get test results of the last build of the given job.
-* |/jenkins get-log jobname <build number>| - Get build log of a given job. Build number is optional.
+* |/jenkins test-results jobname <build number>| - Get a summary of the test results of a build of the given job.
+* |/jenkins changes jobname <build number>| - List the commits and culprits of a build of the given job.
+  * If build number is not specified, the command summarizes the test results of the last build.
+* |/jenkins test-diff jobname <build A> <build B>| - Compare the test results of two builds of the given job.
+  * If the build numbers are not specified, the command compares the last successful build with the last build.
+* |/jenkins flaky jobname| - List the flakiest tests of the given job.
+  * Tests which flip between passing and failing within the recent builds are considered flaky.
+* |/jenkins get-log jobname <build number> [--tail N | --grep regex | --errors]| - Get build log of a given job. Build number is optional.
   * If build number is not specified, the command fetches the log of the last build.
+  * |--tail N| gets the last N lines, |--grep regex| the lines matching a regular expression and |--errors| the lines around errors, failures and exceptions.
+  * Small logs are posted inline, larger ones are uploaded as a file.
+* |/jenkins stages jobname <build number>| - Show the stages of a Pipeline build with their status and duration.
+  * If build number is not specified, the command shows the stages of the last build.
+* |/jenkins stage-log jobname <build number> <stage name>| - Get the log of a single stage of a Pipeline build.
+* |/jenkins inputs jobname <build number>| - Post the input steps a Pipeline build waits on, with buttons to proceed or abort.
+  * If build number is not specified, the command checks the last build.
+  * Responses are sent to Jenkins with your own Jenkins account. Input parameters are filled in a dialog.
+* |/jenkins replay jobname <build number>| - Replay a Pipeline build with an edited Jenkinsfile.
+  * A dialog pre-filled with the main script and the loaded scripts of the build is opened.
+  * If build number is not specified, the last build is replayed.
+* |/jenkins lint [post link]| - Validate a declarative Jenkinsfile and post the errors with their line numbers.
+  * Without a post link, a dialog is opened to paste the Jenkinsfile. Otherwise, the file attached to the post is validated.
+* |/jenkins branches project| - List the branches and pull requests of a multibranch project with their last result.
+  * Branches are used as jobs with |project@branch|, e.g. |/jenkins build project@feature/x|.
+* |/jenkins scan project| - Scan the repository of a multibranch project for branches and report when the scan has finished.
+* |/jenkins queue [--job glob]| - List the items of the build queue with their wait time, the reason they are waiting and their parameters.
+  * |--job| only lists the items of the jobs matching a glob pattern, e.g. |--job "folder/*"|.
+* |/jenkins queue cancel <id or jobname>| - Cancel the queue item with the given ID, or all queue items of the given job.
+* |/jenkins tail jobname <build number>| - Follow the console log of a running build in a thread.
+  * If build number is not specified, the command follows the log of the last build.
+  * New output is posted every few seconds until the build finishes or the Stop button is clicked.
+* |/jenkins history jobname [--last N] [--result failure] [--since 2d] [--user username]| - Show a table of recent builds of a given job.
+  * |--last| limits the number of builds shown (default 10), |--result| filters by result, |--since| filters by age and |--user| by the Jenkins user who triggered the build.
+
+###### Manage Jenkins nodes
+* |/jenkins nodes [--label x] [--offline]| - List the nodes with their status, busy executors, labels, free disk space, clock drift and response time.
+  * |--label| only lists the nodes with the given label and |--offline| only the offline nodes.
+* |/jenkins node offline <name> <reason>| - Take a node offline with a reason. Only available to system admins.
+* |/jenkins node online <name>| - Bring a node taken offline back online. Only available to system admins.
+* |/jenkins node disconnect <name> [reason]| - Disconnect the agent of a node. Only available to system admins.
+  * If the node name has spaces in it, wrap it in double quotes.
 
 ###### Interact with Plugins
-* |/jenkins plugins| - Get a list of installed plugins on the Jenkins server.
+* |/jenkins plugins [--updates] [--disabled] [--filter x]| - Get a report of the installed plugins with their updates, security warnings and dependency problems.
+  * |--updates| only lists the plugins with updates, |--disabled| the disabled plugins and |--filter| the plugins whose name contains the given text.
+* |/jenkins plugins install <shortName>[@version]| - Install the latest version of a plugin. A version is only accepted if it is the latest one.
+* |/jenkins plugins update --all| - Update all plugins with an update.
+* |/jenkins plugins enable <shortName>| - Enable a plugin.
+* |/jenkins plugins disable <shortName>| - Disable a plugin.
+  * Only system admins can manage plugins, and each action is confirmed with a button. A post tells whether Jenkins has to be restarted.
 
 ###### Adhoc Commands
-* |/jenkins safe-restart| - Safe restarts the Jenkins server.
+* |/jenkins safe-restart| - Safe restarts the Jenkins server. Same as |/jenkins restart --safe|.
+* |/jenkins quiet-down [reason]| - Prepare Jenkins for shutdown, so that no new builds are started.
+* |/jenkins cancel-quiet-down| - Cancel the preparation of Jenkins for shutdown.
+* |/jenkins restart --safe| or |/jenkins restart --force| - Restart Jenkins once the running builds have finished, or right away.
+  * A post is created once Jenkins is back up.
+* |/jenkins exit| - Shut down Jenkins right away.
+  * Only system admins can quiet down, restart or shut down Jenkins, and each action is confirmed with a button.
+* |/jenkins health| - Show whether Jenkins is up, degraded or down, and its recent state changes.
+  * Jenkins is checked in the background. Outages and recoveries are posted to the admin channel.
 * |/jenkins me| - Display the connected Jenkins account.
 * |/jenkins help| - Find help related to the syntax of the slash commands.
 `
@@ -55,7 +128,7 @@ func (p *Plugin) getCommand() (*model.Command, error) {
 		Description:          "A Mattermost plugin to interact with Jenkins",
 		DisplayName:          "Jenkins",
 		AutoComplete:         true,
-		AutoCompleteDesc:     "Available commands: connect, disconnect, me, build, get-artifacts, test-results, get-log, abort, disable, enable, delete, safe-restart, plugins, createjob, help",
+		AutoCompleteDesc:     "Available commands: connect, disconnect, link-user, unlink-user, mentions, me, build, get-artifacts, test-results, changes, test-diff, flaky, get-log, stages, stage-log, inputs, replay, branches, scan, queue, tail, history, nodes, node, health, abort, disable, enable, delete, safe-restart, quiet-down, cancel-quiet-down, restart, exit, plugins, createjob, new-pipeline, config, copy, rename, move, bulk, lint, template, help",
 		AutoCompleteHint:     "[command]",
 		AutocompleteData:     getAutocompleteData(),
 		AutocompleteIconData: iconData,
@@ -71,7 +144,56 @@ func getAutocompleteData() *model.AutocompleteData {
 
 	disconnect := model.NewAutocompleteData("disconnect", "", "Disconnect your Mattermost account from your Jenkins account")
 
-	createjob := model.NewAutocompleteData("createjob", "", "Create a Jenkins job using the contents of a config.xml file")
+	createjob := model.NewAutocompleteData("createjob", "[--template name]", "Create a Jenkins job using the contents of a config.xml file")
+	createjob.AddNamedTextArgument("template", "Name of the config.xml template to create the job from", "[name]", "", false)
+
+	newPipeline := model.NewAutocompleteData("new-pipeline", "[folder/name] [git-url] [--build]", "Create a Pipeline job running the Jenkinsfile of a Git repository")
+	newPipeline.AddTextArgument("folder1/jobname if the job is in a folder, or \"job with space\"", "[folder/name]", "")
+	newPipeline.AddTextArgument("URL of the Git repository", "[git-url]", "")
+	newPipeline.AddNamedTextArgument("branch", "Branch to build, main by default", "[branch]", "", false)
+	newPipeline.AddNamedTextArgument("script-path", "Path of the Jenkinsfile, Jenkinsfile by default", "[path]", "", false)
+	newPipeline.AddNamedTextArgument("credentials", "ID of the Jenkins credentials used to check out the repository", "[id]", "", false)
+
+	config := model.NewAutocompleteData("config", "get|edit [jobname]", "View or edit the config.xml of a job")
+	configGet := model.NewAutocompleteData("get", "[jobname]", "Upload the config.xml of a job as a file")
+	configGet.AddTextArgument("The job you want to get the config.xml of", "[jobname]", "")
+	configEdit := model.NewAutocompleteData("edit", "[jobname]", "Edit the config.xml of a job in a dialog")
+	configEdit.AddTextArgument("The job you want to edit the config.xml of", "[jobname]", "")
+	config.AddCommand(configGet)
+	config.AddCommand(configEdit)
+
+	copyJob := model.NewAutocompleteData("copy", "[source] [destination]", "Copy a job")
+	copyJob.AddTextArgument("The job you want to copy", "[source]", "")
+	copyJob.AddTextArgument("Name of the copy, folder1/jobname to create it in a folder", "[destination]", "")
+
+	rename := model.NewAutocompleteData("rename", "[jobname] [newname]", "Rename a job")
+	rename.AddTextArgument("The job you want to rename", "[jobname]", "")
+	rename.AddTextArgument("New name of the job", "[newname]", "")
+
+	move := model.NewAutocompleteData("move", "[jobname] [folder]", "Move a job to another folder")
+	move.AddTextArgument("The job you want to move", "[jobname]", "")
+	move.AddTextArgument("Destination folder, / for the top level", "[folder]", "")
+
+	bulk := model.NewAutocompleteData("bulk", "enable|disable|delete|build [glob] [--dry-run]", "Run an operation on all jobs matching a glob pattern")
+	for _, operation := range bulkOperations {
+		bulkOperation := model.NewAutocompleteData(operation, "[glob] [--dry-run]", fmt.Sprintf("%s all jobs matching a glob pattern", strings.ToUpper(operation[:1])+operation[1:]))
+		bulkOperation.AddTextArgument("Glob pattern, e.g. folder/** for all jobs of a folder", "[glob]", "")
+		bulk.AddCommand(bulkOperation)
+	}
+
+	lint := model.NewAutocompleteData("lint", "<post link>", "Validate a declarative Jenkinsfile")
+	lint.AddTextArgument("Link to a post with the Jenkinsfile attached. If not specified, a dialog is opened to paste it", "<post link>", "")
+
+	template := model.NewAutocompleteData("template", "list|add|remove", "Manage the config.xml templates used to create jobs")
+	templateList := model.NewAutocompleteData("list", "", "List the config.xml templates")
+	templateAdd := model.NewAutocompleteData("add", "[name] [post link]", "Add the config.xml attached to a post as template")
+	templateAdd.AddTextArgument("Name of the template", "[name]", "")
+	templateAdd.AddTextArgument("Link to the post with the config.xml attached", "[post link]", "")
+	templateRemove := model.NewAutocompleteData("remove", "[name]", "Remove a config.xml template")
+	templateRemove.AddTextArgument("Name of the template", "[name]", "")
+	template.AddCommand(templateList)
+	template.AddCommand(templateAdd)
+	template.AddCommand(templateRemove)
 
 	build := model.NewAutocompleteData("build", "[jobname]", "Trigger a build for a given job")
 	build.AddTextArgument("folder1/jobname if the job is in a folder, or \"job with space\"", "[jobname]", "")
@@ -92,36 +214,186 @@ func getAutocompleteData() *model.AutocompleteData {
 	getArtifacts := model.NewAutocompleteData("get-artifacts", "[jobname]", "Get artifacts of the last build of the given job")
 	getArtifacts.AddTextArgument("The job you want to get artifacts from", "[jobname]", "")
 
-	testResults := model.NewAutocompleteData("test-results", "[jobname]", "Get test results of the last build of the given job")
-	getArtifacts.AddTextArgument("The job you want to get test results from", "[jobname]", "")
+	testResults := model.NewAutocompleteData("test-results", "[jobname] <build number>", "Get a summary of the test results of a build of the given job")
+	testResults.AddTextArgument("The job you want to get test results from", "[jobname]", "")
+	testResults.AddTextArgument("Build number to get test results from. If not specified, the last build is chosen", "<build number>", "")
+
+	changes := model.NewAutocompleteData("changes", "[jobname] <build number>", "List the commits and culprits of a build of the given job")
+	changes.AddTextArgument("The job you want to get the changes of", "[jobname]", "")
+	changes.AddTextArgument("Build number to get the changes of. If not specified, the last build is chosen", "<build number>", "")
+
+	testDiff := model.NewAutocompleteData("test-diff", "[jobname] <build A> <build B>", "Compare the test results of two builds of the given job")
+	testDiff.AddTextArgument("The job you want to compare test results of", "[jobname]", "")
+	testDiff.AddTextArgument("Base build number. If not specified, the last successful build is chosen", "<build A>", "")
+	testDiff.AddTextArgument("Build number to compare with the base build. If not specified, the last build is chosen", "<build B>", "")
+
+	flaky := model.NewAutocompleteData("flaky", "[jobname]", "List the flakiest tests of the given job")
+	flaky.AddTextArgument("The job you want to find flaky tests of", "[jobname]", "")
 
-	getLog := model.NewAutocompleteData("get-log", "[jobname] <build number>", "Get log of a build of the given job")
+	getLog := model.NewAutocompleteData("get-log", "[jobname] <build number> [--tail N | --grep regex | --errors]", "Get log of a build of the given job")
 	getLog.AddTextArgument("The job you want to get log from", "[jobname]", "")
 	getLog.AddTextArgument("Build number to get log from. If not specified, the last build is chosen", "<build number>", "")
+	getLog.AddNamedTextArgument("tail", "Only get the last N lines of the log", "N", "", false)
+	getLog.AddNamedTextArgument("grep", "Only get the lines matching a regular expression", "regex", "", false)
+	getLog.AddNamedTextArgument("errors", "Only get the lines reporting errors, failures and exceptions", "", "", false)
 
-	plugins := model.NewAutocompleteData("plugins", "", "Get a list of installed plugins on the Jenkins server")
+	stages := model.NewAutocompleteData("stages", "[jobname] <build number>", "Show the stages of a Pipeline build")
+	stages.AddTextArgument("The Pipeline job you want to see the stages of", "[jobname]", "")
+	stages.AddTextArgument("Build number to see the stages of. If not specified, the last build is chosen", "<build number>", "")
+
+	stageLog := model.NewAutocompleteData("stage-log", "[jobname] [build number] [stage name]", "Get the log of a stage of a Pipeline build")
+	stageLog.AddTextArgument("The Pipeline job you want to get a stage log from", "[jobname]", "")
+	stageLog.AddTextArgument("Build number to get the stage log from", "[build number]", "")
+	stageLog.AddTextArgument("Name of the stage", "[stage name]", "")
+
+	inputs := model.NewAutocompleteData("inputs", "[jobname] <build number>", "Respond to the input steps a Pipeline build waits on")
+	inputs.AddTextArgument("The Pipeline job waiting for input", "[jobname]", "")
+	inputs.AddTextArgument("Build number waiting for input. If not specified, the last build is chosen", "<build number>", "")
+
+	replay := model.NewAutocompleteData("replay", "[jobname] <build number>", "Replay a Pipeline build with an edited script")
+	replay.AddTextArgument("The Pipeline job you want to replay", "[jobname]", "")
+	replay.AddTextArgument("Build number to replay. If not specified, the last build is chosen", "<build number>", "")
+
+	branches := model.NewAutocompleteData("branches", "[project]", "List the branches and pull requests of a multibranch project")
+	branches.AddTextArgument("The multibranch project you want to list the branches of", "[project]", "")
+
+	scan := model.NewAutocompleteData("scan", "[project]", "Scan a multibranch project for branches")
+	scan.AddTextArgument("The multibranch project you want to scan", "[project]", "")
+
+	queue := model.NewAutocompleteData("queue", "[--job glob] | cancel [id|jobname]", "List or cancel items of the build queue")
+	queue.AddNamedTextArgument("job", "Only list the items of the jobs matching a glob pattern", "glob", "", false)
+	queueCancel := model.NewAutocompleteData("cancel", "[id|jobname]", "Cancel a queue item, or all queue items of a job")
+	queueCancel.AddTextArgument("ID of the queue item or name of the job", "[id|jobname]", "")
+	queue.AddCommand(queueCancel)
+
+	tail := model.NewAutocompleteData("tail", "[jobname] <build number>", "Follow the console log of a running build in a thread")
+	tail.AddTextArgument("The job you want to follow the log of", "[jobname]", "")
+	tail.AddTextArgument("Build number to follow the log of. If not specified, the last build is chosen", "<build number>", "")
+
+	history := model.NewAutocompleteData("history", "[jobname] [--last N] [--result result] [--since 2d] [--user username]", "Show recent builds of the given job")
+	history.AddTextArgument("The job you want to see the build history of", "[jobname]", "")
+	history.AddNamedTextArgument("last", "Number of builds to show", "N", "", false)
+	history.AddNamedStaticListArgument("result", "Only show builds with the given result", false, []model.AutocompleteListItem{
+		{Item: "success", HelpText: "Successful builds"},
+		{Item: "failure", HelpText: "Failed builds"},
+		{Item: "unstable", HelpText: "Unstable builds"},
+		{Item: "aborted", HelpText: "Aborted builds"},
+		{Item: "running", HelpText: "Builds in progress"},
+	})
+	history.AddNamedTextArgument("since", "Only show builds started within the given duration, e.g. 12h or 2d", "duration", "", false)
+	history.AddNamedTextArgument("user", "Only show builds triggered by the given Jenkins user", "username", "", false)
+
+	nodes := model.NewAutocompleteData("nodes", "[--label x] [--offline]", "List the nodes of the Jenkins server")
+	nodes.AddNamedTextArgument("label", "Only list the nodes with the given label", "label", "", false)
+
+	node := model.NewAutocompleteData("node", "offline|online|disconnect [name]", "Manage a node of the Jenkins server")
+	nodeOffline := model.NewAutocompleteData("offline", "[name] [reason]", "Take a node offline")
+	nodeOffline.AddTextArgument("Name of the node", "[name]", "")
+	nodeOffline.AddTextArgument("Reason for taking the node offline", "[reason]", "")
+	nodeOnline := model.NewAutocompleteData("online", "[name]", "Bring a node back online")
+	nodeOnline.AddTextArgument("Name of the node", "[name]", "")
+	nodeDisconnect := model.NewAutocompleteData("disconnect", "[name] [reason]", "Disconnect the agent of a node")
+	nodeDisconnect.AddTextArgument("Name of the node", "[name]", "")
+	nodeDisconnect.AddTextArgument("Reason for disconnecting the node", "[reason]", "")
+	node.AddCommand(nodeOffline)
+	node.AddCommand(nodeOnline)
+	node.AddCommand(nodeDisconnect)
+
+	plugins := model.NewAutocompleteData("plugins", "[--updates] [--disabled] [--filter x]", "Get a report of the installed plugins with their updates and security warnings")
+	plugins.AddNamedTextArgument("filter", "Only list the plugins whose name contains the given text", "text", "", false)
+	pluginsInstallCmd := model.NewAutocompleteData("install", "[shortName]@<version>", "Install a plugin")
+	pluginsInstallCmd.AddTextArgument("Short name of the plugin, optionally followed by @ and the version to install", "[shortName]@<version>", "")
+	pluginsUpdateCmd := model.NewAutocompleteData("update", "--all", "Update all plugins with an update")
+	pluginsUpdateCmd.AddStaticListArgument("Plugins to update", true, []model.AutocompleteListItem{
+		{Item: "--all", HelpText: "All plugins with an update"},
+	})
+	pluginsEnableCmd := model.NewAutocompleteData("enable", "[shortName]", "Enable a plugin")
+	pluginsEnableCmd.AddTextArgument("Short name of the plugin", "[shortName]", "")
+	pluginsDisableCmd := model.NewAutocompleteData("disable", "[shortName]", "Disable a plugin")
+	pluginsDisableCmd.AddTextArgument("Short name of the plugin", "[shortName]", "")
+	plugins.AddCommand(pluginsInstallCmd)
+	plugins.AddCommand(pluginsUpdateCmd)
+	plugins.AddCommand(pluginsEnableCmd)
+	plugins.AddCommand(pluginsDisableCmd)
 
 	safeRestart := model.NewAutocompleteData("safe-restart", "", "Safe restart of the Jenkins server")
 
+	quietDown := model.NewAutocompleteData("quiet-down", "[reason]", "Prepare the Jenkins server for shutdown")
+	quietDown.AddTextArgument("Reason shown in Jenkins", "[reason]", "")
+
+	cancelQuietDown := model.NewAutocompleteData("cancel-quiet-down", "", "Cancel the preparation of the Jenkins server for shutdown")
+
+	restart := model.NewAutocompleteData("restart", "--safe|--force", "Restart the Jenkins server")
+	restart.AddStaticListArgument("Wait for the running builds or not", true, []model.AutocompleteListItem{
+		{Item: "--safe", HelpText: "Restart once the running builds have finished"},
+		{Item: "--force", HelpText: "Restart right away"},
+	})
+
+	exit := model.NewAutocompleteData("exit", "", "Shut down the Jenkins server")
+
+	health := model.NewAutocompleteData("health", "", "Show the health of the Jenkins server and its recent state changes")
+
 	me := model.NewAutocompleteData("me", "", "Display the connected Jenkins account")
 
+	linkUser := model.NewAutocompleteData("link-user", "[jenkins-id]", "Link your connected Jenkins account to your account, so you are mentioned for its changes")
+	linkUser.AddTextArgument("The ID of the Jenkins user", "[jenkins-id]", "")
+
+	unlinkUser := model.NewAutocompleteData("unlink-user", "[jenkins-id]", "Remove the link of a Jenkins user ID")
+	unlinkUser.AddTextArgument("The ID of the Jenkins user", "[jenkins-id]", "")
+
+	mentions := model.NewAutocompleteData("mentions", "on|off", "Choose whether you are mentioned in the posts of builds")
+	mentions.AddStaticListArgument("Whether you are mentioned", true, []model.AutocompleteListItem{
+		{Item: "on", HelpText: "Mention me for the failures and changes of builds"},
+		{Item: "off", HelpText: "Don't mention me in the posts of builds"},
+	})
+
 	help := model.NewAutocompleteData("help", "", "Find help related to the syntax of the slash commands")
 
 	jenkins.AddCommand(abort)
 	jenkins.AddCommand(build)
+	jenkins.AddCommand(bulk)
+	jenkins.AddCommand(config)
 	jenkins.AddCommand(connect)
+	jenkins.AddCommand(copyJob)
 	jenkins.AddCommand(createjob)
 	jenkins.AddCommand(delete)
 	jenkins.AddCommand(disable)
 	jenkins.AddCommand(disconnect)
 	jenkins.AddCommand(enable)
+	jenkins.AddCommand(flaky)
 	jenkins.AddCommand(getArtifacts)
 	jenkins.AddCommand(getLog)
+	jenkins.AddCommand(stages)
+	jenkins.AddCommand(stageLog)
+	jenkins.AddCommand(inputs)
+	jenkins.AddCommand(rename)
+	jenkins.AddCommand(replay)
+	jenkins.AddCommand(branches)
+	jenkins.AddCommand(scan)
+	jenkins.AddCommand(queue)
+	jenkins.AddCommand(tail)
+	jenkins.AddCommand(health)
 	jenkins.AddCommand(help)
+	jenkins.AddCommand(history)
+	jenkins.AddCommand(lint)
 	jenkins.AddCommand(me)
+	jenkins.AddCommand(linkUser)
+	jenkins.AddCommand(unlinkUser)
+	jenkins.AddCommand(mentions)
+	jenkins.AddCommand(move)
+	jenkins.AddCommand(newPipeline)
+	jenkins.AddCommand(nodes)
+	jenkins.AddCommand(node)
 	jenkins.AddCommand(plugins)
 	jenkins.AddCommand(safeRestart)
+	jenkins.AddCommand(quietDown)
+	jenkins.AddCommand(cancelQuietDown)
+	jenkins.AddCommand(restart)
+	jenkins.AddCommand(exit)
+	jenkins.AddCommand(template)
+	jenkins.AddCommand(testDiff)
 	jenkins.AddCommand(testResults)
+	jenkins.AddCommand(changes)
 	return jenkins
 }
 
@@ -178,6 +450,9 @@ func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*mo
 				p.API.LogError("Error saving Jenkins user information to KV store", "Err", err.Error())
 				return &model.CommandResponse{}, nil
 			}
+			if err := p.linkConnectedJenkinsUser(args.UserId, parameters[0]); err != nil {
+				p.API.LogWarn("Error linking the connected Jenkins user", "user_id", args.UserId, "err", err.Error())
+			}
 
 			return p.getCommandResponse(args, "Your Jenkins account has been successfully connected to Mattermost."), nil
 		}
@@ -233,6 +508,18 @@ func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*mo
 				return p.getCommandResponse(args, "Error fetching artifacts."), nil
 			}
 		}
+	case "changes":
+		if len(parameters) == 0 {
+			return p.getCommandResponse(args, jobNotSpecifiedResponse), nil
+		}
+		jobName, buildNumber, ok := parseBuildParameters(parameters)
+		if !ok {
+			return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to get the changes of a build."), nil
+		}
+		if err := p.postBuildChanges(args.UserId, args.ChannelId, jobName, buildNumber); err != nil {
+			p.API.LogError("Error fetching the changes of the build", "job_name", jobName, "err", err.Error())
+			return p.getCommandResponse(args, "Encountered an error fetching the changes of the build."), nil
+		}
 	case "test-results":
 		if len(parameters) == 0 {
 			return p.getCommandResponse(args, jobNotSpecifiedResponse), nil
@@ -250,11 +537,43 @@ func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*mo
 				p.createEphemeralPost(args.UserId, args.ChannelId, msg)
 			}
 
-			if err := p.getBuildTestResultsURL(args.UserId, args.ChannelId, jobName, buildNumber); err != nil {
+			if err := p.postBuildTestResults(args.UserId, args.ChannelId, jobName, buildNumber); err != nil {
 				p.API.LogError("Error fetching test results", "job_name", jobName, "err", err.Error())
 				return p.getCommandResponse(args, "Error fetching test results."), nil
 			}
 		}
+	case "test-diff":
+		if len(parameters) == 0 {
+			return p.getCommandResponse(args, jobNotSpecifiedResponse), nil
+		}
+		jobName, buildNumbers, ok := parseJobAndBuilds(parameters, 2)
+		if !ok || len(buildNumbers) == 1 {
+			return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to compare test results of two builds."), nil
+		}
+		baseBuild, targetBuild := "", ""
+		if len(buildNumbers) == 2 {
+			baseBuild, targetBuild = buildNumbers[0], buildNumbers[1]
+		}
+		p.createEphemeralPost(args.UserId, args.ChannelId, fmt.Sprintf("Comparing test results of the job '%s'...", jobName))
+
+		if err := p.postTestReportDiff(args.UserId, args.ChannelId, jobName, baseBuild, targetBuild); err != nil {
+			p.API.LogError("Error comparing test results", "job_name", jobName, "err", err.Error())
+			return p.getCommandResponse(args, "Error comparing test results."), nil
+		}
+	case "flaky":
+		if len(parameters) == 0 {
+			return p.getCommandResponse(args, jobNotSpecifiedResponse), nil
+		}
+		jobName, extraParam, ok := parseBuildParameters(parameters)
+		if !ok || extraParam != "" {
+			return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to list flaky tests of a job."), nil
+		}
+		p.createEphemeralPost(args.UserId, args.ChannelId, fmt.Sprintf("Analyzing test results of the recent builds of the job '%s'...", jobName))
+
+		if err := p.postFlakyTests(args.UserId, args.ChannelId, jobName); err != nil {
+			p.API.LogError("Error fetching flaky tests", "job_name", jobName, "err", err.Error())
+			return p.getCommandResponse(args, "Error fetching flaky tests."), nil
+		}
 	case "disable":
 		if len(parameters) == 0 {
 			return p.getCommandResponse(args, "Please specify a job to disable."), nil
@@ -308,22 +627,197 @@ func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*mo
 			p.API.LogError("Error disconnecting the user", "err", err.Error())
 			return p.getCommandResponse(args, "Encountered an error while disconnecting the user from Jenkins."), nil
 		}
+		if err := p.unlinkConnectedJenkinsUser(args.UserId, userInfo.Username); err != nil {
+			p.API.LogWarn("Error unlinking the disconnected Jenkins user", "user_id", args.UserId, "err", err.Error())
+		}
 		return p.getCommandResponse(args, fmt.Sprintf("User '%s' has been disconnected.", userInfo.Username)), nil
+	case "link-user", "unlink-user":
+		jenkinsUserID, rest, ok := parseQuotedArgument(parameters)
+		if !ok || rest != "" {
+			return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to link a Jenkins user."), nil
+		}
+		link := p.linkJenkinsUser
+		if action == "unlink-user" {
+			link = p.unlinkJenkinsUser
+		}
+		msg, err := link(args.UserId, jenkinsUserID)
+		if err != nil {
+			p.API.LogError("Error linking the Jenkins user", "jenkins_user", jenkinsUserID, "err", err.Error())
+			return p.getCommandResponse(args, "Encountered an error linking the Jenkins user."), nil
+		}
+		return p.getCommandResponse(args, msg), nil
+	case "mentions":
+		if len(parameters) != 1 || (parameters[0] != "on" && parameters[0] != "off") {
+			return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to choose whether you are mentioned."), nil
+		}
+		if err := p.setMentionsOptOut(args.UserId, parameters[0] == "off"); err != nil {
+			p.API.LogError("Error saving the mentions setting", "err", err.Error())
+			return p.getCommandResponse(args, "Encountered an error saving your mentions setting."), nil
+		}
+		if parameters[0] == "off" {
+			return p.getCommandResponse(args, "You will no longer be mentioned in the posts of builds."), nil
+		}
+		return p.getCommandResponse(args, "You will be mentioned in the posts of builds."), nil
 	case "get-log":
 		if len(parameters) == 0 {
 			return p.getCommandResponse(args, "Please specify a job name or jobname and build number."), nil
 		} else if len(parameters) >= 1 {
-			jobName, buildNumber, ok := parseBuildParameters(parameters)
+			positional, flags, err := parseFlags(parameters, []string{"tail", "grep"}, []string{"errors"})
+			if err != nil {
+				return p.getCommandResponse(args, fmt.Sprintf("Error parsing flags: %s. Please check `/jenkins help` to find help on how to get log of a build.", err.Error())), nil
+			}
+			jobName, buildNumber, ok := parseBuildParameters(positional)
 			if !ok {
 				return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to get log of a build."), nil
 			}
+			options, err := parseLogExcerptOptions(flags)
+			if err != nil {
+				return p.getCommandResponse(args, fmt.Sprintf("Invalid options: %s.", err.Error())), nil
+			}
 			p.createEphemeralPost(args.UserId, args.ChannelId, fmt.Sprintf("Fetching logs of job '%s'...", jobName))
 
-			if err := p.fetchAndUploadBuildLog(args.UserId, args.ChannelId, jobName, buildNumber); err != nil {
+			if err := p.postBuildLog(args.UserId, args.ChannelId, jobName, buildNumber, options); err != nil {
 				p.API.LogError("Error fetching logs", "job_name", jobName, "err", err.Error())
 				return p.getCommandResponse(args, "Encountered an error fetching logs."), nil
 			}
 		}
+	case "stages":
+		if len(parameters) == 0 {
+			return p.getCommandResponse(args, jobNotSpecifiedResponse), nil
+		}
+		jobName, buildNumber, ok := parseBuildParameters(parameters)
+		if !ok {
+			return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to get the stages of a build."), nil
+		}
+
+		if err := p.postStages(args.UserId, args.ChannelId, jobName, buildNumber); err != nil {
+			p.API.LogError("Error fetching stages", "job_name", jobName, "err", err.Error())
+			return p.getCommandResponse(args, "Encountered an error fetching the stages of the build."), nil
+		}
+	case "stage-log":
+		jobName, buildNumber, stageName, ok := parseStageLogParameters(parameters)
+		if !ok {
+			return p.getCommandResponse(args, "Please specify a job name, a build number and a stage name. Please check `/jenkins help` to find help on how to get the log of a stage."), nil
+		}
+		p.createEphemeralPost(args.UserId, args.ChannelId, fmt.Sprintf("Fetching the log of the stage '%s'...", stageName))
+
+		if err := p.postStageLog(args.UserId, args.ChannelId, jobName, buildNumber, stageName); err != nil {
+			p.API.LogError("Error fetching stage log", "job_name", jobName, "stage", stageName, "err", err.Error())
+			return p.getCommandResponse(args, "Encountered an error fetching the log of the stage."), nil
+		}
+	case "inputs":
+		if len(parameters) == 0 {
+			return p.getCommandResponse(args, jobNotSpecifiedResponse), nil
+		}
+		jobName, buildNumber, ok := parseBuildParameters(parameters)
+		if !ok {
+			return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to respond to input steps."), nil
+		}
+
+		if err := p.postPendingInputs(args.UserId, args.ChannelId, jobName, buildNumber); err != nil {
+			p.API.LogError("Error fetching pending inputs", "job_name", jobName, "err", err.Error())
+			return p.getCommandResponse(args, "Encountered an error fetching the pending inputs of the build."), nil
+		}
+	case "replay":
+		if len(parameters) == 0 {
+			return p.getCommandResponse(args, jobNotSpecifiedResponse), nil
+		}
+		jobName, buildNumber, ok := parseBuildParameters(parameters)
+		if !ok {
+			return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to replay a build."), nil
+		}
+
+		if err := p.openReplayDialog(args.UserId, args.TriggerId, jobName, buildNumber); err != nil {
+			p.API.LogError("Error opening replay dialog", "job_name", jobName, "err", err.Error())
+			return p.getCommandResponse(args, "Encountered an error fetching the scripts of the build. Make sure it's a Pipeline build and you have the permission to replay it."), nil
+		}
+	case "branches":
+		if len(parameters) == 0 {
+			return p.getCommandResponse(args, jobNotSpecifiedResponse), nil
+		}
+		projectName, extraParam, ok := parseBuildParameters(parameters)
+		if !ok || extraParam != "" {
+			return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to list the branches of a project."), nil
+		}
+
+		if err := p.postBranches(args.UserId, args.ChannelId, projectName); err != nil {
+			p.API.LogError("Error fetching branches", "job_name", projectName, "err", err.Error())
+			return p.getCommandResponse(args, "Encountered an error fetching the branches of the project."), nil
+		}
+	case "scan":
+		if len(parameters) == 0 {
+			return p.getCommandResponse(args, jobNotSpecifiedResponse), nil
+		}
+		projectName, extraParam, ok := parseBuildParameters(parameters)
+		if !ok || extraParam != "" {
+			return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to scan a project."), nil
+		}
+
+		if err := p.scanProject(args.UserId, args.ChannelId, projectName); err != nil {
+			p.API.LogError("Error scanning project", "job_name", projectName, "err", err.Error())
+			return p.getCommandResponse(args, "Encountered an error scanning the project."), nil
+		}
+	case "queue":
+		if len(parameters) > 0 && parameters[0] == "cancel" {
+			target, extraParam, ok := parseBuildParameters(parameters[1:])
+			if len(parameters) == 1 || !ok || extraParam != "" {
+				return p.getCommandResponse(args, "Please specify a queue item ID or a job name. Please check `/jenkins help` to find help on how to cancel a queue item."), nil
+			}
+			msg, err := p.cancelQueueItems(args.UserId, target)
+			if err != nil {
+				p.API.LogError("Error cancelling queue item", "target", target, "err", err.Error())
+				return p.getCommandResponse(args, "Encountered an error cancelling the queue item."), nil
+			}
+			return p.getCommandResponse(args, msg), nil
+		}
+
+		positional, flags, err := parseFlags(parameters, []string{"job"}, nil)
+		if err != nil || len(positional) > 0 {
+			return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to list the build queue."), nil
+		}
+		glob, err := parseQueueFilter(flags)
+		if err != nil {
+			return p.getCommandResponse(args, fmt.Sprintf("Invalid filter: %s.", err.Error())), nil
+		}
+
+		if err := p.postQueue(args.UserId, args.ChannelId, glob); err != nil {
+			p.API.LogError("Error fetching the build queue", "err", err.Error())
+			return p.getCommandResponse(args, "Encountered an error fetching the build queue."), nil
+		}
+	case "tail":
+		if len(parameters) == 0 {
+			return p.getCommandResponse(args, jobNotSpecifiedResponse), nil
+		}
+		jobName, buildNumber, ok := parseBuildParameters(parameters)
+		if !ok {
+			return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to follow the log of a build."), nil
+		}
+
+		if err := p.startLogTail(args.UserId, args.ChannelId, jobName, buildNumber); err != nil {
+			p.API.LogError("Error following log", "job_name", jobName, "err", err.Error())
+			return p.getCommandResponse(args, "Encountered an error following the log."), nil
+		}
+	case "history":
+		if len(parameters) == 0 {
+			return p.getCommandResponse(args, jobNotSpecifiedResponse), nil
+		}
+		positional, flags, err := parseFlags(parameters, []string{"last", "result", "since", "user"}, nil)
+		if err != nil {
+			return p.getCommandResponse(args, fmt.Sprintf("Error parsing flags: %s. Please check `/jenkins help` to find help on how to get the build history of a job.", err.Error())), nil
+		}
+		jobName, extraParam, ok := parseBuildParameters(positional)
+		if !ok || extraParam != "" {
+			return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to get the build history of a job."), nil
+		}
+		filter, err := parseHistoryFilter(flags)
+		if err != nil {
+			return p.getCommandResponse(args, fmt.Sprintf("Invalid filter: %s.", err.Error())), nil
+		}
+
+		if err := p.postBuildHistory(args.UserId, args.ChannelId, jobName, filter); err != nil {
+			p.API.LogError("Error fetching build history", "job_name", jobName, "err", err.Error())
+			return p.getCommandResponse(args, "Encountered an error fetching the build history."), nil
+		}
 	case "abort":
 		if len(parameters) == 0 {
 			return p.getCommandResponse(args, "Please specify a job name or jobname and build number."), nil
@@ -362,34 +856,283 @@ func (p *Plugin) ExecuteCommand(c *plugin.Context, args *model.CommandArgs) (*mo
 
 			p.createPost(args.UserId, args.ChannelId, fmt.Sprintf("Job '%s' has been deleted.", jobName))
 		}
-	case "safe-restart":
+	case "nodes":
+		positional, flags, err := parseFlags(parameters, []string{"label"}, []string{"offline"})
+		if err != nil || len(positional) > 0 {
+			return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to list the nodes."), nil
+		}
+		_, offline := flags["offline"]
+		filter := &nodeFilter{Label: flags["label"], Offline: offline}
+		if err := p.postNodes(args.UserId, args.ChannelId, filter); err != nil {
+			p.API.LogError("Error fetching nodes", "err", err.Error())
+			return p.getCommandResponse(args, "Encountered an error fetching the nodes."), nil
+		}
+	case "node":
+		if len(parameters) == 0 || (parameters[0] != "offline" && parameters[0] != "online" && parameters[0] != "disconnect") {
+			return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to manage a node."), nil
+		}
+		nodeAction := parameters[0]
+		nodeName, reason, ok := parseQuotedArgument(parameters[1:])
+		if !ok || (nodeAction == "offline" && reason == "") || (nodeAction == "online" && reason != "") {
+			return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to manage a node."), nil
+		}
+		if !p.isSystemAdmin(args.UserId) {
+			return p.getCommandResponse(args, "Only system admins can manage nodes."), nil
+		}
+		msg, err := p.manageNode(args.UserId, nodeAction, nodeName, reason)
+		if err != nil {
+			p.API.LogError("Error managing node", "node", nodeName, "action", nodeAction, "err", err.Error())
+			return p.getCommandResponse(args, "Encountered an error managing the node."), nil
+		}
+		p.createPost(args.UserId, args.ChannelId, msg)
+	case "quiet-down":
+		if !p.isSystemAdmin(args.UserId) {
+			return p.getCommandResponse(args, "Only system admins can prepare Jenkins for shutdown."), nil
+		}
+		p.askLifecycleConfirmation(args.UserId, args.ChannelId, lifecycleQuietDown, strings.Join(parameters, " "))
+	case "cancel-quiet-down", "safe-restart", "exit":
 		if len(parameters) != 0 {
-			return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to safe restart Jenkins."), nil
+			return p.getCommandResponse(args, fmt.Sprintf("Please check `/jenkins help` to find help on how to use `%s`.", action)), nil
 		}
-		if err := p.safeRestart(args.UserId); err != nil {
-			p.API.LogError("Error while safe restarting the Jenkins server", err.Error())
-			return p.getCommandResponse(args, "Encountered an error while safe restarting the Jenkins server."), nil
+		if !p.isSystemAdmin(args.UserId) {
+			return p.getCommandResponse(args, "Only system admins can restart or shut down Jenkins."), nil
 		}
-		p.createPost(args.UserId, args.ChannelId, "Safe restart of Jenkins server has been triggered.")
-	case "plugins":
+		p.askLifecycleConfirmation(args.UserId, args.ChannelId, action, "")
+	case "restart":
+		positional, flags, err := parseFlags(parameters, nil, []string{"safe", "force"})
+		_, safe := flags["safe"]
+		_, force := flags["force"]
+		if err != nil || len(positional) > 0 || safe == force {
+			return p.getCommandResponse(args, "Please specify either `--safe` or `--force`. Please check `/jenkins help` to find help on how to restart Jenkins."), nil
+		}
+		if !p.isSystemAdmin(args.UserId) {
+			return p.getCommandResponse(args, "Only system admins can restart or shut down Jenkins."), nil
+		}
+		lifecycleAction := lifecycleRestart
+		if safe {
+			lifecycleAction = lifecycleSafeRestart
+		}
+		p.askLifecycleConfirmation(args.UserId, args.ChannelId, lifecycleAction, "")
+	case "health":
 		if len(parameters) != 0 {
-			return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to get a list of plugins."), nil
+			return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to check the health of Jenkins."), nil
 		}
-		if err := p.getListOfInstalledPlugins(args.UserId, args.ChannelId); err != nil {
-			p.API.LogError("Error while fetching list of installed plugins", err.Error())
+		msg, err := p.getHealth()
+		if err != nil {
+			p.API.LogError("Error checking the health of Jenkins", "err", err.Error())
+			return p.getCommandResponse(args, "Encountered an error checking the health of Jenkins."), nil
+		}
+		return p.getCommandResponse(args, msg), nil
+	case "plugins":
+		if len(parameters) > 0 && !strings.HasPrefix(parameters[0], "--") {
+			return p.executePluginsSubcommand(args, parameters[0], parameters[1:]), nil
+		}
+		positional, flags, err := parseFlags(parameters, []string{"filter"}, []string{"updates", "disabled"})
+		if err != nil || len(positional) > 0 {
+			return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to get a report of the plugins."), nil
+		}
+		_, updates := flags["updates"]
+		_, disabled := flags["disabled"]
+		filter := &pluginFilter{Updates: updates, Disabled: disabled, Text: flags["filter"]}
+		if err := p.postPlugins(args.UserId, args.ChannelId, filter); err != nil {
+			p.API.LogError("Error while fetching list of installed plugins", "err", err.Error())
 			return p.getCommandResponse(args, "Encountered an error while fetching list of installed plugins"), nil
 		}
 	case "createjob":
-		if len(parameters) != 0 {
+		positional, flags, err := parseFlags(parameters, []string{"template"}, nil)
+		if err != nil || len(positional) != 0 {
 			return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to create a job."), nil
 		}
+		if name, ok := flags["template"]; ok {
+			msg, err := p.createDialogForTemplate(args.UserId, args.TriggerId, name)
+			if err != nil {
+				p.API.LogError("Error while creating the job from a template", "template", name, "err", err.Error())
+				return p.getCommandResponse(args, "Encountered an error while creating the job"), nil
+			}
+			if msg != "" {
+				return p.getCommandResponse(args, msg), nil
+			}
+			return &model.CommandResponse{}, nil
+		}
 		if err := p.createJob(args.UserId, args.ChannelId, args.TriggerId); err != nil {
 			p.API.LogError("Error while creating the job.", err.Error())
 			return p.getCommandResponse(args, "Encountered an error while creating the job"), nil
 		}
+	case "new-pipeline":
+		options, err := parsePipelineOptions(parameters)
+		if err != nil {
+			return p.getCommandResponse(args, fmt.Sprintf("Invalid parameters: %s. Please check `/jenkins help` to find help on how to create a Pipeline job.", err.Error())), nil
+		}
+		if err := p.createPipelineJob(args.UserId, args.ChannelId, options); err != nil {
+			p.API.LogError("Error creating Pipeline job", "job_name", options.JobName, "err", err.Error())
+			return p.getCommandResponse(args, fmt.Sprintf("Encountered an error creating the Pipeline job '%s'.", options.JobName)), nil
+		}
+	case "config":
+		if len(parameters) < 2 || (parameters[0] != "get" && parameters[0] != "edit") {
+			return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to view or edit the configuration of a job."), nil
+		}
+		jobName, extraParam, ok := parseBuildParameters(parameters[1:])
+		if !ok || extraParam != "" {
+			return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to view or edit the configuration of a job."), nil
+		}
+		if parameters[0] == "get" {
+			if err := p.postJobConfig(args.UserId, args.ChannelId, jobName); err != nil {
+				p.API.LogError("Error fetching job configuration", "job_name", jobName, "err", err.Error())
+				return p.getCommandResponse(args, fmt.Sprintf("Encountered an error fetching the configuration of the job '%s'.", jobName)), nil
+			}
+			return &model.CommandResponse{}, nil
+		}
+		msg, err := p.createDialogForConfig(args.UserId, args.TriggerId, jobName)
+		if err != nil {
+			p.API.LogError("Error opening job configuration", "job_name", jobName, "err", err.Error())
+			return p.getCommandResponse(args, fmt.Sprintf("Encountered an error opening the configuration of the job '%s'.", jobName)), nil
+		}
+		if msg != "" {
+			return p.getCommandResponse(args, msg), nil
+		}
+	case "copy", "rename", "move":
+		first, second, ok := parseJobNamePair(parameters)
+		if !ok {
+			return p.getCommandResponse(args, fmt.Sprintf("Please check `/jenkins help` to find help on how to use `%s`.", action)), nil
+		}
+		var msg string
+		var err error
+		switch action {
+		case "copy":
+			msg, err = p.copyJob(args.UserId, args.ChannelId, first, second)
+		case "rename":
+			msg, err = p.renameJob(args.UserId, args.ChannelId, first, second)
+		default:
+			err = p.moveJob(args.UserId, args.ChannelId, first, second)
+		}
+		if err != nil {
+			p.API.LogError("Error reorganizing job", "action", action, "job_name", first, "err", err.Error())
+			return p.getCommandResponse(args, fmt.Sprintf("Encountered an error while trying to %s the job '%s'.", action, first)), nil
+		}
+		if msg != "" {
+			return p.getCommandResponse(args, msg), nil
+		}
+	case "bulk":
+		positional, flags, err := parseFlags(parameters, nil, []string{"dry-run"})
+		if err != nil || len(positional) < 2 || !containsString(bulkOperations, positional[0]) {
+			return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to run bulk operations."), nil
+		}
+		pattern := strings.Trim(strings.Join(positional[1:], " "), `"`)
+		_, dryRun := flags["dry-run"]
+		if err := p.askBulkConfirmation(args.UserId, args.ChannelId, positional[0], pattern, dryRun); err != nil {
+			p.API.LogError("Error fetching jobs for bulk operation", "pattern", pattern, "err", err.Error())
+			return p.getCommandResponse(args, "Encountered an error fetching the matching jobs."), nil
+		}
+	case "lint":
+		if len(parameters) > 1 {
+			return p.getCommandResponse(args, "Please check `/jenkins help` to find help on how to validate a Jenkinsfile."), nil
+		}
+		if len(parameters) == 0 {
+			if err := p.createDialogForLint(args.UserId, args.TriggerId); err != nil {
+				p.API.LogError("Error opening the lint dialog", "err", err.Error())
+				return p.getCommandResponse(args, "Encountered an error while validating the Jenkinsfile."), nil
+			}
+			return &model.CommandResponse{}, nil
+		}
+		msg, err := p.lintAttachedJenkinsfile(args.UserId, args.ChannelId, parameters[0])
+		if err != nil {
+			p.API.LogError("Error validating Jenkinsfile", "err", err.Error())
+			return p.getCommandResponse(args, "Encountered an error while validating the Jenkinsfile."), nil
+		}
+		if msg != "" {
+			return p.getCommandResponse(args, msg), nil
+		}
+	case "template":
+		return p.executeTemplateSubcommand(args, parameters), nil
 	default:
 		text := "###### Unknown Command: " + action + "\n" + "###### Mattermost Jenkins Plugin - Slash Command Help\n" + strings.ReplaceAll(helpText, "|", "`")
 		return p.getCommandResponse(args, text), nil
 	}
 	return &model.CommandResponse{}, nil
 }
+
+// executePluginsSubcommand runs the subcommands of the plugins command managing the plugins of Jenkins.
+func (p *Plugin) executePluginsSubcommand(args *model.CommandArgs, subcommand string, parameters []string) *model.CommandResponse {
+	usage := "Please check `/jenkins help` to find help on how to manage plugins."
+	switch subcommand {
+	case "install":
+		specs, err := parsePluginSpecs(parameters)
+		if err != nil || len(specs) == 0 {
+			return p.getCommandResponse(args, usage)
+		}
+		if !p.isSystemAdmin(args.UserId) {
+			return p.getCommandResponse(args, "Only system admins can manage plugins.")
+		}
+		p.askPluginInstallConfirmation(args.UserId, args.ChannelId, specs)
+	case "update":
+		if len(parameters) != 1 || parameters[0] != "--all" {
+			return p.getCommandResponse(args, usage)
+		}
+		if !p.isSystemAdmin(args.UserId) {
+			return p.getCommandResponse(args, "Only system admins can manage plugins.")
+		}
+		if err := p.askPluginUpdateConfirmation(args.UserId, args.ChannelId); err != nil {
+			p.API.LogError("Error fetching plugin updates", "err", err.Error())
+			return p.getCommandResponse(args, "Encountered an error fetching the plugin updates.")
+		}
+	case "enable", "disable":
+		if len(parameters) != 1 || !pluginNamePattern.MatchString(parameters[0]) {
+			return p.getCommandResponse(args, usage)
+		}
+		if !p.isSystemAdmin(args.UserId) {
+			return p.getCommandResponse(args, "Only system admins can manage plugins.")
+		}
+		action := pluginsEnable
+		if subcommand == "disable" {
+			action = pluginsDisable
+		}
+		p.askPluginToggleConfirmation(args.UserId, args.ChannelId, action, parameters[0])
+	default:
+		return p.getCommandResponse(args, usage)
+	}
+	return &model.CommandResponse{}
+}
+
+func (p *Plugin) executeTemplateSubcommand(args *model.CommandArgs, parameters []string) *model.CommandResponse {
+	usage := "Please check `/jenkins help` to find help on how to manage templates."
+	if len(parameters) == 0 {
+		return p.getCommandResponse(args, usage)
+	}
+	var msg string
+	var err error
+	switch parameters[0] {
+	case "list":
+		if len(parameters) != 1 {
+			return p.getCommandResponse(args, usage)
+		}
+		templates, templatesErr := p.getJobTemplates()
+		if templatesErr != nil {
+			p.API.LogError("Error fetching job templates", "err", templatesErr.Error())
+			return p.getCommandResponse(args, "Encountered an error fetching the templates.")
+		}
+		return p.getCommandResponse(args, formatJobTemplates(templates))
+	case "add":
+		if len(parameters) != 3 || !templateNamePattern.MatchString(parameters[1]) {
+			return p.getCommandResponse(args, usage)
+		}
+		if !p.isSystemAdmin(args.UserId) {
+			return p.getCommandResponse(args, "Only system admins can manage templates.")
+		}
+		msg, err = p.addJobTemplate(args.UserId, parameters[1], parameters[2])
+	case "remove":
+		if len(parameters) != 2 {
+			return p.getCommandResponse(args, usage)
+		}
+		if !p.isSystemAdmin(args.UserId) {
+			return p.getCommandResponse(args, "Only system admins can manage templates.")
+		}
+		msg, err = p.removeJobTemplate(parameters[1])
+	default:
+		return p.getCommandResponse(args, usage)
+	}
+	if err != nil {
+		p.API.LogError("Error managing job templates", "err", err.Error())
+		return p.getCommandResponse(args, "Encountered an error managing the templates.")
+	}
+	return p.getCommandResponse(args, msg)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/waseem18/gojenkins"
+)
+
+const (
+	// maxBuildWatchDuration is the time after which a triggered build is no longer watched for its completion.
+	maxBuildWatchDuration = 6 * time.Hour
+	// maxWatchedBuilds is the maximum number of builds watched at the same time, as each one is polled
+	// by its own goroutine.
+	maxWatchedBuilds = 50
+	// watchInputCheckPolls is the number of polls of a watched build between two checks for pending input steps.
+	watchInputCheckPolls = 3
+)
+
+// formatBuildCompletion renders the post announcing that a build has finished.
+func formatBuildCompletion(jobName string, build *gojenkins.BuildResponse) string {
+	duration := time.Duration(build.Duration) * time.Millisecond
+	return fmt.Sprintf("Job '%s' - #%d has finished: %s in %s\nBuild URL : %s", jobName, build.Number, build.Result, formatDuration(duration), build.URL)
+}
+
+// watchBuild waits for a build triggered by the plugin to finish, and posts its result.
+// It is only started when PostBuildCompletion is enabled, and gives up if maxWatchedBuilds builds are
+// watched already or once the plugin is deactivated. Input steps a Pipeline build waits on are posted while it runs, and its test report is
+// recorded in the test history of the job once it finished.
+// The changes of the build are added if ShowChangesOnCompletion is enabled. If the build failed, the users
+// who committed changes since the last successful build and the user who triggered it are mentioned,
+// and its failing tests which are known to be flaky are listed.
+func (p *Plugin) watchBuild(userID, channelID, jobName string, buildNumber int64) {
+	if p.watchedBuilds.Add(1) > maxWatchedBuilds {
+		p.watchedBuilds.Add(-1)
+		p.API.LogWarn("Too many builds are watched, not watching the build", "job_name", jobName, "build", buildNumber)
+		p.createEphemeralPost(userID, channelID, fmt.Sprintf("The result of the build #%d of the job '%s' won't be posted, as too many builds are watched already.", buildNumber, jobName))
+		return
+	}
+	defer p.watchedBuilds.Add(-1)
+
+	// The build is fetched again, as the build returned when triggering it is used by the caller.
+	build, err := p.getBuild(jobName, userID, strconv.FormatInt(buildNumber, 10))
+	if err != nil {
+		p.API.LogWarn("Error fetching the build to watch", "job_name", jobName, "build", buildNumber, "err", err.Error())
+		return
+	}
+
+	isPipeline, err := isPipelineBuild(build)
+	if err != nil {
+		p.API.LogWarn("Error checking if the build is a Pipeline build", "job_name", jobName, "build", buildNumber, "err", err.Error())
+	}
+
+	deadline := time.Now().Add(maxBuildWatchDuration)
+	postedInputs := make(map[string]bool)
+	for polls := 0; build.Raw.Building; polls++ {
+		if time.Now().After(deadline) {
+			return
+		}
+		if isPipeline && polls%watchInputCheckPolls == 0 {
+			p.postNewPendingInputs(userID, channelID, jobName, build, postedInputs)
+		}
+		if !p.sleep(pollingSleepTime * time.Second) {
+			return
+		}
+		if _, err := build.Poll(); err != nil {
+			p.API.LogWarn("Error polling the build", "job_name", jobName, "build", buildNumber, "err", err.Error())
+		}
+	}
+
+	report, err := getTestReport(build)
+	if err != nil {
+		p.API.LogWarn("Error fetching the test report of the build", "job_name", jobName, "build", buildNumber, "err", err.Error())
+	} else if report != nil {
+		p.recordCompletedBuildReport(jobName, build, report)
+	}
+
+	message := formatBuildCompletion(jobName, build.Raw)
+	if p.getConfiguration().ShowChangesOnCompletion {
+		changes, err := getBuildChanges(build)
+		if err != nil {
+			p.API.LogWarn("Error fetching the changes of the build", "job_name", jobName, "build", buildNumber, "err", err.Error())
+		} else {
+			message += "\n" + formatChanges(changes, p.newUserMentioner(build.Jenkins))
+		}
+	}
+	if isFailedResult(build.GetResult()) {
+		if mentions := p.getFailureMentions(userID, jobName, build); mentions != "" {
+			message += "\n" + mentions
+		}
+		if report != nil {
+			if flakyFailures := formatFlakyFailures(report, p.getKnownFlakyTests(jobName)); flakyFailures != "" {
+				message += "\n" + flakyFailures
+			}
+		}
+	}
+	p.createPost(userID, channelID, message)
+}
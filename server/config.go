@@ -0,0 +1,297 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/waseem18/gojenkins"
+)
+
+const (
+	configUpdate = "config-update"
+
+	// maxConfigLength is the maximum length of a config.xml edited in a dialog.
+	maxConfigLength = 100000
+	// maxConfigDiffLength is the maximum length of the diff shown before updating a config.xml.
+	maxConfigDiffLength = 6000
+	// maxJenkinsErrorLength is the maximum length of an error message extracted from an error page of Jenkins.
+	maxJenkinsErrorLength = 500
+)
+
+var (
+	xmlDeclarationPattern   = regexp.MustCompile(`^\s*<\?xml[^?]*\?>`)
+	htmlPreformattedPattern = regexp.MustCompile(`(?is)<pre[^>]*>(.*?)</pre>`)
+	htmlHiddenPattern       = regexp.MustCompile(`(?is)<(head|script|style)[^>]*>.*?</(head|script|style)>`)
+	whitespacePattern       = regexp.MustCompile(`\s+`)
+)
+
+// jenkinsRequestError is an error response of Jenkins, with the message of its error page.
+type jenkinsRequestError struct {
+	Status  string
+	Message string
+}
+
+func (e *jenkinsRequestError) Error() string {
+	if e.Message == "" {
+		return e.Status
+	}
+	return fmt.Sprintf("%s: %s", e.Status, e.Message)
+}
+
+// extractJenkinsErrorMessage extracts the message of an error page of Jenkins, which is the first line
+// of the stack trace if there is one, or the text of the page otherwise.
+func extractJenkinsErrorMessage(page string) string {
+	message := ""
+	if match := htmlPreformattedPattern.FindStringSubmatch(page); match != nil {
+		for _, line := range strings.Split(htmlLogToText(match[1]), "\n") {
+			if strings.TrimSpace(line) != "" {
+				message = strings.TrimSpace(line)
+				break
+			}
+		}
+	}
+	if message == "" {
+		message = htmlLogToText(htmlHiddenPattern.ReplaceAllString(page, ""))
+	}
+	message = strings.TrimSpace(whitespacePattern.ReplaceAllString(message, " "))
+	if len(message) > maxJenkinsErrorLength {
+		// The message is cut at the start of a rune, so that no rune is split.
+		cut := maxJenkinsErrorLength
+		for cut > 0 && !utf8.RuneStart(message[cut]) {
+			cut--
+		}
+		message = message[:cut] + "..."
+	}
+	return message
+}
+
+// postJenkinsXML posts an XML document to the given endpoint of the Jenkins server.
+// Unlike Requester.PostXML, error responses are returned as jenkinsRequestError with the message of the error page.
+func postJenkinsXML(jenkins *gojenkins.Jenkins, endpoint, document string) error {
+	_, err := postJenkinsDocument(jenkins, endpoint, "application/xml", document)
+	return err
+}
+
+// postJenkinsDocument posts a document of the given content type to the given endpoint of the Jenkins server,
+// and returns the body of the response. Error responses are returned as jenkinsRequestError.
+func postJenkinsDocument(jenkins *gojenkins.Jenkins, endpoint, contentType, document string) (_ string, err error) {
+	defer recoverJenkinsPanic(&err)
+	ar := gojenkins.NewAPIRequest(http.MethodPost, endpoint, nil)
+	if err := jenkins.Requester.SetCrumb(ar); err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest(http.MethodPost, jenkins.Requester.Base+endpoint, strings.NewReader(document))
+	if err != nil {
+		return "", err
+	}
+	for k := range ar.Headers {
+		req.Header.Set(k, ar.Headers.Get(k))
+	}
+	req.Header.Set("Content-Type", contentType)
+	if auth := jenkins.Requester.BasicAuth; auth != nil {
+		req.SetBasicAuth(auth.Username, auth.Password)
+	}
+
+	resp, err := jenkins.Requester.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxAttachedFileSize))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode < http.StatusBadRequest {
+		return string(body), nil
+	}
+
+	message := resp.Header.Get("X-Error")
+	if message == "" {
+		message = extractJenkinsErrorMessage(string(body))
+	}
+	return "", &jenkinsRequestError{Status: resp.Status, Message: message}
+}
+
+// validateConfigXML checks that a config.xml is a well-formed XML document.
+func validateConfigXML(configXML string) error {
+	// The XML 1.1 declaration Jenkins writes isn't supported by encoding/xml.
+	decoder := xml.NewDecoder(strings.NewReader(xmlDeclarationPattern.ReplaceAllString(configXML, "")))
+	hasRoot := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if _, ok := token.(xml.StartElement); ok {
+			hasRoot = true
+		}
+	}
+	if !hasRoot {
+		return errors.New("the document has no root element")
+	}
+	return nil
+}
+
+// normalizeConfigXML normalizes the line endings of a config.xml, as browsers may submit CRLF.
+func normalizeConfigXML(configXML string) string {
+	return strings.TrimRight(strings.ReplaceAll(configXML, "\r\n", "\n"), "\n") + "\n"
+}
+
+// configChecksum identifies a version of a config.xml.
+func configChecksum(configXML string) string {
+	sum := sha256.Sum256([]byte(normalizeConfigXML(configXML)))
+	return hex.EncodeToString(sum[:])
+}
+
+// diffConfigXML returns the unified diff between two versions of a config.xml, truncated to maxConfigDiffLength.
+func diffConfigXML(current, edited string) (string, error) {
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(normalizeConfigXML(current)),
+		B:        difflib.SplitLines(normalizeConfigXML(edited)),
+		FromFile: "current/config.xml",
+		ToFile:   "edited/config.xml",
+		Context:  3,
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(diff) > maxConfigDiffLength {
+		diff = diff[:strings.LastIndex(diff[:maxConfigDiffLength], "\n")+1] + "...\n"
+	}
+	return diff, nil
+}
+
+// postJobConfig uploads the config.xml of the given job as a file.
+func (p *Plugin) postJobConfig(userID, channelID, jobName string) error {
+	job, err := p.getJob(userID, jobName)
+	if err != nil {
+		return err
+	}
+	configXML, err := job.GetConfig()
+	if err != nil {
+		return errors.Wrap(err, "Error fetching the job configuration")
+	}
+
+	fileInfo, appErr := p.API.UploadFile([]byte(configXML), channelID, path.Base(jobName)+"-config.xml")
+	if appErr != nil {
+		return errors.Wrap(appErr, "Error uploading file")
+	}
+	p.createPost(userID, channelID, fmt.Sprintf("Configuration of the job '%s'", jobName), fileInfo.Id)
+	return nil
+}
+
+// createDialogForConfig opens a dialog pre-filled with the config.xml of the given job.
+// Returns a message for the user if the config.xml can't be edited in a dialog.
+func (p *Plugin) createDialogForConfig(userID, triggerID, jobName string) (string, error) {
+	job, err := p.getJob(userID, jobName)
+	if err != nil {
+		return "", err
+	}
+	configXML, err := job.GetConfig()
+	if err != nil {
+		return "", errors.Wrap(err, "Error fetching the job configuration")
+	}
+	if len(configXML) > maxConfigLength {
+		return fmt.Sprintf("The configuration of the job '%s' is too large to be edited in a dialog.", jobName), nil
+	}
+
+	dialog := model.OpenDialogRequest{
+		TriggerId: triggerID,
+		URL:       fmt.Sprintf("%s/plugins/jenkins/config", *p.API.GetConfig().ServiceSettings.SiteURL),
+		Dialog: model.Dialog{
+			Title:       fmt.Sprintf("Configuration of %s", jobName),
+			CallbackId:  userID,
+			SubmitLabel: "Show changes",
+			State:       jobName,
+			Elements: []model.DialogElement{{
+				DisplayName: "Config.xml",
+				Name:        "ConfigXml",
+				Type:        "textarea",
+				SubType:     "text",
+				Default:     configXML,
+				MaxLength:   maxConfigLength,
+			}},
+		},
+	}
+	if appErr := p.API.OpenInteractiveDialog(dialog); appErr != nil {
+		return "", errors.Wrap(appErr, "Error opening the interactive dialog")
+	}
+	return "", nil
+}
+
+// askConfigUpdateConfirmation shows the diff between the current and the edited config.xml of a job
+// and asks the user to confirm the update. Returns a message shown in the dialog if the edited config.xml
+// can't be used.
+func (p *Plugin) askConfigUpdateConfirmation(userID, channelID, jobName, edited string) (string, error) {
+	if err := validateConfigXML(edited); err != nil {
+		return fmt.Sprintf("Invalid XML: %s.", err.Error()), nil
+	}
+	job, err := p.getJob(userID, jobName)
+	if err != nil {
+		return "", err
+	}
+	current, err := job.GetConfig()
+	if err != nil {
+		return "", errors.Wrap(err, "Error fetching the job configuration")
+	}
+	diff, err := diffConfigXML(current, edited)
+	if err != nil {
+		return "", err
+	}
+	if diff == "" {
+		return "No changes have been made.", nil
+	}
+
+	p.askConfirmation(userID, channelID, &confirmationRequest{
+		Message: fmt.Sprintf("Update the configuration of the job '%s'?\n```diff\n%s```", jobName, diff),
+		Action:  configUpdate,
+		Args: map[string]string{
+			"job":      jobName,
+			"config":   edited,
+			"checksum": configChecksum(current),
+		},
+	})
+	return "", nil
+}
+
+// updateJobConfig updates the config.xml of a job, unless it has changed since the diff was shown.
+// Returns a message describing the outcome, including the validation errors of Jenkins.
+func (p *Plugin) updateJobConfig(userID, channelID, jobName, configXML, checksum string) (string, error) {
+	job, err := p.getJob(userID, jobName)
+	if err != nil {
+		return "", err
+	}
+	current, err := job.GetConfig()
+	if err != nil {
+		return "", errors.Wrap(err, "Error fetching the job configuration")
+	}
+	if configChecksum(current) != checksum {
+		return "The configuration has been changed since the changes were shown. Please edit it again.", nil
+	}
+
+	err = postJenkinsXML(job.Jenkins, job.Base+"/config.xml", configXML)
+	var requestErr *jenkinsRequestError
+	if errors.As(err, &requestErr) {
+		return fmt.Sprintf("Jenkins rejected the configuration: %s", requestErr.Error()), nil
+	}
+	if err != nil {
+		return "", errors.Wrap(err, "Error updating the job configuration")
+	}
+
+	p.createPost(userID, channelID, fmt.Sprintf("The configuration of the job '%s' has been updated.", jobName))
+	return "The configuration has been updated.", nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/waseem18/gojenkins"
+)
+
+const testConfigXML = `<?xml version='1.1' encoding='UTF-8'?>
+<project>
+  <description>Build the app</description>
+  <keepDependencies>false</keepDependencies>
+  <disabled>false</disabled>
+  <builders>
+    <hudson.tasks.Shell>
+      <command>make</command>
+    </hudson.tasks.Shell>
+  </builders>
+</project>`
+
+func TestValidateConfigXML(t *testing.T) {
+	assert.Nil(t, validateConfigXML(testConfigXML))
+	assert.Nil(t, validateConfigXML("<project/>"))
+
+	for _, invalid := range []string{"", "just text", "<project>", "<project></builders>", "<project a=b/>"} {
+		assert.NotNil(t, validateConfigXML(invalid), invalid)
+	}
+}
+
+func TestDiffConfigXML(t *testing.T) {
+	edited := strings.ReplaceAll(strings.Replace(testConfigXML, "<disabled>false</disabled>", "<disabled>true</disabled>", 1), "\n", "\r\n")
+	diff, err := diffConfigXML(testConfigXML, edited)
+	require.Nil(t, err)
+	assert.Equal(t, `--- current/config.xml
++++ edited/config.xml
+@@ -2,7 +2,7 @@
+ <project>
+   <description>Build the app</description>
+   <keepDependencies>false</keepDependencies>
+-  <disabled>false</disabled>
++  <disabled>true</disabled>
+   <builders>
+     <hudson.tasks.Shell>
+       <command>make</command>
+`, diff)
+
+	diff, err = diffConfigXML(testConfigXML, testConfigXML+"\r\n")
+	require.Nil(t, err)
+	assert.Empty(t, diff)
+	assert.Equal(t, configChecksum(testConfigXML), configChecksum(strings.ReplaceAll(testConfigXML, "\n", "\r\n")))
+
+	diff, err = diffConfigXML(testConfigXML, strings.Repeat("<!-- a long comment -->\n", 1000)+testConfigXML)
+	require.Nil(t, err)
+	assert.LessOrEqual(t, len(diff), maxConfigDiffLength+4)
+	assert.True(t, strings.HasSuffix(diff, "\n...\n"))
+}
+
+func TestExtractJenkinsErrorMessage(t *testing.T) {
+	for page, expected := range map[string]string{
+		`<html><head><title>Error</title><script>var x;</script></head><body><h2>HTTP ERROR 500</h2>
+<pre>
+
+java.io.IOException: Unable to read &lt;project&gt;
+	at hudson.model.AbstractItem.updateByXml(AbstractItem.java:860)
+</pre></body></html>`: "java.io.IOException: Unable to read <project>",
+		`<html><head><title>Jenkins</title></head><body><h1>Oops!</h1>
+<p>A problem occurred while processing the request.</p></body></html>`: "Oops! A problem occurred while processing the request.",
+		"": "",
+	} {
+		assert.Equal(t, expected, extractJenkinsErrorMessage(page))
+	}
+
+	message := extractJenkinsErrorMessage("<p>" + strings.Repeat("x", 2*maxJenkinsErrorLength) + "</p>")
+	assert.Len(t, message, maxJenkinsErrorLength+3)
+
+	for _, prefix := range []string{"", "x"} {
+		message = extractJenkinsErrorMessage("<p>" + prefix + strings.Repeat("é", maxJenkinsErrorLength) + "</p>")
+		assert.True(t, utf8.ValidString(message), message)
+		assert.True(t, strings.HasSuffix(message, "é..."), message)
+		assert.LessOrEqual(t, len(message), maxJenkinsErrorLength+3)
+	}
+}
+
+func TestPostJenkinsXMLUnreachable(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	testServer.Close()
+
+	err := postJenkinsXML(gojenkins.CreateJenkins(nil, testServer.URL), "/job/app/config.xml", "<project/>")
+	assert.Error(t, err)
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"path"
 	"reflect"
+	"regexp"
 
 	"github.com/mattermost/mattermost/server/public/model"
 	"github.com/pkg/errors"
@@ -25,6 +26,30 @@ type configuration struct {
 	EncryptionKey    string
 	ProfileImageURL  string
 	PluginsDirectory string
+	// RedactionPatterns holds additional regular expressions of secrets to redact, one per line.
+	RedactionPatterns string
+	// AdminChannelID is the channel where health alerts are posted.
+	AdminChannelID string
+	// HealthCheckUsername and HealthCheckAPIToken are the Jenkins account used by the health check.
+	HealthCheckUsername string
+	HealthCheckAPIToken string
+	// HealthCheckInterval is the number of minutes between health checks.
+	HealthCheckInterval int
+	// HealthLatencyThreshold is the response time in milliseconds above which Jenkins is considered degraded.
+	HealthLatencyThreshold int
+	// HealthQueueThreshold is the queue length from which Jenkins is considered degraded.
+	HealthQueueThreshold int
+	// UserMapping maps Jenkins users to Mattermost usernames, one jenkins-user=mattermost-username per line.
+	UserMapping string
+	// PostBuildCompletion enables the post announcing that a build triggered from Mattermost has finished.
+	PostBuildCompletion bool
+	// ShowChangesOnCompletion adds the changes of a build to the post announcing it has finished.
+	ShowChangesOnCompletion bool
+
+	// redactionPatterns are the compiled RedactionPatterns.
+	redactionPatterns []*regexp.Regexp
+	// userMapping is the parsed UserMapping.
+	userMapping map[string]string
 }
 
 // Clone shallow copies the configuration. Your implementation may require a deep copy if
@@ -92,6 +117,18 @@ func (p *Plugin) OnConfigurationChange() error {
 		return errors.Wrap(err, "failed to load plugin configuration")
 	}
 
+	redactionPatterns, err := compileRedactionPatterns(configuration.RedactionPatterns)
+	if err != nil {
+		return errors.Wrap(err, "failed to load plugin configuration")
+	}
+	configuration.redactionPatterns = redactionPatterns
+
+	userMapping, err := parseUserMapping(configuration.UserMapping)
+	if err != nil {
+		return errors.Wrap(err, "failed to load plugin configuration")
+	}
+	configuration.userMapping = userMapping
+
 	serverConfiguration := p.API.GetConfig()
 
 	p.setConfiguration(configuration, serverConfiguration)
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+)
+
+// confirmationRequest is an action which is only run once the user has confirmed it with a button.
+type confirmationRequest struct {
+	Message string
+	Action  string
+	Args    map[string]string
+}
+
+func (c *confirmationRequest) toContext() map[string]interface{} {
+	args := make(map[string]interface{})
+	for k, v := range c.Args {
+		args[k] = v
+	}
+	return map[string]interface{}{
+		"message": c.Message,
+		"action":  c.Action,
+		"args":    args,
+	}
+}
+
+// toCancelContext returns the context of the Cancel button, which only needs the message to update the post.
+// The args are left out, as they may be large, e.g. an edited config.xml.
+func (c *confirmationRequest) toCancelContext() map[string]interface{} {
+	return map[string]interface{}{"message": c.Message}
+}
+
+func confirmationRequestFromContext(context map[string]interface{}) *confirmationRequest {
+	request := &confirmationRequest{Args: make(map[string]string)}
+	request.Message, _ = context["message"].(string)
+	request.Action, _ = context["action"].(string)
+	args, _ := context["args"].(map[string]interface{})
+	for k, v := range args {
+		request.Args[k], _ = v.(string)
+	}
+	return request
+}
+
+// askConfirmation sends an ephemeral post describing the action, with buttons to confirm or cancel it.
+func (p *Plugin) askConfirmation(userID, channelID string, request *confirmationRequest) {
+	siteURL := *p.API.GetConfig().ServiceSettings.SiteURL
+	attachment := generateSlackAttachment(request.Message)
+	attachment.Actions = []*model.PostAction{
+		{
+			Name:  "Confirm",
+			Type:  model.PostActionTypeButton,
+			Style: "danger",
+			Integration: &model.PostActionIntegration{
+				URL:     fmt.Sprintf("%s/plugins/jenkins/confirm/confirm", siteURL),
+				Context: request.toContext(),
+			},
+		},
+		{
+			Name: "Cancel",
+			Type: model.PostActionTypeButton,
+			Integration: &model.PostActionIntegration{
+				URL:     fmt.Sprintf("%s/plugins/jenkins/confirm/cancel", siteURL),
+				Context: request.toCancelContext(),
+			},
+		},
+	}
+	post := &model.Post{
+		UserId:    p.botUserID,
+		ChannelId: channelID,
+		Type:      model.PostTypeDefault,
+		Props: map[string]interface{}{
+			"attachments": []*model.SlackAttachment{attachment},
+		},
+	}
+	p.API.SendEphemeralPost(userID, post)
+}
+
+// handleConfirmation runs the action of a confirmation post once it has been confirmed, and returns
+// the post replacing the confirmation post.
+func (p *Plugin) handleConfirmation(userID, decision string, action *model.PostActionIntegrationRequest) (*model.Post, error) {
+	request := confirmationRequestFromContext(action.Context)
+	attachment := generateSlackAttachment(request.Message)
+	update := &model.Post{}
+	update.AddProp("attachments", []*model.SlackAttachment{attachment})
+
+	if decision != "confirm" {
+		attachment.Footer = "Cancelled."
+		return update, nil
+	}
+
+	msg, err := p.runConfirmedAction(userID, action.ChannelId, request)
+	if err != nil {
+		attachment.Footer = "Encountered an error."
+		return update, err
+	}
+	attachment.Footer = msg
+	return update, nil
+}
+
+// runConfirmedAction runs a confirmed action. Returns a message describing the outcome.
+func (p *Plugin) runConfirmedAction(userID, channelID string, request *confirmationRequest) (string, error) {
+	switch request.Action {
+	case lifecycleQuietDown, lifecycleCancelQuietDown, lifecycleSafeRestart, lifecycleRestart, lifecycleExit:
+		return p.runLifecycleAction(userID, channelID, request.Action, request.Args["reason"])
+	case pluginsInstall, pluginsUpdate:
+		specs, err := parsePluginSpecs(strings.Fields(request.Args["plugins"]))
+		if err != nil {
+			return "", err
+		}
+		return p.installPlugins(userID, channelID, specs)
+	case pluginsEnable, pluginsDisable:
+		return p.togglePlugin(userID, channelID, request.Action, request.Args["name"])
+	case bulkJobs:
+		return p.runBulkJobs(userID, channelID, request.Args["operation"], request.Args["pattern"], strings.Split(request.Args["jobs"], "\n"))
+	case configUpdate:
+		return p.updateJobConfig(userID, channelID, request.Args["job"], request.Args["config"], request.Args["checksum"])
+	default:
+		return "", errors.Errorf("unknown action %q", request.Action)
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfirmationRequestContext(t *testing.T) {
+	request := &confirmationRequest{
+		Message: "Restart Jenkins now?",
+		Action:  lifecycleRestart,
+		Args:    map[string]string{"reason": "Upgrade"},
+	}
+
+	// The context is sent to the client and back as JSON.
+	data, err := json.Marshal(request.toContext())
+	require.Nil(t, err)
+	var context map[string]interface{}
+	require.Nil(t, json.Unmarshal(data, &context))
+
+	assert.Equal(t, request, confirmationRequestFromContext(context))
+	assert.Equal(t, &confirmationRequest{Args: map[string]string{}}, confirmationRequestFromContext(nil))
+
+	assert.Equal(t, &confirmationRequest{Message: "Restart Jenkins now?", Args: map[string]string{}},
+		confirmationRequestFromContext(request.toCancelContext()))
+}
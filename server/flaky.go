@@ -0,0 +1,375 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/waseem18/gojenkins"
+)
+
+const (
+	testHistoryKeyPrefix = "testhistory_"
+	// testHistorySize is the number of builds kept in the test history of a job.
+	testHistorySize = 30
+	// flakyMinFlips is the number of times a test has to flip between passing and failing
+	// within the test history to be considered flaky.
+	flakyMinFlips       = 2
+	maxFlakyTestsInPost = 15
+	// testHistoryUpdateAttempts is the number of times an update of a test history is tried
+	// when the history is changed concurrently, e.g. by two builds of the job completing at once.
+	testHistoryUpdateAttempts = 5
+
+	testOutcomePassed  = 'P'
+	testOutcomeFailed  = 'F'
+	testOutcomeMissing = '-'
+)
+
+// testHistory is the rolling history of test outcomes of a job.
+// Outcomes maps the full name of a test to one outcome character per build in Builds.
+type testHistory struct {
+	Builds   []int64
+	Outcomes map[string]string
+}
+
+// flakyTest describes a test which flipped between passing and failing within the test history.
+type flakyTest struct {
+	Name     string
+	Flips    int
+	Runs     int
+	Outcomes string
+}
+
+func (f *flakyTest) flipRate() float64 {
+	if f.Runs < 2 {
+		return 0
+	}
+	return float64(f.Flips) / float64(f.Runs-1)
+}
+
+func newTestHistory() *testHistory {
+	return &testHistory{Outcomes: make(map[string]string)}
+}
+
+// buildIndex returns the position of the build in the history, or the position at which it has to be
+// inserted, and whether the build is missing from the history and recent enough to be added.
+func (h *testHistory) buildIndex(number int64) (int, bool) {
+	idx := sort.Search(len(h.Builds), func(i int) bool { return h.Builds[i] >= number })
+	if idx < len(h.Builds) && h.Builds[idx] == number {
+		return idx, false
+	}
+	if idx == 0 && len(h.Builds) >= testHistorySize {
+		return idx, false
+	}
+	return idx, true
+}
+
+// addBuild records the outcomes of a test report and drops the oldest builds which fall out of the history.
+// Returns whether the build has been added.
+func (h *testHistory) addBuild(number int64, report *testReport) bool {
+	idx, ok := h.buildIndex(number)
+	if !ok {
+		return false
+	}
+
+	outcomes := make(map[string]byte)
+	for _, s := range report.Suites {
+		for _, c := range s.Cases {
+			switch {
+			case c.isFailed():
+				outcomes[c.fullName()] = testOutcomeFailed
+			case c.Status == testCaseStatusSkipped:
+				outcomes[c.fullName()] = testOutcomeMissing
+			default:
+				outcomes[c.fullName()] = testOutcomePassed
+			}
+		}
+	}
+
+	for name, history := range h.Outcomes {
+		outcome, ok := outcomes[name]
+		if !ok {
+			outcome = testOutcomeMissing
+		}
+		h.Outcomes[name] = history[:idx] + string(outcome) + history[idx:]
+		delete(outcomes, name)
+	}
+	for name, outcome := range outcomes {
+		missing := string(testOutcomeMissing)
+		h.Outcomes[name] = strings.Repeat(missing, idx) + string(outcome) + strings.Repeat(missing, len(h.Builds)-idx)
+	}
+	h.Builds = append(h.Builds[:idx], append([]int64{number}, h.Builds[idx:]...)...)
+
+	if drop := len(h.Builds) - testHistorySize; drop > 0 {
+		h.Builds = h.Builds[drop:]
+		for name, history := range h.Outcomes {
+			history = history[drop:]
+			if strings.Trim(history, string(testOutcomeMissing)) == "" {
+				delete(h.Outcomes, name)
+				continue
+			}
+			h.Outcomes[name] = history
+		}
+	}
+	return true
+}
+
+// countFlips returns the number of times the outcomes switch between passing and failing,
+// and the number of builds in which the test ran.
+func countFlips(outcomes string) (int, int) {
+	flips, runs := 0, 0
+	var previous byte
+	for i := 0; i < len(outcomes); i++ {
+		outcome := outcomes[i]
+		if outcome == testOutcomeMissing {
+			continue
+		}
+		runs++
+		if previous != 0 && outcome != previous {
+			flips++
+		}
+		previous = outcome
+	}
+	return flips, runs
+}
+
+// flakyTests returns the flaky tests of the history, flakiest first.
+func (h *testHistory) flakyTests() []flakyTest {
+	flaky := []flakyTest{}
+	for name, outcomes := range h.Outcomes {
+		flips, runs := countFlips(outcomes)
+		if flips >= flakyMinFlips {
+			flaky = append(flaky, flakyTest{Name: name, Flips: flips, Runs: runs, Outcomes: outcomes})
+		}
+	}
+	sort.Slice(flaky, func(i, j int) bool {
+		if flaky[i].Flips != flaky[j].Flips {
+			return flaky[i].Flips > flaky[j].Flips
+		}
+		if flaky[i].flipRate() != flaky[j].flipRate() {
+			return flaky[i].flipRate() > flaky[j].flipRate()
+		}
+		return flaky[i].Name < flaky[j].Name
+	})
+	return flaky
+}
+
+func testHistoryKey(jobName string) string {
+	// Job names can be longer than the maximum length of a KV key.
+	return fmt.Sprintf("%s%x", testHistoryKeyPrefix, sha256.Sum256([]byte(jobName)))
+}
+
+func decodeTestHistory(historyBytes []byte) (*testHistory, error) {
+	history := newTestHistory()
+	if historyBytes == nil {
+		return history, nil
+	}
+	if err := json.Unmarshal(historyBytes, history); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+func (p *Plugin) getTestHistory(jobName string) (*testHistory, error) {
+	historyBytes, appErr := p.API.KVGet(testHistoryKey(jobName))
+	if appErr != nil {
+		return nil, appErr
+	}
+	return decodeTestHistory(historyBytes)
+}
+
+// updateTestHistory applies update to the test history of the job and stores the history if update
+// returns true. The history is only stored if it hasn't been changed in the meantime; otherwise
+// update is applied to the changed history again.
+func (p *Plugin) updateTestHistory(jobName string, update func(history *testHistory) bool) (*testHistory, error) {
+	key := testHistoryKey(jobName)
+	for attempt := 0; attempt < testHistoryUpdateAttempts; attempt++ {
+		oldBytes, appErr := p.API.KVGet(key)
+		if appErr != nil {
+			return nil, appErr
+		}
+		history, err := decodeTestHistory(oldBytes)
+		if err != nil {
+			return nil, err
+		}
+		if !update(history) {
+			return history, nil
+		}
+
+		newBytes, err := json.Marshal(history)
+		if err != nil {
+			return nil, err
+		}
+		stored, appErr := p.API.KVCompareAndSet(key, oldBytes, newBytes)
+		if appErr != nil {
+			return nil, appErr
+		}
+		if stored {
+			return history, nil
+		}
+	}
+	return nil, errors.Errorf("the test history has been changed concurrently %d times", testHistoryUpdateAttempts)
+}
+
+// recordTestReport adds the test report of a completed build to the test history of the job.
+func (p *Plugin) recordTestReport(jobName string, buildNumber int64, report *testReport) error {
+	_, err := p.updateTestHistory(jobName, func(history *testHistory) bool {
+		return history.addBuild(buildNumber, report)
+	})
+	return err
+}
+
+// getKnownFlakyTests returns the flaky tests of the job by their full name.
+// Errors are logged and result in an empty map, as the information is only used to annotate posts.
+func (p *Plugin) getKnownFlakyTests(jobName string) map[string]flakyTest {
+	flaky := make(map[string]flakyTest)
+	history, err := p.getTestHistory(jobName)
+	if err != nil {
+		p.API.LogWarn("Error fetching test history", "job_name", jobName, "err", err.Error())
+		return flaky
+	}
+	for _, f := range history.flakyTests() {
+		flaky[f.Name] = f
+	}
+	return flaky
+}
+
+// syncTestHistory records the test reports of the recent completed builds of the job
+// which are missing from its test history.
+func (p *Plugin) syncTestHistory(userID, jobName string) (*testHistory, error) {
+	job, jobErr := p.getJob(userID, jobName)
+	if jobErr != nil {
+		return nil, jobErr
+	}
+
+	var builds struct {
+		Builds []struct {
+			Number   int64 `json:"number"`
+			Building bool  `json:"building"`
+		} `json:"builds"`
+	}
+	query := map[string]string{"tree": fmt.Sprintf("builds[number,building]{0,%d}", testHistorySize)}
+	resp, err := job.Jenkins.Requester.GetJSON(job.Base, &builds, query)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error fetching builds")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("Error fetching builds: %s", resp.Status)
+	}
+
+	history, err := p.getTestHistory(jobName)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error fetching test history")
+	}
+
+	sort.Slice(builds.Builds, func(i, j int) bool { return builds.Builds[i].Number > builds.Builds[j].Number })
+	reports := make(map[int64]*testReport)
+	for _, b := range builds.Builds {
+		if _, missing := history.buildIndex(b.Number); b.Building || !missing {
+			continue
+		}
+		report, err := fetchTestReport(job.Jenkins, fmt.Sprintf("%s/%d", job.Base, b.Number))
+		if err != nil {
+			return nil, err
+		}
+		if report != nil {
+			reports[b.Number] = report
+		}
+	}
+	if len(reports) == 0 {
+		return history, nil
+	}
+
+	// The reports are fetched before updating, so that the history can be updated again quickly
+	// if it has been changed in the meantime.
+	history, err = p.updateTestHistory(jobName, func(history *testHistory) bool {
+		changed := false
+		for _, b := range builds.Builds {
+			if report, ok := reports[b.Number]; ok && history.addBuild(b.Number, report) {
+				changed = true
+			}
+		}
+		return changed
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "Error storing test history")
+	}
+	return history, nil
+}
+
+// formatFlakyAnnotation renders the note added to failures of known flaky tests.
+func formatFlakyAnnotation(f flakyTest) string {
+	return fmt.Sprintf(":repeat: _known flaky: flipped %d times in the last %d runs_", f.Flips, f.Runs)
+}
+
+// formatFlakyFailures renders the failing tests of the report which are known to be flaky,
+// or an empty string if none of them is.
+func formatFlakyFailures(report *testReport, flaky map[string]flakyTest) string {
+	var lines []string
+	failed := report.failedCases()
+	for _, c := range failed {
+		if f, ok := flaky[c.fullName()]; ok {
+			lines = append(lines, fmt.Sprintf("* `%s` %s", c.fullName(), formatFlakyAnnotation(f)))
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d of %d failing test(s) known to be flaky:\n", len(lines), len(failed))
+	if len(lines) > maxFailingTestsInPost {
+		fmt.Fprintf(&sb, "%s\n* ...and %d more", strings.Join(lines[:maxFailingTestsInPost], "\n"), len(lines)-maxFailingTestsInPost)
+	} else {
+		sb.WriteString(strings.Join(lines, "\n"))
+	}
+	return sb.String()
+}
+
+// formatFlakyTests renders the flakiest tests of a job as a markdown table.
+func formatFlakyTests(jobName string, history *testHistory) string {
+	flaky := history.flakyTests()
+	if len(flaky) == 0 {
+		return fmt.Sprintf("No flaky tests found in the last %d build(s) of the job '%s'.", len(history.Builds), jobName)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Flaky tests in the last %d build(s) of the job '%s'\n\n", len(history.Builds), jobName)
+	sb.WriteString("| Test | Flips | Flip rate | Outcomes (oldest first) |\n")
+	sb.WriteString("|:-----|:------|:----------|:------------------------|\n")
+	for i, f := range flaky {
+		if i == maxFlakyTestsInPost {
+			break
+		}
+		fmt.Fprintf(&sb, "| `%s` | %d | %.0f%% | `%s` |\n", escapeTableCell(f.Name), f.Flips, f.flipRate()*100, f.Outcomes)
+	}
+	if len(flaky) > maxFlakyTestsInPost {
+		fmt.Fprintf(&sb, "\n...and %d more flaky test(s).\n", len(flaky)-maxFlakyTestsInPost)
+	}
+	return sb.String()
+}
+
+// postFlakyTests updates the test history of the job and creates a post with its flakiest tests.
+func (p *Plugin) postFlakyTests(userID, channelID, jobName string) error {
+	history, err := p.syncTestHistory(userID, jobName)
+	if err != nil {
+		return err
+	}
+	p.createPost(userID, channelID, formatFlakyTests(jobName, history))
+	return nil
+}
+
+// recordCompletedBuildReport records the test report of the build in the test history
+// if the build has completed.
+func (p *Plugin) recordCompletedBuildReport(jobName string, build *gojenkins.Build, report *testReport) {
+	if build.Raw.Building {
+		return
+	}
+	if err := p.recordTestReport(jobName, build.GetBuildNumber(), report); err != nil {
+		p.API.LogWarn("Error recording test report", "job_name", jobName, "err", err.Error())
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func reportWithOutcomes(outcomes map[string]string) *testReport {
+	suite := testSuite{}
+	for name, status := range outcomes {
+		suite.Cases = append(suite.Cases, testCase{ClassName: "A", Name: name, Status: status})
+	}
+	return &testReport{Suites: []testSuite{suite}}
+}
+
+func TestTestHistoryAddBuild(t *testing.T) {
+	h := newTestHistory()
+	assert.True(t, h.addBuild(2, reportWithOutcomes(map[string]string{"one": "PASSED", "two": "FAILED"})))
+	assert.True(t, h.addBuild(4, reportWithOutcomes(map[string]string{"one": "REGRESSION", "three": "PASSED"})))
+	// Older builds are inserted in order.
+	assert.True(t, h.addBuild(3, reportWithOutcomes(map[string]string{"one": "FIXED", "two": "SKIPPED"})))
+	assert.False(t, h.addBuild(3, reportWithOutcomes(map[string]string{"one": "FAILED"})))
+
+	assert.Equal(t, []int64{2, 3, 4}, h.Builds)
+	assert.Equal(t, map[string]string{
+		"A.one":   "PPF",
+		"A.two":   "F--",
+		"A.three": "--P",
+	}, h.Outcomes)
+}
+
+func TestRecordTestReportConcurrently(t *testing.T) {
+	p := &Plugin{}
+	api := &plugintest.API{}
+	p.SetAPI(api)
+
+	// Another build is recorded between reading and storing the history.
+	concurrent := newTestHistory()
+	concurrent.addBuild(2, reportWithOutcomes(map[string]string{"one": "PASSED"}))
+	concurrentBytes, err := json.Marshal(concurrent)
+	require.NoError(t, err)
+
+	key := testHistoryKey("app")
+	api.On("KVGet", key).Return(nil, nil).Once()
+	api.On("KVGet", key).Return(concurrentBytes, nil).Once()
+	api.On("KVCompareAndSet", key, []byte(nil), mock.Anything).Return(false, nil).Once()
+	var stored []byte
+	api.On("KVCompareAndSet", key, concurrentBytes, mock.Anything).Run(func(args mock.Arguments) {
+		stored = args.Get(2).([]byte)
+	}).Return(true, nil).Once()
+
+	require.NoError(t, p.recordTestReport("app", 3, reportWithOutcomes(map[string]string{"one": "FAILED"})))
+	history, err := decodeTestHistory(stored)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{2, 3}, history.Builds)
+	assert.Equal(t, "PF", history.Outcomes["A.one"])
+	api.AssertExpectations(t)
+}
+
+func TestTestHistoryWindow(t *testing.T) {
+	h := newTestHistory()
+	h.addBuild(1, reportWithOutcomes(map[string]string{"removed": "PASSED"}))
+	for i := int64(2); i <= testHistorySize+1; i++ {
+		h.addBuild(i, reportWithOutcomes(map[string]string{"kept": "PASSED"}))
+	}
+
+	assert.Len(t, h.Builds, testHistorySize)
+	assert.Equal(t, int64(2), h.Builds[0])
+	assert.NotContains(t, h.Outcomes, "A.removed")
+	assert.Len(t, h.Outcomes["A.kept"], testHistorySize)
+
+	// Builds older than a full history are ignored.
+	assert.False(t, h.addBuild(1, reportWithOutcomes(map[string]string{"kept": "FAILED"})))
+}
+
+func TestFlakyTests(t *testing.T) {
+	assertFlips := func(outcomes string, expectedFlips, expectedRuns int) {
+		flips, runs := countFlips(outcomes)
+		assert.Equal(t, expectedFlips, flips, outcomes)
+		assert.Equal(t, expectedRuns, runs, outcomes)
+	}
+	assertFlips("PPPP", 0, 4)
+	assertFlips("PPFF", 1, 4)
+	assertFlips("PF-P", 2, 3)
+	assertFlips("FPFPF", 4, 5)
+	assertFlips("---", 0, 0)
+
+	h := &testHistory{
+		Builds: []int64{1, 2, 3, 4, 5},
+		Outcomes: map[string]string{
+			"stable":   "PPPPP",
+			"broken":   "PPFFF",
+			"flaky":    "PFP-P",
+			"flakiest": "FPFPF",
+		},
+	}
+	flaky := h.flakyTests()
+	assert.Len(t, flaky, 2)
+	assert.Equal(t, "flakiest", flaky[0].Name)
+	assert.Equal(t, 1.0, flaky[0].flipRate())
+	assert.Equal(t, "flaky", flaky[1].Name)
+	assert.Equal(t, 2.0/3.0, flaky[1].flipRate())
+
+	msg := formatFlakyTests("job1", h)
+	assert.Contains(t, msg, "Flaky tests in the last 5 build(s) of the job 'job1'")
+	assert.Contains(t, msg, "| `flakiest` | 4 | 100% | `FPFPF` |")
+
+	assert.Equal(t, "No flaky tests found in the last 0 build(s) of the job 'job1'.", formatFlakyTests("job1", newTestHistory()))
+}
+
+func TestFormatTestReportSummaryWithFlakyTests(t *testing.T) {
+	report := reportWithOutcomes(map[string]string{"flaky": "FAILED"})
+	report.FailCount = 1
+	msg := formatTestReportSummary("job1", 3, "url", report, map[string]flakyTest{
+		"A.flaky": {Name: "A.flaky", Flips: 3, Runs: 10},
+	})
+	assert.Contains(t, msg, "1. `A.flaky` :repeat: _known flaky: flipped 3 times in the last 10 runs_\n")
+}
+
+func TestFormatFlakyFailures(t *testing.T) {
+	report := &testReport{Suites: []testSuite{{Cases: []testCase{
+		{ClassName: "A", Name: "stable", Status: "FAILED"},
+		{ClassName: "A", Name: "flaky", Status: "REGRESSION"},
+		{ClassName: "A", Name: "passing", Status: "PASSED"},
+	}}}}
+	flaky := map[string]flakyTest{
+		"A.flaky":   {Name: "A.flaky", Flips: 3, Runs: 10},
+		"A.passing": {Name: "A.passing", Flips: 2, Runs: 10},
+	}
+	assert.Equal(t, "1 of 2 failing test(s) known to be flaky:\n* `A.flaky` :repeat: _known flaky: flipped 3 times in the last 10 runs_",
+		formatFlakyFailures(report, flaky))
+
+	assert.Equal(t, "", formatFlakyFailures(report, map[string]flakyTest{}))
+
+	report = &testReport{Suites: []testSuite{{}}}
+	flaky = map[string]flakyTest{}
+	for i := 0; i < maxFailingTestsInPost+2; i++ {
+		name := fmt.Sprintf("test%d", i)
+		report.Suites[0].Cases = append(report.Suites[0].Cases, testCase{Name: name, Status: "FAILED"})
+		flaky[name] = flakyTest{Name: name, Flips: 2, Runs: 5}
+	}
+	formatted := formatFlakyFailures(report, flaky)
+	assert.Contains(t, formatted, "7 of 7 failing test(s) known to be flaky:\n")
+	assert.Contains(t, formatted, "`test4`")
+	assert.NotContains(t, formatted, "`test5`")
+	assert.Contains(t, formatted, "\n* ...and 2 more")
+}
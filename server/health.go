@@ -0,0 +1,433 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/pluginapi/cluster"
+	"github.com/pkg/errors"
+	"github.com/waseem18/gojenkins"
+)
+
+const (
+	healthCheckJobKey = "health_check"
+	healthStatusKey   = "health_status"
+	healthHistoryKey  = "health_history"
+
+	// healthCheckTimeout is the time after which a probe of the Jenkins server fails.
+	healthCheckTimeout = 10 * time.Second
+	// maxHealthHistory is the number of state changes kept in the health history.
+	maxHealthHistory = 20
+
+	defaultHealthCheckInterval    = 5
+	defaultHealthLatencyThreshold = 2000
+	defaultHealthQueueThreshold   = 20
+)
+
+type healthState string
+
+const (
+	healthUp       healthState = "up"
+	healthDegraded healthState = "degraded"
+	healthDown     healthState = "down"
+)
+
+func (s healthState) icon() string {
+	switch s {
+	case healthDown:
+		return ":red_circle:"
+	case healthDegraded:
+		return ":warning:"
+	default:
+		return ":large_green_circle:"
+	}
+}
+
+// healthThresholds are the limits above which the Jenkins server is considered degraded.
+type healthThresholds struct {
+	LatencyMillis int64
+	QueueLength   int
+}
+
+// healthCheck is the outcome of a probe of the Jenkins server.
+type healthCheck struct {
+	State     healthState `json:"state"`
+	Problems  []string    `json:"problems"`
+	CheckedAt int64       `json:"checked_at"`
+	// Since is the time of the check which first saw the current state.
+	Since int64 `json:"since"`
+
+	Reachable     bool   `json:"reachable"`
+	Error         string `json:"error,omitempty"`
+	LatencyMillis int64  `json:"latency_millis"`
+	// Restricted is set if the health check account isn't allowed to read the state of the server,
+	// in which case only its reachability and latency are known.
+	Restricted     bool `json:"restricted"`
+	QuietingDown   bool `json:"quieting_down"`
+	BusyExecutors  int  `json:"busy_executors"`
+	TotalExecutors int  `json:"total_executors"`
+	QueueLength    int  `json:"queue_length"`
+
+	// session is the ID Jenkins gives to each of its runs, which changes when it restarts.
+	session string
+}
+
+// healthEvent is a state change of the Jenkins server.
+type healthEvent struct {
+	State    healthState `json:"state"`
+	Problems []string    `json:"problems"`
+	Time     int64       `json:"time"`
+}
+
+func (c *configuration) healthCheckInterval() time.Duration {
+	if c.HealthCheckInterval <= 0 {
+		return defaultHealthCheckInterval * time.Minute
+	}
+	return time.Duration(c.HealthCheckInterval) * time.Minute
+}
+
+func (c *configuration) healthThresholds() healthThresholds {
+	thresholds := healthThresholds{
+		LatencyMillis: int64(c.HealthLatencyThreshold),
+		QueueLength:   c.HealthQueueThreshold,
+	}
+	if thresholds.LatencyMillis <= 0 {
+		thresholds.LatencyMillis = defaultHealthLatencyThreshold
+	}
+	if thresholds.QueueLength <= 0 {
+		thresholds.QueueLength = defaultHealthQueueThreshold
+	}
+	return thresholds
+}
+
+// evaluateHealth sets the state of the check and the problems found from the probed values.
+func evaluateHealth(check *healthCheck, thresholds healthThresholds) {
+	check.Problems = []string{}
+	if !check.Reachable {
+		check.State = healthDown
+		check.Problems = append(check.Problems, check.Error)
+		return
+	}
+
+	if check.LatencyMillis > thresholds.LatencyMillis {
+		check.Problems = append(check.Problems, fmt.Sprintf("Slow response: %d ms", check.LatencyMillis))
+	}
+	if check.QuietingDown {
+		check.Problems = append(check.Problems, "Preparing for shutdown, no new builds are started")
+	}
+	if check.TotalExecutors > 0 && check.BusyExecutors >= check.TotalExecutors {
+		check.Problems = append(check.Problems, fmt.Sprintf("All %d executors are busy", check.TotalExecutors))
+	}
+	if check.QueueLength >= thresholds.QueueLength {
+		check.Problems = append(check.Problems, fmt.Sprintf("%d builds in queue", check.QueueLength))
+	}
+
+	check.State = healthUp
+	if len(check.Problems) > 0 {
+		check.State = healthDegraded
+	}
+}
+
+// healthChangeMessage returns the message to post when the state of the server differs from the previous check.
+// No message is returned for a first check which finds the server up.
+func healthChangeMessage(previous, current *healthCheck) (string, bool) {
+	if previous == nil && current.State == healthUp {
+		return "", false
+	}
+	if previous != nil && previous.State == current.State {
+		return "", false
+	}
+
+	switch current.State {
+	case healthDown:
+		return fmt.Sprintf("%s Jenkins is down: %s", current.State.icon(), strings.Join(current.Problems, ", ")), true
+	case healthDegraded:
+		return fmt.Sprintf("%s Jenkins is degraded: %s", current.State.icon(), strings.Join(current.Problems, ", ")), true
+	default:
+		downtime := time.Duration(current.CheckedAt-previous.Since) * time.Millisecond
+		return fmt.Sprintf("%s Jenkins has recovered after being %s for %s.", current.State.icon(), previous.State, formatDuration(downtime)), true
+	}
+}
+
+// appendHealthEvent adds the state of the check to the history, keeping the most recent events.
+func appendHealthEvent(history []healthEvent, check *healthCheck) []healthEvent {
+	history = append(history, healthEvent{State: check.State, Problems: check.Problems, Time: check.CheckedAt})
+	if len(history) > maxHealthHistory {
+		history = history[len(history)-maxHealthHistory:]
+	}
+	return history
+}
+
+// formatHealth renders the current state of the server and the recent state changes.
+func formatHealth(check *healthCheck, history []healthEvent, now time.Time) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s Jenkins is **%s** since %s ago (checked %s ago)\n\n",
+		check.State.icon(),
+		check.State,
+		formatDuration(now.Sub(time.UnixMilli(check.Since))),
+		formatDuration(now.Sub(time.UnixMilli(check.CheckedAt))),
+	)
+	for _, problem := range check.Problems {
+		fmt.Fprintf(&sb, "* %s\n", problem)
+	}
+
+	if check.Reachable {
+		sb.WriteString("\n| Response time | Preparing for shutdown | Busy executors | Queue length |\n")
+		sb.WriteString("|:--------------|:-----------------------|:---------------|:-------------|\n")
+		if check.Restricted {
+			fmt.Fprintf(&sb, "| %d ms | - | - | - |\n", check.LatencyMillis)
+			sb.WriteString("\nThe health check account isn't allowed to read the state of the server, so only its reachability is checked.\n")
+		} else {
+			quietingDown := "No"
+			if check.QuietingDown {
+				quietingDown = "Yes"
+			}
+			fmt.Fprintf(&sb, "| %d ms | %s | %d/%d | %d |\n", check.LatencyMillis, quietingDown, check.BusyExecutors, check.TotalExecutors, check.QueueLength)
+		}
+	}
+
+	if len(history) > 0 {
+		sb.WriteString("\n**History**\n\n")
+		sb.WriteString("| Time | State | Details |\n")
+		sb.WriteString("|:-----|:------|:--------|\n")
+		for i := len(history) - 1; i >= 0; i-- {
+			event := history[i]
+			fmt.Fprintf(&sb, "| %s | %s %s | %s |\n",
+				time.UnixMilli(event.Time).UTC().Format("2006-01-02 15:04"),
+				event.State.icon(),
+				event.State,
+				escapeTableCell(strings.Join(event.Problems, ", ")),
+			)
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// getHealthCheckClient creates a Jenkins client with the health check account configured by the admin.
+// Without an account, the server is probed anonymously.
+func (p *Plugin) getHealthCheckClient() *gojenkins.Jenkins {
+	config := p.getConfiguration()
+	client := &http.Client{Timeout: healthCheckTimeout}
+	if config.HealthCheckUsername == "" {
+		return gojenkins.CreateJenkins(client, config.JenkinsURL)
+	}
+	return gojenkins.CreateJenkins(client, config.JenkinsURL, config.HealthCheckUsername, config.HealthCheckAPIToken)
+}
+
+// probeJenkins checks the reachability and the response time of the Jenkins server, and reads
+// its quiet-down mode, executors and queue length if the account is allowed to.
+func probeJenkins(jenkins *gojenkins.Jenkins) *healthCheck {
+	check := &healthCheck{}
+
+	req, err := http.NewRequest(http.MethodGet, jenkins.Requester.Base+"/api/json?tree=quietingDown", nil)
+	if err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	if auth := jenkins.Requester.BasicAuth; auth != nil {
+		req.SetBasicAuth(auth.Username, auth.Password)
+	}
+
+	start := time.Now()
+	resp, err := jenkins.Requester.Client.Do(req)
+	check.LatencyMillis = time.Since(start).Milliseconds()
+	if err != nil {
+		check.Error = fmt.Sprintf("Jenkins is unreachable: %s", err.Error())
+		return check
+	}
+	defer resp.Body.Close()
+	check.session = resp.Header.Get("X-Jenkins-Session")
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized, http.StatusForbidden:
+		check.Reachable = true
+		check.Restricted = true
+		return check
+	default:
+		check.Error = fmt.Sprintf("Jenkins responded with %s", resp.Status)
+		return check
+	}
+	check.Reachable = true
+
+	var root struct {
+		QuietingDown bool `json:"quietingDown"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&root); err != nil {
+		check.Restricted = true
+		return check
+	}
+	check.QuietingDown = root.QuietingDown
+
+	var computers struct {
+		BusyExecutors  int `json:"busyExecutors"`
+		TotalExecutors int `json:"totalExecutors"`
+	}
+	var queue struct {
+		Items []struct{} `json:"items"`
+	}
+	if err := getJenkinsJSON(jenkins, "/computer/api/json?tree=busyExecutors,totalExecutors", &computers); err != nil {
+		check.Restricted = true
+		return check
+	}
+	if err := getJenkinsJSON(jenkins, "/queue/api/json?tree=items[id]", &queue); err != nil {
+		check.Restricted = true
+		return check
+	}
+	check.BusyExecutors = computers.BusyExecutors
+	check.TotalExecutors = computers.TotalExecutors
+	check.QueueLength = len(queue.Items)
+	return check
+}
+
+// scheduleHealthCheck starts the background job probing the Jenkins server.
+// The job runs on a single server of a cluster.
+func (p *Plugin) scheduleHealthCheck() error {
+	job, err := cluster.Schedule(p.API, healthCheckJobKey, p.nextHealthCheckWait, p.runHealthCheck)
+	if err != nil {
+		return errors.Wrap(err, "failed to schedule the health check")
+	}
+	p.healthCheckJob = job
+	return nil
+}
+
+// nextHealthCheckWait returns the time to wait until the next health check, so that changes
+// of the configured interval are applied without reactivating the plugin.
+func (p *Plugin) nextHealthCheckWait(now time.Time, metadata cluster.JobMetadata) time.Duration {
+	wait := p.getConfiguration().healthCheckInterval() - now.Sub(metadata.LastFinished)
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
+
+// runHealthCheck probes the Jenkins server, stores the outcome and posts state changes to the admin channel.
+func (p *Plugin) runHealthCheck() {
+	if _, err := p.checkHealth(); err != nil {
+		p.API.LogWarn("Error checking the health of Jenkins", "err", err.Error())
+	}
+}
+
+// checkHealth probes the Jenkins server and records the outcome. Returns the outcome of the check.
+func (p *Plugin) checkHealth() (*healthCheck, error) {
+	config := p.getConfiguration()
+	if config.JenkinsURL == "" {
+		return nil, errors.New("Jenkins URL is not configured")
+	}
+
+	previous, err := p.getHealthStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	current := probeJenkins(p.getHealthCheckClient())
+	current.CheckedAt = time.Now().UnixMilli()
+	evaluateHealth(current, config.healthThresholds())
+	current.Since = current.CheckedAt
+	if previous != nil && previous.State == current.State {
+		current.Since = previous.Since
+	}
+
+	if err := p.storeJSON(healthStatusKey, current); err != nil {
+		return nil, errors.Wrap(err, "Error storing the health status")
+	}
+
+	if previous != nil && previous.State == current.State {
+		return current, nil
+	}
+	history, err := p.getHealthHistory()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.storeJSON(healthHistoryKey, appendHealthEvent(history, current)); err != nil {
+		return nil, errors.Wrap(err, "Error storing the health history")
+	}
+	if msg, changed := healthChangeMessage(previous, current); changed {
+		p.postAdminMessage(msg)
+	}
+	return current, nil
+}
+
+// getHealthStatus returns the outcome of the last health check, or nil if the server hasn't been checked yet.
+func (p *Plugin) getHealthStatus() (*healthCheck, error) {
+	data, appErr := p.API.KVGet(healthStatusKey)
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "Error fetching the health status")
+	}
+	if data == nil {
+		return nil, nil
+	}
+	var check healthCheck
+	if err := json.Unmarshal(data, &check); err != nil {
+		return nil, errors.Wrap(err, "Error decoding the health status")
+	}
+	return &check, nil
+}
+
+func (p *Plugin) getHealthHistory() ([]healthEvent, error) {
+	data, appErr := p.API.KVGet(healthHistoryKey)
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "Error fetching the health history")
+	}
+	history := []healthEvent{}
+	if data == nil {
+		return history, nil
+	}
+	if err := json.Unmarshal(data, &history); err != nil {
+		return nil, errors.Wrap(err, "Error decoding the health history")
+	}
+	return history, nil
+}
+
+func (p *Plugin) storeJSON(key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if appErr := p.API.KVSet(key, data); appErr != nil {
+		return appErr
+	}
+	return nil
+}
+
+// postAdminMessage posts a message to the admin channel, if one is configured.
+func (p *Plugin) postAdminMessage(message string) {
+	channelID := p.getConfiguration().AdminChannelID
+	if channelID == "" {
+		return
+	}
+	post := &model.Post{
+		UserId:    p.botUserID,
+		ChannelId: channelID,
+		Type:      model.PostTypeDefault,
+		Props: map[string]interface{}{
+			"attachments": []*model.SlackAttachment{generateSlackAttachment(message)},
+		},
+	}
+	if _, appErr := p.API.CreatePost(post); appErr != nil {
+		p.API.LogError("Error posting to the admin channel", "channel_id", channelID, "err", appErr.Error())
+	}
+}
+
+// getHealth returns the current state of the Jenkins server and its recent state changes.
+// The server is checked right away if it hasn't been checked yet.
+func (p *Plugin) getHealth() (string, error) {
+	check, err := p.getHealthStatus()
+	if err != nil {
+		return "", err
+	}
+	if check == nil {
+		if check, err = p.checkHealth(); err != nil {
+			return "", err
+		}
+	}
+	history, err := p.getHealthHistory()
+	if err != nil {
+		return "", err
+	}
+	return formatHealth(check, history, time.Now()), nil
+}
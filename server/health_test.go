@@ -0,0 +1,109 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluateHealth(t *testing.T) {
+	thresholds := healthThresholds{LatencyMillis: 1000, QueueLength: 10}
+	for name, test := range map[string]struct {
+		check    healthCheck
+		state    healthState
+		problems []string
+	}{
+		"unreachable": {
+			check:    healthCheck{Error: "Jenkins is unreachable: connection refused"},
+			state:    healthDown,
+			problems: []string{"Jenkins is unreachable: connection refused"},
+		},
+		"healthy": {
+			check:    healthCheck{Reachable: true, LatencyMillis: 200, BusyExecutors: 1, TotalExecutors: 4, QueueLength: 2},
+			state:    healthUp,
+			problems: []string{},
+		},
+		"restricted": {
+			check:    healthCheck{Reachable: true, Restricted: true, LatencyMillis: 200},
+			state:    healthUp,
+			problems: []string{},
+		},
+		"degraded": {
+			check: healthCheck{Reachable: true, LatencyMillis: 1500, QuietingDown: true, BusyExecutors: 4, TotalExecutors: 4, QueueLength: 10},
+			state: healthDegraded,
+			problems: []string{
+				"Slow response: 1500 ms",
+				"Preparing for shutdown, no new builds are started",
+				"All 4 executors are busy",
+				"10 builds in queue",
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			evaluateHealth(&test.check, thresholds)
+			assert.Equal(t, test.state, test.check.State)
+			assert.Equal(t, test.problems, test.check.Problems)
+		})
+	}
+}
+
+func TestHealthChangeMessage(t *testing.T) {
+	up := &healthCheck{State: healthUp, CheckedAt: 1700000300000}
+	down := &healthCheck{State: healthDown, Problems: []string{"Jenkins responded with 503 Service Unavailable"}, Since: 1700000000000}
+	degraded := &healthCheck{State: healthDegraded, Problems: []string{"12 builds in queue", "Slow response: 3000 ms"}}
+
+	_, changed := healthChangeMessage(nil, up)
+	assert.False(t, changed)
+	_, changed = healthChangeMessage(up, up)
+	assert.False(t, changed)
+
+	msg, changed := healthChangeMessage(nil, down)
+	assert.True(t, changed)
+	assert.Equal(t, ":red_circle: Jenkins is down: Jenkins responded with 503 Service Unavailable", msg)
+
+	msg, changed = healthChangeMessage(up, degraded)
+	assert.True(t, changed)
+	assert.Equal(t, ":warning: Jenkins is degraded: 12 builds in queue, Slow response: 3000 ms", msg)
+
+	msg, changed = healthChangeMessage(down, up)
+	assert.True(t, changed)
+	assert.Equal(t, ":large_green_circle: Jenkins has recovered after being down for 5m 0s.", msg)
+}
+
+func TestAppendHealthEvent(t *testing.T) {
+	history := []healthEvent{}
+	for i := 0; i < maxHealthHistory+5; i++ {
+		history = appendHealthEvent(history, &healthCheck{State: healthUp, CheckedAt: int64(i)})
+	}
+	assert.Len(t, history, maxHealthHistory)
+	assert.Equal(t, int64(5), history[0].Time)
+	assert.Equal(t, int64(maxHealthHistory+4), history[maxHealthHistory-1].Time)
+}
+
+func TestFormatHealth(t *testing.T) {
+	now := time.UnixMilli(1700000600000)
+	check := &healthCheck{
+		State:          healthDegraded,
+		Problems:       []string{"All 2 executors are busy"},
+		CheckedAt:      1700000540000,
+		Since:          1700000000000,
+		Reachable:      true,
+		LatencyMillis:  120,
+		BusyExecutors:  2,
+		TotalExecutors: 2,
+		QueueLength:    3,
+	}
+	history := []healthEvent{
+		{State: healthDown, Problems: []string{"Jenkins is unreachable"}, Time: 1699990000000},
+		{State: healthDegraded, Problems: []string{"All 2 executors are busy"}, Time: 1700000000000},
+	}
+
+	msg := formatHealth(check, history, now)
+	assert.Contains(t, msg, ":warning: Jenkins is **degraded** since 10m 0s ago (checked 1m 0s ago)\n\n* All 2 executors are busy\n")
+	assert.Contains(t, msg, "| 120 ms | No | 2/2 | 3 |\n")
+	assert.Contains(t, msg, "| 2023-11-14 22:13 | :warning: degraded | All 2 executors are busy |\n| 2023-11-14 19:26 | :red_circle: down | Jenkins is unreachable |")
+
+	check.Restricted = true
+	assert.Contains(t, formatHealth(check, nil, now), "| 120 ms | - | - | - |\n")
+}
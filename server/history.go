@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultHistoryLength = 10
+	maxHistoryLength     = 100
+)
+
+// buildHistoryEntry is a build as returned by the tree query in historyTreeQuery.
+type buildHistoryEntry struct {
+	Number    int64  `json:"number"`
+	Result    string `json:"result"`
+	Building  bool   `json:"building"`
+	Timestamp int64  `json:"timestamp"`
+	Duration  int64  `json:"duration"`
+	Actions   []struct {
+		Causes []struct {
+			ShortDescription string `json:"shortDescription"`
+			UserID           string `json:"userId"`
+			UserName         string `json:"userName"`
+		} `json:"causes"`
+		Parameters []buildParameter `json:"parameters"`
+	} `json:"actions"`
+}
+
+// buildParameter is a parameter value of a build or a queue item.
+type buildParameter struct {
+	Class string      `json:"_class"`
+	Name  string      `json:"name"`
+	Value interface{} `json:"value"`
+}
+
+// formatBuildParameters renders parameter values as a comma separated list of name=value pairs.
+// The values of password parameters are masked.
+func formatBuildParameters(params []buildParameter) string {
+	pairs := []string{}
+	for _, param := range params {
+		if param.Class == passwordParameterValueClass {
+			pairs = append(pairs, fmt.Sprintf("%s=%s", param.Name, redactedText))
+			continue
+		}
+		pairs = append(pairs, fmt.Sprintf("%s=%v", param.Name, param.Value))
+	}
+	return strings.Join(pairs, ", ")
+}
+
+// historyFilter holds the filters of the history command.
+type historyFilter struct {
+	Last   int
+	Result string
+	Since  time.Duration
+	User   string
+}
+
+// parseHistoryFilter builds a historyFilter from the flags passed to the history command.
+func parseHistoryFilter(flags map[string]string) (*historyFilter, error) {
+	filter := &historyFilter{
+		Last:   defaultHistoryLength,
+		Result: strings.ToUpper(flags["result"]),
+		User:   flags["user"],
+	}
+
+	if last, ok := flags["last"]; ok {
+		n, err := strconv.Atoi(last)
+		if err != nil || n < 1 || n > maxHistoryLength {
+			return nil, fmt.Errorf("--last must be a number between 1 and %d", maxHistoryLength)
+		}
+		filter.Last = n
+	}
+
+	if since, ok := flags["since"]; ok {
+		d, err := parseSinceDuration(since)
+		if err != nil {
+			return nil, err
+		}
+		filter.Since = d
+	}
+
+	return filter, nil
+}
+
+// hasConditions reports whether the filter does more than limiting the number of builds.
+func (f *historyFilter) hasConditions() bool {
+	return f.Result != "" || f.Since != 0 || f.User != ""
+}
+
+// historyTreeQuery returns the value of the tree parameter used to fetch the given number
+// of builds with their causes and parameters in a single request.
+func historyTreeQuery(limit int) string {
+	return fmt.Sprintf("builds[number,result,building,timestamp,duration,actions[causes[shortDescription,userId,userName],parameters[_class,name,value]]]{0,%d}", limit)
+}
+
+// getBuildHistory fetches the recent builds of the given job and applies the filter.
+func (p *Plugin) getBuildHistory(userID, jobName string, filter *historyFilter) ([]buildHistoryEntry, error) {
+	job, jobErr := p.getJob(userID, jobName)
+	if jobErr != nil {
+		return nil, jobErr
+	}
+
+	// Only the newest builds are needed when no conditions are set.
+	// Otherwise fetch a larger window and filter it.
+	limit := filter.Last
+	if filter.hasConditions() {
+		limit = maxHistoryLength
+	}
+
+	var history struct {
+		Builds []buildHistoryEntry `json:"builds"`
+	}
+	resp, err := job.Jenkins.Requester.GetJSON(job.Base, &history, map[string]string{"tree": historyTreeQuery(limit)})
+	if err != nil {
+		return nil, errors.Wrap(err, "Error fetching build history")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("Error fetching build history: %s", resp.Status)
+	}
+
+	return filterBuildHistory(history.Builds, filter, time.Now()), nil
+}
+
+// filterBuildHistory returns the builds matching the filter, newest first.
+func filterBuildHistory(builds []buildHistoryEntry, filter *historyFilter, now time.Time) []buildHistoryEntry {
+	sort.Slice(builds, func(i, j int) bool { return builds[i].Number > builds[j].Number })
+
+	filtered := []buildHistoryEntry{}
+	for _, b := range builds {
+		if len(filtered) == filter.Last {
+			break
+		}
+		if filter.Result != "" && b.resultString() != filter.Result {
+			continue
+		}
+		if filter.Since != 0 && time.UnixMilli(b.Timestamp).Before(now.Add(-filter.Since)) {
+			continue
+		}
+		if filter.User != "" && !b.triggeredBy(filter.User) {
+			continue
+		}
+		filtered = append(filtered, b)
+	}
+	return filtered
+}
+
+func (b *buildHistoryEntry) resultString() string {
+	if b.Building {
+		return "RUNNING"
+	}
+	return b.Result
+}
+
+// triggeredBy reports whether the build was started by the given Jenkins user ID or name.
+func (b *buildHistoryEntry) triggeredBy(user string) bool {
+	for _, a := range b.Actions {
+		for _, c := range a.Causes {
+			if strings.EqualFold(c.UserID, user) || strings.EqualFold(c.UserName, user) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (b *buildHistoryEntry) cause() string {
+	causes := []string{}
+	for _, a := range b.Actions {
+		for _, c := range a.Causes {
+			causes = append(causes, c.ShortDescription)
+		}
+	}
+	return strings.Join(causes, ", ")
+}
+
+func (b *buildHistoryEntry) parameters() string {
+	params := []buildParameter{}
+	for _, a := range b.Actions {
+		params = append(params, a.Parameters...)
+	}
+	return formatBuildParameters(params)
+}
+
+// formatBuildHistory renders the builds as a markdown table.
+func formatBuildHistory(jobName string, builds []buildHistoryEntry) string {
+	if len(builds) == 0 {
+		return fmt.Sprintf("No builds of the job '%s' match the given filters.", jobName)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Build history of the job '%s'\n\n", jobName)
+	sb.WriteString("| Build | Result | Started | Duration | Cause | Parameters |\n")
+	sb.WriteString("|:------|:-------|:--------|:---------|:------|:-----------|\n")
+	for _, b := range builds {
+		duration := formatDuration(time.Duration(b.Duration) * time.Millisecond)
+		if b.Building {
+			duration = "-"
+		}
+		fmt.Fprintf(&sb, "| #%d | %s | %s | %s | %s | %s |\n",
+			b.Number,
+			b.resultString(),
+			time.UnixMilli(b.Timestamp).UTC().Format("2006-01-02 15:04"),
+			duration,
+			escapeTableCell(b.cause()),
+			escapeTableCell(b.parameters()),
+		)
+	}
+	return sb.String()
+}
+
+// postBuildHistory creates a post with the filtered build history of the given job.
+func (p *Plugin) postBuildHistory(userID, channelID, jobName string, filter *historyFilter) error {
+	builds, err := p.getBuildHistory(userID, jobName, filter)
+	if err != nil {
+		return err
+	}
+	p.createPost(userID, channelID, formatBuildHistory(jobName, builds))
+	return nil
+}
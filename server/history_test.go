@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testBuildHistory = `[
+	{"number": 41, "result": "SUCCESS", "timestamp": 1700000000000, "duration": 65000,
+	 "actions": [{"causes": [{"shortDescription": "Started by user alice", "userId": "alice", "userName": "Alice"}]},
+	             {"parameters": [{"name": "BRANCH", "value": "main"}, {"_class": "hudson.model.PasswordParameterValue", "name": "TOKEN"}]}]},
+	{"number": 43, "building": true, "timestamp": 1700100000000,
+	 "actions": [{"causes": [{"shortDescription": "Started by timer"}]}]},
+	{"number": 42, "result": "FAILURE", "timestamp": 1700050000000, "duration": 3000,
+	 "actions": [{"causes": [{"shortDescription": "Started by user bob", "userId": "bob", "userName": "Bob"}]}]}
+]`
+
+func TestFilterBuildHistory(t *testing.T) {
+	now := time.UnixMilli(1700100000000)
+
+	for name, tc := range map[string]struct {
+		Filter   historyFilter
+		Expected []int64
+	}{
+		"newest first": {
+			Filter:   historyFilter{Last: 10},
+			Expected: []int64{43, 42, 41},
+		},
+		"last": {
+			Filter:   historyFilter{Last: 2},
+			Expected: []int64{43, 42},
+		},
+		"result": {
+			Filter:   historyFilter{Last: 10, Result: "FAILURE"},
+			Expected: []int64{42},
+		},
+		"running": {
+			Filter:   historyFilter{Last: 10, Result: "RUNNING"},
+			Expected: []int64{43},
+		},
+		"since": {
+			Filter:   historyFilter{Last: 10, Since: 24 * time.Hour},
+			Expected: []int64{43, 42},
+		},
+		"user by id": {
+			Filter:   historyFilter{Last: 10, User: "alice"},
+			Expected: []int64{41},
+		},
+		"user by name": {
+			Filter:   historyFilter{Last: 10, User: "bob"},
+			Expected: []int64{42},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var builds []buildHistoryEntry
+			require.Nil(t, json.Unmarshal([]byte(testBuildHistory), &builds))
+
+			numbers := []int64{}
+			for _, b := range filterBuildHistory(builds, &tc.Filter, now) {
+				numbers = append(numbers, b.Number)
+			}
+			assert.Equal(t, tc.Expected, numbers)
+		})
+	}
+}
+
+func TestParseHistoryFilter(t *testing.T) {
+	filter, err := parseHistoryFilter(map[string]string{})
+	require.Nil(t, err)
+	assert.Equal(t, &historyFilter{Last: defaultHistoryLength}, filter)
+
+	filter, err = parseHistoryFilter(map[string]string{"last": "5", "result": "failure", "since": "2d", "user": "alice"})
+	require.Nil(t, err)
+	assert.Equal(t, &historyFilter{Last: 5, Result: "FAILURE", Since: 48 * time.Hour, User: "alice"}, filter)
+
+	_, err = parseHistoryFilter(map[string]string{"last": "0"})
+	assert.NotNil(t, err)
+
+	_, err = parseHistoryFilter(map[string]string{"since": "yesterday"})
+	assert.NotNil(t, err)
+}
+
+func TestFormatBuildHistory(t *testing.T) {
+	var builds []buildHistoryEntry
+	require.Nil(t, json.Unmarshal([]byte(testBuildHistory), &builds))
+
+	table := formatBuildHistory("job1", builds[:1])
+	assert.Contains(t, table, "| #41 | SUCCESS | 2023-11-14 22:13 | 1m 5s | Started by user alice | BRANCH=main, TOKEN=******** |")
+
+	assert.Equal(t, "No builds of the job 'job1' match the given filters.", formatBuildHistory("job1", nil))
+}
@@ -0,0 +1,375 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+	"github.com/waseem18/gojenkins"
+)
+
+const (
+	inputActionProceed = "proceed"
+	inputActionAbort   = "abort"
+
+	inputParameterBoolean  = "BooleanParameterDefinition"
+	inputParameterChoice   = "ChoiceParameterDefinition"
+	inputParameterPassword = "PasswordParameterDefinition"
+	inputParameterText     = "TextParameterDefinition"
+
+	// pipelineRunClass is the class of the builds of Pipeline jobs, the only builds which can wait on input steps.
+	pipelineRunClass = "org.jenkinsci.plugins.workflow.job.WorkflowRun"
+)
+
+// pendingInput is an input step a Pipeline build waits on, as returned by the wfapi/pendingInputActions endpoint.
+type pendingInput struct {
+	ID          string           `json:"id"`
+	Message     string           `json:"message"`
+	ProceedText string           `json:"proceedText"`
+	Inputs      []inputParameter `json:"inputs"`
+}
+
+// inputParameter is a parameter requested by an input step.
+type inputParameter struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Definition  struct {
+		DefaultVal interface{} `json:"defaultVal"`
+		Choices    []string    `json:"choices"`
+	} `json:"definition"`
+}
+
+// proceedLabel returns the label of the button which proceeds with the input.
+func (i *pendingInput) proceedLabel() string {
+	if i.ProceedText == "" {
+		return "Proceed"
+	}
+	return i.ProceedText
+}
+
+// inputRequest identifies a pending input of a build. It is passed as the context of
+// the buttons and as the state of the dialog of an input request post.
+type inputRequest struct {
+	JobName     string `json:"job_name"`
+	BuildNumber string `json:"build_number"`
+	InputID     string `json:"input_id"`
+	PostID      string `json:"post_id,omitempty"`
+}
+
+func (r *inputRequest) toContext() map[string]interface{} {
+	return map[string]interface{}{
+		"job_name":     r.JobName,
+		"build_number": r.BuildNumber,
+		"input_id":     r.InputID,
+	}
+}
+
+func inputRequestFromContext(context map[string]interface{}) (*inputRequest, bool) {
+	r := &inputRequest{}
+	r.JobName, _ = context["job_name"].(string)
+	r.BuildNumber, _ = context["build_number"].(string)
+	r.InputID, _ = context["input_id"].(string)
+	return r, r.JobName != "" && r.BuildNumber != "" && r.InputID != ""
+}
+
+// isPipelineBuild reports whether the given build is a Pipeline build.
+func isPipelineBuild(build *gojenkins.Build) (bool, error) {
+	var run struct {
+		Class string `json:"_class"`
+	}
+	resp, err := build.Jenkins.Requester.GetJSON(build.Base, &run, map[string]string{"tree": "_class"})
+	if err != nil {
+		return false, errors.Wrap(err, "Error fetching the build class")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, errors.Errorf("Error fetching the build class: %s", resp.Status)
+	}
+	return run.Class == pipelineRunClass, nil
+}
+
+// getPendingInputs fetches the input steps the given build waits on.
+// Builds of jobs without the Pipeline REST API, such as freestyle jobs, don't wait on any input.
+func getPendingInputs(build *gojenkins.Build) ([]pendingInput, error) {
+	inputs := []pendingInput{}
+	err := getJenkinsJSON(build.Jenkins, build.Base+"/wfapi/pendingInputActions", &inputs)
+	var statusErr *jenkinsStatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+		return []pendingInput{}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "Error fetching pending inputs")
+	}
+	return inputs, nil
+}
+
+// findPendingInput returns the pending input with the given ID, or nil if the build doesn't wait on it anymore.
+func findPendingInput(build *gojenkins.Build, inputID string) (*pendingInput, error) {
+	inputs, err := getPendingInputs(build)
+	if err != nil {
+		return nil, err
+	}
+	for i := range inputs {
+		if inputs[i].ID == inputID {
+			return &inputs[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// postJenkinsForm posts the form to the given endpoint of the Jenkins server.
+func postJenkinsForm(jenkins *gojenkins.Jenkins, endpoint string, form url.Values) error {
+	resp, err := jenkins.Requester.Post(endpoint, strings.NewReader(form.Encode()), nil, nil)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return errors.Errorf("unexpected response from Jenkins: %s", resp.Status)
+	}
+	return nil
+}
+
+// formatInputRequest renders the message of the post asking for an input of a build.
+func formatInputRequest(jobName string, buildNumber int64, buildURL string, input *pendingInput) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, ":raised_hand: The build #%d of the job '%s' is waiting for input\n", buildNumber, jobName)
+	fmt.Fprintf(&sb, "**%s**\n", input.Message)
+	for _, param := range input.Inputs {
+		if param.Description != "" {
+			fmt.Fprintf(&sb, "* `%s`: %s\n", param.Name, param.Description)
+		} else {
+			fmt.Fprintf(&sb, "* `%s`\n", param.Name)
+		}
+	}
+	fmt.Fprintf(&sb, "Build URL : %s", buildURL)
+	return sb.String()
+}
+
+// inputDialogElements creates the dialog elements for the parameters of an input step.
+func inputDialogElements(params []inputParameter) []model.DialogElement {
+	elements := []model.DialogElement{}
+	for _, param := range params {
+		element := model.DialogElement{
+			DisplayName: param.Name,
+			Name:        param.Name,
+			HelpText:    param.Description,
+			Type:        "text",
+			Optional:    true,
+		}
+		if param.Definition.DefaultVal != nil {
+			element.Default = fmt.Sprintf("%v", param.Definition.DefaultVal)
+		}
+		switch param.Type {
+		case inputParameterBoolean:
+			element.Type = "bool"
+			element.Placeholder = param.Name
+		case inputParameterChoice:
+			element.Type = "select"
+			element.Optional = false
+			for _, choice := range param.Definition.Choices {
+				element.Options = append(element.Options, &model.PostActionOptions{Text: choice, Value: choice})
+			}
+			if element.Default == "" && len(param.Definition.Choices) > 0 {
+				element.Default = param.Definition.Choices[0]
+			}
+		case inputParameterPassword:
+			element.SubType = "password"
+		case inputParameterText:
+			element.Type = "textarea"
+		}
+		elements = append(elements, element)
+	}
+	return elements
+}
+
+// inputSubmissionForm creates the form submitted to Jenkins to proceed with an input step with the given values.
+func inputSubmissionForm(input *pendingInput, submission map[string]interface{}) (url.Values, error) {
+	type parameterValue struct {
+		Name  string      `json:"name"`
+		Value interface{} `json:"value"`
+	}
+	params := []parameterValue{}
+	for _, param := range input.Inputs {
+		value := submission[param.Name]
+		if value == nil {
+			value = ""
+		}
+		if param.Type == inputParameterBoolean {
+			value = value == true || value == "true"
+		}
+		params = append(params, parameterValue{Name: param.Name, Value: value})
+	}
+
+	data, err := json.Marshal(map[string]interface{}{"parameter": params})
+	if err != nil {
+		return nil, err
+	}
+	return url.Values{"json": {string(data)}, "proceed": {input.proceedLabel()}}, nil
+}
+
+// postInputRequest creates a post with Proceed and Abort buttons for a pending input of a build.
+func (p *Plugin) postInputRequest(userID, channelID, jobName string, build *gojenkins.Build, input *pendingInput) {
+	request := &inputRequest{JobName: jobName, BuildNumber: fmt.Sprintf("%d", build.GetBuildNumber()), InputID: input.ID}
+	siteURL := *p.API.GetConfig().ServiceSettings.SiteURL
+
+	attachment := generateSlackAttachment(formatInputRequest(jobName, build.GetBuildNumber(), build.GetUrl(), input))
+	attachment.Actions = []*model.PostAction{
+		{
+			Name:  input.proceedLabel(),
+			Type:  model.PostActionTypeButton,
+			Style: "primary",
+			Integration: &model.PostActionIntegration{
+				URL:     fmt.Sprintf("%s/plugins/jenkins/input/%s", siteURL, inputActionProceed),
+				Context: request.toContext(),
+			},
+		},
+		{
+			Name:  "Abort",
+			Type:  model.PostActionTypeButton,
+			Style: "danger",
+			Integration: &model.PostActionIntegration{
+				URL:     fmt.Sprintf("%s/plugins/jenkins/input/%s", siteURL, inputActionAbort),
+				Context: request.toContext(),
+			},
+		},
+	}
+	p.createAttachmentPost(userID, channelID, attachment)
+}
+
+// postPendingInputs creates a post for every input the given build waits on.
+// If build number is not specified, the inputs of the last build of the job are posted.
+func (p *Plugin) postPendingInputs(userID, channelID, jobName, buildID string) error {
+	build, buildErr := p.getBuild(jobName, userID, buildID)
+	if buildErr != nil {
+		return buildErr
+	}
+
+	inputs, err := getPendingInputs(build)
+	if err != nil {
+		return err
+	}
+	if len(inputs) == 0 {
+		p.createPost(userID, channelID, fmt.Sprintf("The build #%d of the job '%s' isn't waiting for input.", build.GetBuildNumber(), jobName))
+		return nil
+	}
+	for i := range inputs {
+		p.postInputRequest(userID, channelID, jobName, build, &inputs[i])
+	}
+	return nil
+}
+
+// handleInputAction handles a click on the Proceed or Abort button of an input request.
+// The input is submitted to Jenkins with the credentials of the user who clicked. If the
+// input has parameters, a dialog is opened to fill them instead.
+func (p *Plugin) handleInputAction(userID, action string, request *model.PostActionIntegrationRequest) (string, error) {
+	input, ok := inputRequestFromContext(request.Context)
+	if !ok {
+		return "", errors.New("invalid input request")
+	}
+	input.PostID = request.PostId
+
+	if _, err := p.getJenkinsUserInfo(userID); err != nil {
+		return "Please connect your Jenkins account with `/jenkins connect` to respond to the input.", nil
+	}
+	build, err := p.getBuild(input.JobName, userID, input.BuildNumber)
+	if err != nil {
+		return "", err
+	}
+	pending, err := findPendingInput(build, input.InputID)
+	if err != nil {
+		return "", err
+	}
+	if pending == nil {
+		p.closeInputRequest(input.PostID, "The build isn't waiting for this input anymore.")
+		return "The build isn't waiting for this input anymore.", nil
+	}
+
+	if action == inputActionAbort {
+		if err := postJenkinsForm(build.Jenkins, fmt.Sprintf("%s/input/%s/abort", build.Base, url.PathEscape(input.InputID)), url.Values{}); err != nil {
+			return "Jenkins refused to abort the input. Are you allowed to respond to it?", err
+		}
+		p.closeInputRequest(input.PostID, fmt.Sprintf("Aborted by @%s.", p.getUsername(userID)))
+		return "", nil
+	}
+
+	if len(pending.Inputs) > 0 {
+		return "", p.openInputDialog(request.TriggerId, input, pending)
+	}
+
+	if err := postJenkinsForm(build.Jenkins, fmt.Sprintf("%s/input/%s/proceedEmpty", build.Base, url.PathEscape(input.InputID)), url.Values{}); err != nil {
+		return "Jenkins refused to proceed. Are you allowed to respond to the input?", err
+	}
+	p.closeInputRequest(input.PostID, fmt.Sprintf("Approved by @%s.", p.getUsername(userID)))
+	return "", nil
+}
+
+// openInputDialog opens a dialog to fill the parameters of a pending input.
+func (p *Plugin) openInputDialog(triggerID string, input *inputRequest, pending *pendingInput) error {
+	state, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+
+	siteURL := *p.API.GetConfig().ServiceSettings.SiteURL
+	dialog := model.OpenDialogRequest{
+		TriggerId: triggerID,
+		URL:       fmt.Sprintf("%s/plugins/jenkins/input/submit", siteURL),
+		Dialog: model.Dialog{
+			Title:            truncateString(pending.Message, 24),
+			IntroductionText: pending.Message,
+			SubmitLabel:      pending.proceedLabel(),
+			State:            string(state),
+			Elements:         inputDialogElements(pending.Inputs),
+		},
+	}
+	if appErr := p.API.OpenInteractiveDialog(dialog); appErr != nil {
+		return errors.Wrap(appErr, "Error opening the interactive dialog")
+	}
+	return nil
+}
+
+// submitInput proceeds with a pending input using the parameters filled in its dialog.
+// Returns a message for the user if Jenkins refused the submission.
+func (p *Plugin) submitInput(userID string, request *model.SubmitDialogRequest) (string, error) {
+	input := &inputRequest{}
+	if err := json.Unmarshal([]byte(request.State), input); err != nil {
+		return "", errors.Wrap(err, "invalid dialog state")
+	}
+
+	build, err := p.getBuild(input.JobName, userID, input.BuildNumber)
+	if err != nil {
+		return "", err
+	}
+	pending, err := findPendingInput(build, input.InputID)
+	if err != nil {
+		return "", err
+	}
+	if pending == nil {
+		p.closeInputRequest(input.PostID, "The build isn't waiting for this input anymore.")
+		return "The build isn't waiting for this input anymore.", nil
+	}
+
+	form, err := inputSubmissionForm(pending, request.Submission)
+	if err != nil {
+		return "", err
+	}
+	if err := postJenkinsForm(build.Jenkins, fmt.Sprintf("%s/input/%s/submit", build.Base, url.PathEscape(input.InputID)), form); err != nil {
+		return "Jenkins refused to proceed. Are you allowed to respond to the input?", err
+	}
+	p.closeInputRequest(input.PostID, fmt.Sprintf("Approved by @%s.", p.getUsername(userID)))
+	return "", nil
+}
+
+// closeInputRequest removes the buttons from an input request post and records the outcome.
+func (p *Plugin) closeInputRequest(postID, footer string) {
+	post, appErr := p.API.GetPost(postID)
+	if appErr != nil {
+		p.API.LogWarn("Error fetching input request post", "post_id", postID, "err", appErr.Error())
+		return
+	}
+	p.closeActionPost(post, footer)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/waseem18/gojenkins"
+)
+
+const testPendingInputs = `[{
+	"id": "Release", "message": "Deploy to production?", "proceedText": "Deploy",
+	"inputs": [
+		{"type": "ChoiceParameterDefinition", "name": "REGION", "description": "Target region", "definition": {"choices": ["eu", "us"]}},
+		{"type": "BooleanParameterDefinition", "name": "DRY_RUN", "definition": {"defaultVal": true}},
+		{"type": "PasswordParameterDefinition", "name": "OTP"}
+	]
+}]`
+
+func getTestPendingInput(t *testing.T) *pendingInput {
+	inputs := []pendingInput{}
+	require.Nil(t, json.Unmarshal([]byte(testPendingInputs), &inputs))
+	require.Len(t, inputs, 1)
+	return &inputs[0]
+}
+
+func TestFormatInputRequest(t *testing.T) {
+	msg := formatInputRequest("job1", 7, "http://jenkins/job/job1/7/", getTestPendingInput(t))
+	assert.Equal(t, ":raised_hand: The build #7 of the job 'job1' is waiting for input\n**Deploy to production?**\n* `REGION`: Target region\n* `DRY_RUN`\n* `OTP`\nBuild URL : http://jenkins/job/job1/7/", msg)
+}
+
+func TestInputDialogElements(t *testing.T) {
+	elements := inputDialogElements(getTestPendingInput(t).Inputs)
+	require.Len(t, elements, 3)
+
+	assert.Equal(t, "select", elements[0].Type)
+	assert.Equal(t, "eu", elements[0].Default)
+	assert.Len(t, elements[0].Options, 2)
+	assert.False(t, elements[0].Optional)
+
+	assert.Equal(t, "bool", elements[1].Type)
+	assert.Equal(t, "true", elements[1].Default)
+
+	assert.Equal(t, "text", elements[2].Type)
+	assert.Equal(t, "password", elements[2].SubType)
+}
+
+func TestInputSubmissionForm(t *testing.T) {
+	form, err := inputSubmissionForm(getTestPendingInput(t), map[string]interface{}{"REGION": "us", "DRY_RUN": false})
+	require.Nil(t, err)
+	assert.Equal(t, "Deploy", form.Get("proceed"))
+	assert.JSONEq(t, `{"parameter": [{"name": "REGION", "value": "us"}, {"name": "DRY_RUN", "value": false}, {"name": "OTP", "value": ""}]}`, form.Get("json"))
+
+	form, err = inputSubmissionForm(&pendingInput{ID: "Ok"}, nil)
+	require.Nil(t, err)
+	assert.Equal(t, "Proceed", form.Get("proceed"))
+}
+
+func TestInputRequestContext(t *testing.T) {
+	request := &inputRequest{JobName: "folder/job1", BuildNumber: "7", InputID: "Release"}
+	decoded, ok := inputRequestFromContext(request.toContext())
+	assert.True(t, ok)
+	assert.Equal(t, request, decoded)
+
+	_, ok = inputRequestFromContext(map[string]interface{}{"job_name": "job1"})
+	assert.False(t, ok)
+}
+
+func TestGetPendingInputs(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/job/pipeline/1/wfapi/pendingInputActions":
+			_, _ = res.Write([]byte(testPendingInputs))
+		case "/job/pipeline/1/api/json":
+			_, _ = res.Write([]byte(`{"_class": "org.jenkinsci.plugins.workflow.job.WorkflowRun"}`))
+		case "/job/freestyle/1/api/json":
+			_, _ = res.Write([]byte(`{"_class": "hudson.model.FreeStyleBuild"}`))
+		default:
+			res.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer testServer.Close()
+	jenkins := gojenkins.CreateJenkins(nil, testServer.URL)
+
+	pipeline := &gojenkins.Build{Jenkins: jenkins, Base: "/job/pipeline/1"}
+	isPipeline, err := isPipelineBuild(pipeline)
+	require.NoError(t, err)
+	assert.True(t, isPipeline)
+	inputs, err := getPendingInputs(pipeline)
+	require.NoError(t, err)
+	assert.Len(t, inputs, 1)
+
+	// Builds without the Pipeline REST API don't wait on any input.
+	freestyle := &gojenkins.Build{Jenkins: jenkins, Base: "/job/freestyle/1"}
+	isPipeline, err = isPipelineBuild(freestyle)
+	require.NoError(t, err)
+	assert.False(t, isPipeline)
+	inputs, err = getPendingInputs(freestyle)
+	require.NoError(t, err)
+	assert.Empty(t, inputs)
+}
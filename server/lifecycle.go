@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/waseem18/gojenkins"
+)
+
+const (
+	lifecycleQuietDown       = "quiet-down"
+	lifecycleCancelQuietDown = "cancel-quiet-down"
+	lifecycleSafeRestart     = "safe-restart"
+	lifecycleRestart         = "restart"
+	lifecycleExit            = "exit"
+
+	// restartTimeout is the time after which a restarting Jenkins server is no longer waited for.
+	// A safe restart waits for the running builds to finish, which may take a while.
+	restartTimeout = 3 * time.Hour
+)
+
+// lifecycleEndpoint returns the endpoint of the Jenkins server performing the given action.
+func lifecycleEndpoint(action string) string {
+	switch action {
+	case lifecycleQuietDown:
+		return "/quietDown"
+	case lifecycleCancelQuietDown:
+		return "/cancelQuietDown"
+	case lifecycleSafeRestart:
+		return "/safeRestart"
+	case lifecycleRestart:
+		return "/restart"
+	case lifecycleExit:
+		return "/exit"
+	default:
+		return ""
+	}
+}
+
+// lifecycleConfirmationMessage returns the question asked before running the given action.
+func lifecycleConfirmationMessage(action, reason string) string {
+	switch action {
+	case lifecycleQuietDown:
+		msg := "Prepare Jenkins for shutdown? No new builds will be started until the quiet-down is cancelled."
+		if reason != "" {
+			msg += fmt.Sprintf("\nReason: %s", reason)
+		}
+		return msg
+	case lifecycleCancelQuietDown:
+		return "Cancel the shutdown of Jenkins? New builds will be started again."
+	case lifecycleSafeRestart:
+		return "Restart Jenkins once the running builds have finished? No new builds will be started until then."
+	case lifecycleRestart:
+		return "Restart Jenkins now? **Running builds will be aborted.**"
+	case lifecycleExit:
+		return "Shut down Jenkins now? **Running builds will be aborted and Jenkins won't be started again.**"
+	default:
+		return ""
+	}
+}
+
+// lifecycleDoneMessage returns the message posted once the given action has been triggered.
+func lifecycleDoneMessage(action, reason string) string {
+	switch action {
+	case lifecycleQuietDown:
+		msg := "Jenkins is preparing for shutdown. No new builds will be started."
+		if reason != "" {
+			msg += fmt.Sprintf("\nReason: %s", reason)
+		}
+		return msg
+	case lifecycleCancelQuietDown:
+		return "The shutdown of Jenkins has been cancelled."
+	case lifecycleSafeRestart:
+		return "Safe restart of Jenkins server has been triggered. Jenkins will restart once the running builds have finished."
+	case lifecycleRestart:
+		return "Restart of Jenkins server has been triggered."
+	case lifecycleExit:
+		return "Shutdown of Jenkins server has been triggered."
+	default:
+		return ""
+	}
+}
+
+// isRestarted reports whether the Jenkins server is back up after a restart, given the session it ran before.
+func isRestarted(check *healthCheck, previousSession string, wentDown bool) bool {
+	if !check.Reachable {
+		return false
+	}
+	return wentDown || (check.session != "" && check.session != previousSession)
+}
+
+// triggerLifecycleAction posts the form to the endpoint of the action. Jenkins may already be unavailable
+// when following the redirect after a restart or a shutdown, which isn't an error.
+func triggerLifecycleAction(jenkins *gojenkins.Jenkins, action string, form url.Values) (err error) {
+	defer recoverJenkinsPanic(&err)
+	resp, err := jenkins.Requester.Post(lifecycleEndpoint(action), strings.NewReader(form.Encode()), nil, nil)
+	if err != nil {
+		return err
+	}
+	stopping := action == lifecycleSafeRestart || action == lifecycleRestart || action == lifecycleExit
+	if resp.StatusCode == http.StatusServiceUnavailable && stopping {
+		return nil
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return errors.Errorf("unexpected response from Jenkins: %s", resp.Status)
+	}
+	return nil
+}
+
+// askLifecycleConfirmation asks the user to confirm quieting down, restarting or shutting down the Jenkins server.
+func (p *Plugin) askLifecycleConfirmation(userID, channelID, action, reason string) {
+	p.askConfirmation(userID, channelID, &confirmationRequest{
+		Message: lifecycleConfirmationMessage(action, reason),
+		Action:  action,
+		Args:    map[string]string{"reason": reason},
+	})
+}
+
+// runLifecycleAction quiets down, restarts or shuts down the Jenkins server. After a restart, a post is
+// created in the channel once the server is back up. Returns a message describing the outcome.
+func (p *Plugin) runLifecycleAction(userID, channelID, action, reason string) (string, error) {
+	if !p.isSystemAdmin(userID) {
+		return "Only system admins can restart or shut down Jenkins.", nil
+	}
+
+	jenkins, err := p.getJenkinsClient(userID)
+	if err != nil {
+		return "", errors.Wrap(err, "Error creating Jenkins client")
+	}
+	jenkins.Requester.Client = &http.Client{Timeout: healthCheckTimeout}
+	before := probeJenkins(jenkins)
+
+	form := url.Values{}
+	if action == lifecycleQuietDown && reason != "" {
+		form.Set("message", reason)
+	}
+	if err := triggerLifecycleAction(jenkins, action, form); err != nil {
+		return "", errors.Wrapf(err, "Error running %s", action)
+	}
+
+	msg := lifecycleDoneMessage(action, reason)
+	p.createPost(userID, channelID, msg)
+	if action == lifecycleSafeRestart || action == lifecycleRestart {
+		go p.waitForRestart(jenkins, userID, channelID, before.session)
+	}
+	return msg, nil
+}
+
+// waitForRestart polls the Jenkins server until it is back up after a restart, and then posts
+// the time the restart took.
+func (p *Plugin) waitForRestart(jenkins *gojenkins.Jenkins, userID, channelID, previousSession string) {
+	start := time.Now()
+	wentDown := false
+	for time.Since(start) < restartTimeout {
+		if !p.sleep(pollingSleepTime * time.Second) {
+			return
+		}
+		check := probeJenkins(jenkins)
+		if isRestarted(check, previousSession, wentDown) {
+			p.createPost(userID, channelID, fmt.Sprintf("Jenkins is back up after %s.", formatDuration(time.Since(start))))
+			return
+		}
+		if !check.Reachable {
+			wentDown = true
+		}
+	}
+	p.createPost(userID, channelID, fmt.Sprintf("Jenkins didn't come back up within %s.", formatDuration(restartTimeout)))
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/waseem18/gojenkins"
+)
+
+func TestLifecycleMessages(t *testing.T) {
+	for _, action := range []string{lifecycleQuietDown, lifecycleCancelQuietDown, lifecycleSafeRestart, lifecycleRestart, lifecycleExit} {
+		assert.NotEmpty(t, lifecycleEndpoint(action), action)
+		assert.NotEmpty(t, lifecycleConfirmationMessage(action, ""), action)
+		assert.NotEmpty(t, lifecycleDoneMessage(action, ""), action)
+	}
+	assert.Empty(t, lifecycleEndpoint("unknown"))
+	assert.Equal(t, "/safeRestart", lifecycleEndpoint(lifecycleSafeRestart))
+	assert.Contains(t, lifecycleConfirmationMessage(lifecycleQuietDown, "Upgrading plugins"), "\nReason: Upgrading plugins")
+}
+
+func TestIsRestarted(t *testing.T) {
+	for name, test := range map[string]struct {
+		check    healthCheck
+		wentDown bool
+		expected bool
+	}{
+		"still running":       {check: healthCheck{Reachable: true, session: "a"}},
+		"down":                {check: healthCheck{}, wentDown: true},
+		"new session":         {check: healthCheck{Reachable: true, session: "b"}, expected: true},
+		"back after downtime": {check: healthCheck{Reachable: true, session: "a"}, wentDown: true, expected: true},
+		"no session header":   {check: healthCheck{Reachable: true}},
+	} {
+		assert.Equal(t, test.expected, isRestarted(&test.check, "a", test.wentDown), name)
+	}
+}
+
+func TestTriggerLifecycleActionUnreachable(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	testServer.Close()
+
+	err := triggerLifecycleAction(gojenkins.CreateJenkins(nil, testServer.URL), lifecycleQuietDown, url.Values{})
+	assert.Error(t, err)
+}
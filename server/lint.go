@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	// maxJenkinsfileLength is the maximum length of a Jenkinsfile pasted in the lint dialog.
+	maxJenkinsfileLength = 100000
+
+	lintSuccessMessage = "Jenkinsfile successfully validated."
+)
+
+// lintErrorPattern matches the errors reported by the Pipeline Model Definition plugin,
+// e.g. WorkflowScript: 3: Undefined section "stagess" @ line 3, column 5.
+var lintErrorPattern = regexp.MustCompile(`WorkflowScript: \d+: (.*?) @ line (\d+), column (\d+)\.`)
+
+// lintError is an error found in a Jenkinsfile.
+type lintError struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+// parseLintResult parses the response of the validate endpoint. Returns whether the Jenkinsfile is valid,
+// and the errors with their position.
+func parseLintResult(result string) (bool, []lintError) {
+	if strings.Contains(result, lintSuccessMessage) {
+		return true, nil
+	}
+	lintErrors := []lintError{}
+	for _, match := range lintErrorPattern.FindAllStringSubmatch(result, -1) {
+		line, _ := strconv.Atoi(match[2])
+		column, _ := strconv.Atoi(match[3])
+		lintErrors = append(lintErrors, lintError{Line: line, Column: column, Message: match[1]})
+	}
+	sort.SliceStable(lintErrors, func(i, j int) bool {
+		return lintErrors[i].Line < lintErrors[j].Line
+	})
+	return false, lintErrors
+}
+
+// formatLintResult renders the result of linting a Jenkinsfile, with the lines of the errors
+// and a marker at the reported columns.
+func formatLintResult(name, script, result string) string {
+	valid, lintErrors := parseLintResult(result)
+	if valid {
+		return fmt.Sprintf(":white_check_mark: `%s` has been successfully validated.", name)
+	}
+	if len(lintErrors) == 0 {
+		return fmt.Sprintf(":x: `%s` is invalid:\n```\n%s\n```", name, strings.TrimSpace(result))
+	}
+
+	lines := strings.Split(strings.ReplaceAll(script, "\r\n", "\n"), "\n")
+	width := len(strconv.Itoa(lintErrors[len(lintErrors)-1].Line))
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, ":x: %d error(s) found in `%s`:\n```\n", len(lintErrors), name)
+	for _, e := range lintErrors {
+		source := ""
+		if e.Line >= 1 && e.Line <= len(lines) {
+			source = strings.ReplaceAll(lines[e.Line-1], "\t", " ")
+		}
+		fmt.Fprintf(&sb, "%*d | %s\n", width, e.Line, source)
+		indent := ""
+		if e.Column > 1 {
+			indent = strings.Repeat(" ", e.Column-1)
+		}
+		fmt.Fprintf(&sb, "%*s | %s^ %s\n", width, "", indent, e.Message)
+	}
+	sb.WriteString("```")
+	return sb.String()
+}
+
+// lintJenkinsfile validates a declarative Jenkinsfile with the Jenkins server and posts the result.
+func (p *Plugin) lintJenkinsfile(userID, channelID, name, script string) error {
+	jenkins, err := p.getJenkinsClient(userID)
+	if err != nil {
+		return errors.Wrap(err, "Error creating Jenkins client")
+	}
+	form := url.Values{"jenkinsfile": {script}}
+	result, err := postJenkinsDocument(jenkins, "/pipeline-model-converter/validate", "application/x-www-form-urlencoded", form.Encode())
+	if err != nil {
+		return errors.Wrap(err, "Error validating the Jenkinsfile")
+	}
+	p.createPost(userID, channelID, formatLintResult(name, script, result))
+	return nil
+}
+
+// lintAttachedJenkinsfile validates the Jenkinsfile attached to the given post.
+// Returns a message for the user if the attached file can't be read.
+func (p *Plugin) lintAttachedJenkinsfile(userID, channelID, postRef string) (string, error) {
+	info, content, err := p.getAttachedFile(userID, postRef)
+	if err != nil {
+		return fmt.Sprintf("Unable to read the Jenkinsfile: %s.", err.Error()), nil
+	}
+	return "", p.lintJenkinsfile(userID, channelID, info.Name, string(content))
+}
+
+// createDialogForLint opens a dialog for the user to paste a Jenkinsfile to validate.
+func (p *Plugin) createDialogForLint(userID, triggerID string) error {
+	dialog := model.OpenDialogRequest{
+		TriggerId: triggerID,
+		URL:       fmt.Sprintf("%s/plugins/jenkins/lint", *p.API.GetConfig().ServiceSettings.SiteURL),
+		Dialog: model.Dialog{
+			Title:       "Validate a Jenkinsfile",
+			CallbackId:  userID,
+			SubmitLabel: "Validate",
+			Elements: []model.DialogElement{{
+				DisplayName: "Jenkinsfile",
+				Name:        "Jenkinsfile",
+				Type:        "textarea",
+				SubType:     "text",
+				HelpText:    "Only declarative Pipelines can be validated.",
+				MaxLength:   maxJenkinsfileLength,
+			}},
+		},
+	}
+	if appErr := p.API.OpenInteractiveDialog(dialog); appErr != nil {
+		return errors.Wrap(appErr, "Error opening the interactive dialog")
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testJenkinsfile = "pipeline {\n    agent any\n    stagess {\n\tstage('Build') {\n            steps { sh 'make' }\n        }\n    }\n}\n"
+
+const testLintFailure = `Errors encountered validating Jenkinsfile:
+WorkflowScript: 3: Undefined section "stagess" @ line 3, column 5.
+       stagess {
+       ^
+
+WorkflowScript: 1: Missing required section "stages" @ line 1, column 1.
+   pipeline {
+   ^
+
+WorkflowScript: 11: Expected a step @ line 11, column 13.
+`
+
+func TestParseLintResult(t *testing.T) {
+	valid, lintErrors := parseLintResult("Jenkinsfile successfully validated.\n")
+	assert.True(t, valid)
+	assert.Empty(t, lintErrors)
+
+	valid, lintErrors = parseLintResult(testLintFailure)
+	assert.False(t, valid)
+	assert.Equal(t, []lintError{
+		{Line: 1, Column: 1, Message: `Missing required section "stages"`},
+		{Line: 3, Column: 5, Message: `Undefined section "stagess"`},
+		{Line: 11, Column: 13, Message: "Expected a step"},
+	}, lintErrors)
+}
+
+func TestFormatLintResult(t *testing.T) {
+	assert.Equal(t, ":white_check_mark: `Jenkinsfile` has been successfully validated.",
+		formatLintResult("Jenkinsfile", testJenkinsfile, "Jenkinsfile successfully validated."))
+
+	assert.Equal(t, ":x: 3 error(s) found in `ci/Jenkinsfile`:\n```\n"+
+		" 1 | pipeline {\n"+
+		"   | ^ Missing required section \"stages\"\n"+
+		" 3 |     stagess {\n"+
+		"   |     ^ Undefined section \"stagess\"\n"+
+		"11 | \n"+
+		"   |             ^ Expected a step\n"+
+		"```", formatLintResult("ci/Jenkinsfile", testJenkinsfile, testLintFailure))
+
+	assert.Equal(t, ":x: `Jenkinsfile` is invalid:\n```\nJenkinsfile content 'node {}' did not contain the 'pipeline' step\n```",
+		formatLintResult("Jenkinsfile", "node {}", "Jenkinsfile content 'node {}' did not contain the 'pipeline' step\n"))
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/waseem18/gojenkins"
+)
+
+const (
+	// maxInlineLogLength is the size up to which a log is posted inline in a code block.
+	// Larger logs are uploaded as a file.
+	maxInlineLogLength = 4000
+	maxTailLines       = 10000
+	maxLogMatches      = 2000
+	errorContextLines  = 3
+)
+
+// errorLinePattern matches the lines reported by the --errors mode of get-log.
+var errorLinePattern = regexp.MustCompile(`(?i)\b(error|errors|failed|failure|fatal)\b|exception\b|^\s*caused by:`)
+
+// logExcerptOptions selects the part of a console log to post.
+// The zero value selects the whole log.
+type logExcerptOptions struct {
+	Tail   int
+	Grep   *regexp.Regexp
+	Errors bool
+}
+
+// parseLogExcerptOptions builds the excerpt options from the flags passed to the get-log command.
+// The --tail, --grep and --errors flags can't be combined.
+func parseLogExcerptOptions(flags map[string]string) (*logExcerptOptions, error) {
+	if len(flags) > 1 {
+		return nil, errors.New("only one of --tail, --grep and --errors can be used")
+	}
+
+	options := &logExcerptOptions{}
+	if tail, ok := flags["tail"]; ok {
+		n, err := strconv.Atoi(tail)
+		if err != nil || n < 1 || n > maxTailLines {
+			return nil, fmt.Errorf("--tail must be a number between 1 and %d", maxTailLines)
+		}
+		options.Tail = n
+	}
+	if grep, ok := flags["grep"]; ok {
+		re, err := regexp.Compile(grep)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q", grep)
+		}
+		options.Grep = re
+	}
+	if _, ok := flags["errors"]; ok {
+		options.Errors = true
+	}
+	return options, nil
+}
+
+// description describes the excerpt for the post which contains it.
+func (o *logExcerptOptions) description() string {
+	switch {
+	case o.Tail > 0:
+		return fmt.Sprintf("Last %d lines of the console log", o.Tail)
+	case o.Grep != nil:
+		return fmt.Sprintf("Lines matching `%s` in the console log", o.Grep.String())
+	case o.Errors:
+		return "Errors in the console log"
+	default:
+		return "Console log"
+	}
+}
+
+// extract reads the log and returns the selected excerpt.
+func (o *logExcerptOptions) extract(r io.Reader) (string, error) {
+	switch {
+	case o.Tail > 0:
+		return tailLines(r, o.Tail)
+	case o.Grep != nil:
+		return grepLines(r, o.Grep, 0)
+	case o.Errors:
+		return grepLines(r, errorLinePattern, errorContextLines)
+	default:
+		content, err := io.ReadAll(r)
+		return string(content), err
+	}
+}
+
+// readLines calls fn for every line of r, without the trailing newline.
+// Unlike bufio.Scanner, lines of any length are supported.
+func readLines(r io.Reader, fn func(line string)) error {
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			fn(strings.TrimRight(line, "\r\n"))
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// tailLines returns the last n lines of r.
+func tailLines(r io.Reader, n int) (string, error) {
+	ring := make([]string, n)
+	count := 0
+	err := readLines(r, func(line string) {
+		ring[count%n] = line
+		count++
+	})
+	if err != nil {
+		return "", err
+	}
+
+	lines := []string{}
+	for i := count - n; i < count; i++ {
+		if i >= 0 {
+			lines = append(lines, ring[i%n])
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// grepLines returns the lines of r matching re, prefixed with their line number like grep -n does.
+// Up to context lines before and after every match are included, and groups of lines which
+// are not adjacent are separated by --.
+func grepLines(r io.Reader, re *regexp.Regexp, context int) (string, error) {
+	type numberedLine struct {
+		number int
+		text   string
+	}
+
+	var sb strings.Builder
+	before := []numberedLine{}
+	lastPrinted, afterLeft, matches, number := 0, 0, 0, 0
+	writeLine := func(l numberedLine, separator string) {
+		if lastPrinted != 0 && l.number > lastPrinted+1 {
+			sb.WriteString("--\n")
+		}
+		fmt.Fprintf(&sb, "%d%s%s\n", l.number, separator, l.text)
+		lastPrinted = l.number
+	}
+
+	err := readLines(r, func(text string) {
+		number++
+		if matches >= maxLogMatches {
+			return
+		}
+		line := numberedLine{number, text}
+		if re.MatchString(text) {
+			for _, b := range before {
+				writeLine(b, "-")
+			}
+			before = before[:0]
+			writeLine(line, ":")
+			afterLeft = context
+			matches++
+			if matches == maxLogMatches {
+				fmt.Fprintf(&sb, "... stopped after %d matches\n", maxLogMatches)
+			}
+			return
+		}
+		if afterLeft > 0 {
+			writeLine(line, "-")
+			afterLeft--
+			return
+		}
+		if context > 0 {
+			before = append(before, line)
+			if len(before) > context {
+				before = before[1:]
+			}
+		}
+	})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(sb.String(), "\n"), nil
+}
+
+// openJenkinsStream sends an authenticated GET request to the given endpoint of the Jenkins server
+// and returns the response, leaving the body to be read by the caller.
+// Unlike the gojenkins requester, the body isn't read into memory at once.
+func openJenkinsStream(jenkins *gojenkins.Jenkins, endpoint string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, jenkins.Requester.Base+endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	if auth := jenkins.Requester.BasicAuth; auth != nil {
+		req.SetBasicAuth(auth.Username, auth.Password)
+	}
+
+	resp, err := jenkins.Requester.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, &jenkinsStatusError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	return resp, nil
+}
+
+// jenkinsStatusError is an unexpected response status of the Jenkins server.
+type jenkinsStatusError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *jenkinsStatusError) Error() string {
+	return "unexpected response from Jenkins: " + e.Status
+}
+
+// getJenkinsJSON decodes the JSON response of the given endpoint of the Jenkins server into v.
+// Unlike Requester.GetJSON, no api/json suffix is added, which is needed for the REST APIs
+// of plugins such as the Pipeline REST API.
+func getJenkinsJSON(jenkins *gojenkins.Jenkins, endpoint string, v interface{}) error {
+	resp, err := openJenkinsStream(jenkins, endpoint)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// postBuildLog fetches the console log of the given job and build, and posts the part of the log selected by options.
+// Small results are posted inline in a code block, larger ones are uploaded as a file.
+// If build number is not specified, the method fetches the log of the last build of the job.
+func (p *Plugin) postBuildLog(userID, channelID, jobName, buildID string, options *logExcerptOptions) error {
+	build, buildErr := p.getBuild(jobName, userID, buildID)
+	if buildErr != nil {
+		return buildErr
+	}
+
+	resp, err := openJenkinsStream(build.Jenkins, build.Base+"/consoleText")
+	if err != nil {
+		return errors.Wrap(err, "Error fetching console log")
+	}
+	defer resp.Body.Close()
+
+	excerpt, err := options.extract(resp.Body)
+	if err != nil {
+		return errors.Wrap(err, "Error reading console log")
+	}
+	excerpt = p.getBuildRedactor(userID, jobName, build).redact(excerpt)
+
+	msg := fmt.Sprintf("%s of the build #%d of the job '%s'", options.description(), build.GetBuildNumber(), jobName)
+	filename := fmt.Sprintf("%s-%d.log", jobName, build.GetBuildNumber())
+	return p.postLog(userID, channelID, msg, filename, excerpt)
+}
+
+// postLog creates a post with the given log, inline in a code block if it's small
+// or uploaded as a file with the given name otherwise.
+func (p *Plugin) postLog(userID, channelID, msg, filename, log string) error {
+	if strings.TrimSpace(log) == "" {
+		p.createPost(userID, channelID, msg+": nothing found.")
+		return nil
+	}
+
+	if len(log) <= maxInlineLogLength && !strings.Contains(log, "```") {
+		p.createPost(userID, channelID, fmt.Sprintf("%s\n```\n%s\n```", msg, log))
+		return nil
+	}
+
+	fileInfo, fileUploadErr := p.API.UploadFile([]byte(log), channelID, filename)
+	if fileUploadErr != nil {
+		return errors.Wrap(fileUploadErr, "Error uploading file")
+	}
+	p.createPost(userID, channelID, msg, fileInfo.Id)
+	return nil
+}
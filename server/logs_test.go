@@ -0,0 +1,76 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testConsoleLog = `Started by user alice
+Building in workspace /var/jenkins
++ make build
+compiling
+compiling
+compiling
+ERROR: cannot find symbol
+	at Foo.java:12
+done
++ make test
+java.lang.IllegalStateException: boom
+Finished: FAILURE`
+
+func TestTailLines(t *testing.T) {
+	tail, err := tailLines(strings.NewReader(testConsoleLog), 2)
+	require.Nil(t, err)
+	assert.Equal(t, "java.lang.IllegalStateException: boom\nFinished: FAILURE", tail)
+
+	tail, err = tailLines(strings.NewReader("one\ntwo\n"), 5)
+	require.Nil(t, err)
+	assert.Equal(t, "one\ntwo", tail)
+}
+
+func TestGrepLines(t *testing.T) {
+	matches, err := grepLines(strings.NewReader(testConsoleLog), regexp.MustCompile(`^\+ make`), 0)
+	require.Nil(t, err)
+	assert.Equal(t, "3:+ make build\n--\n10:+ make test", matches)
+
+	matches, err = grepLines(strings.NewReader(testConsoleLog), errorLinePattern, 1)
+	require.Nil(t, err)
+	assert.Equal(t, "6-compiling\n7:ERROR: cannot find symbol\n8-\tat Foo.java:12\n--\n10-+ make test\n11:java.lang.IllegalStateException: boom\n12:Finished: FAILURE", matches)
+
+	matches, err = grepLines(strings.NewReader(testConsoleLog), regexp.MustCompile("nothing"), 3)
+	require.Nil(t, err)
+	assert.Equal(t, "", matches)
+}
+
+func TestParseLogExcerptOptions(t *testing.T) {
+	options, err := parseLogExcerptOptions(map[string]string{})
+	require.Nil(t, err)
+	assert.Equal(t, "Console log", options.description())
+
+	options, err = parseLogExcerptOptions(map[string]string{"tail": "200"})
+	require.Nil(t, err)
+	assert.Equal(t, 200, options.Tail)
+	assert.Equal(t, "Last 200 lines of the console log", options.description())
+
+	options, err = parseLogExcerptOptions(map[string]string{"grep": "fail(ed|ure)"})
+	require.Nil(t, err)
+	assert.Equal(t, "Lines matching `fail(ed|ure)` in the console log", options.description())
+
+	options, err = parseLogExcerptOptions(map[string]string{"errors": "true"})
+	require.Nil(t, err)
+	assert.True(t, options.Errors)
+
+	for _, flags := range []map[string]string{
+		{"tail": "0"},
+		{"tail": "many"},
+		{"grep": "("},
+		{"tail": "10", "errors": "true"},
+	} {
+		_, err = parseLogExcerptOptions(flags)
+		assert.NotNil(t, err, flags)
+	}
+}
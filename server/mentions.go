@@ -0,0 +1,264 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+	"github.com/waseem18/gojenkins"
+)
+
+const (
+	// jenkinsUserLinkKeyPrefix prefixes the keys mapping a Jenkins user ID to a Mattermost user ID.
+	jenkinsUserLinkKeyPrefix = "jenkins_user_link_"
+	// mentionsOptOutKey suffixes the user ID in the key storing that a user doesn't want to be mentioned.
+	mentionsOptOutKey = "_jenkinsMentionsOptOut"
+
+	// maxCulpritBuilds is the maximum number of builds searched for changes since the last successful build.
+	maxCulpritBuilds = 50
+	// maxJenkinsUserIDLength is the maximum length of a Jenkins user ID which can be linked.
+	maxJenkinsUserIDLength = 100
+)
+
+// culpritsTreeQuery selects the changes of the latest builds of a job and its last successful build.
+var culpritsTreeQuery = fmt.Sprintf("lastSuccessfulBuild[number],builds[%s]{0,%d}", changesTreeQuery, maxCulpritBuilds)
+
+// jobChanges are the changes of the latest builds of a job.
+type jobChanges struct {
+	LastSuccessfulBuild *struct {
+		Number int64 `json:"number"`
+	} `json:"lastSuccessfulBuild"`
+	Builds []buildChanges `json:"builds"`
+}
+
+// culprit is a Jenkins user who committed changes, with the email of the commits if known.
+type culprit struct {
+	User  jenkinsUser
+	Email string
+}
+
+// isFailedResult reports whether the result of a build is a failure the culprits are mentioned for.
+func isFailedResult(result string) bool {
+	return result == "FAILURE" || result == "UNSTABLE"
+}
+
+// jenkinsUserLinkKey returns the key of the link of a Jenkins user ID. Jenkins user IDs are case-insensitive.
+func jenkinsUserLinkKey(jenkinsUserID string) string {
+	return jenkinsUserLinkKeyPrefix + strings.ToLower(jenkinsUserID)
+}
+
+// collectCulprits returns the authors of the changes of the given build and the builds since the last
+// successful build, followed by the culprits Jenkins reported for the build. Each user is listed once.
+func collectCulprits(changes *jobChanges, buildNumber int64) []culprit {
+	lastSuccessful := int64(0)
+	if changes.LastSuccessfulBuild != nil {
+		lastSuccessful = changes.LastSuccessfulBuild.Number
+	}
+
+	culprits := []culprit{}
+	seen := map[string]bool{}
+	add := func(user jenkinsUser, email string) {
+		key := strings.ToLower(user.ID())
+		if key == "" {
+			key = strings.ToLower(email)
+		}
+		if key == "" {
+			key = user.FullName
+		}
+		if key == "" || seen[key] {
+			return
+		}
+		seen[key] = true
+		culprits = append(culprits, culprit{User: user, Email: email})
+	}
+
+	var failed *buildChanges
+	for i := range changes.Builds {
+		build := &changes.Builds[i]
+		if build.Number > buildNumber || build.Number <= lastSuccessful {
+			continue
+		}
+		if build.Number == buildNumber {
+			failed = build
+		}
+		for _, commit := range build.commits() {
+			add(commit.Author, commit.AuthorEmail)
+		}
+	}
+	if failed != nil {
+		for _, user := range failed.Culprits {
+			add(user, "")
+		}
+	}
+	return culprits
+}
+
+// formatFailureMentions renders the mentions of the culprits and the initiator of a failed build.
+// Returns an empty string if there is nobody to mention.
+func formatFailureMentions(culpritMentions []string, initiatorMention string) string {
+	mentions := []string{}
+	for _, mention := range culpritMentions {
+		if mention != "" && mention != initiatorMention && !containsString(mentions, mention) {
+			mentions = append(mentions, mention)
+		}
+	}
+
+	lines := []string{}
+	if len(mentions) > 0 {
+		lines = append(lines, fmt.Sprintf("Changes since the last successful build by %s", strings.Join(mentions, ", ")))
+	}
+	if initiatorMention != "" {
+		lines = append(lines, fmt.Sprintf("Triggered by %s", initiatorMention))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// getJobChanges fetches the changes of the latest builds of a job.
+func getJobChanges(job *gojenkins.Job) (*jobChanges, error) {
+	changes := &jobChanges{}
+	resp, err := job.Jenkins.Requester.GetJSON(job.Base, changes, map[string]string{"tree": culpritsTreeQuery})
+	if err != nil {
+		return nil, errors.Wrap(err, "Error fetching the changes of the job")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("Error fetching the changes of the job: %s", resp.Status)
+	}
+	return changes, nil
+}
+
+// getFailureMentions returns the mentions of the users who committed changes since the last successful build
+// and of the user who triggered the failed build.
+func (p *Plugin) getFailureMentions(userID, jobName string, build *gojenkins.Build) string {
+	initiator := p.getMention(userID)
+	job, err := p.getJob(userID, jobName)
+	if err != nil {
+		p.API.LogWarn("Error fetching the job to find the culprits", "job_name", jobName, "err", err.Error())
+		return formatFailureMentions(nil, initiator)
+	}
+	changes, err := getJobChanges(job)
+	if err != nil {
+		p.API.LogWarn("Error fetching the culprits of the build", "job_name", jobName, "err", err.Error())
+		return formatFailureMentions(nil, initiator)
+	}
+
+	mention := p.newUserMentioner(build.Jenkins)
+	culpritMentions := []string{}
+	for _, c := range collectCulprits(changes, build.GetBuildNumber()) {
+		culpritMentions = append(culpritMentions, mention(c.User, c.Email))
+	}
+	return formatFailureMentions(culpritMentions, initiator)
+}
+
+// getMention returns the mention of a Mattermost user, or an empty string if the user has opted out of mentions.
+func (p *Plugin) getMention(userID string) string {
+	if p.hasOptedOutOfMentions(userID) {
+		return ""
+	}
+	user, appErr := p.API.GetUser(userID)
+	if appErr != nil {
+		p.API.LogWarn("Error fetching the user to mention", "user_id", userID, "err", appErr.Error())
+		return ""
+	}
+	return "@" + user.Username
+}
+
+// hasOptedOutOfMentions reports whether a user doesn't want to be mentioned in build posts.
+func (p *Plugin) hasOptedOutOfMentions(userID string) bool {
+	optOut, appErr := p.API.KVGet(userID + mentionsOptOutKey)
+	return appErr == nil && optOut != nil
+}
+
+// setMentionsOptOut stores whether a user wants to be mentioned in build posts.
+func (p *Plugin) setMentionsOptOut(userID string, optOut bool) error {
+	if !optOut {
+		if appErr := p.API.KVDelete(userID + mentionsOptOutKey); appErr != nil {
+			return appErr
+		}
+		return nil
+	}
+	if appErr := p.API.KVSet(userID+mentionsOptOutKey, []byte("true")); appErr != nil {
+		return appErr
+	}
+	return nil
+}
+
+// getLinkedUser returns the Mattermost user linked to a Jenkins user ID, or nil if there is none.
+func (p *Plugin) getLinkedUser(jenkinsUserID string) *model.User {
+	if jenkinsUserID == "" {
+		return nil
+	}
+	linkedUserID, appErr := p.API.KVGet(jenkinsUserLinkKey(jenkinsUserID))
+	if appErr != nil || linkedUserID == nil {
+		return nil
+	}
+	user, appErr := p.API.GetUser(string(linkedUserID))
+	if appErr != nil {
+		return nil
+	}
+	return user
+}
+
+// linkConnectedJenkinsUser links the Jenkins account a user connected with /jenkins connect to the user,
+// replacing an existing link, as connecting proves the user owns the account.
+func (p *Plugin) linkConnectedJenkinsUser(userID, jenkinsUsername string) error {
+	if appErr := p.API.KVSet(jenkinsUserLinkKey(jenkinsUsername), []byte(userID)); appErr != nil {
+		return appErr
+	}
+	return nil
+}
+
+// unlinkConnectedJenkinsUser removes the link of the Jenkins account a user disconnected,
+// unless it has been linked to another user since.
+func (p *Plugin) unlinkConnectedJenkinsUser(userID, jenkinsUsername string) error {
+	linkedUserID, appErr := p.API.KVGet(jenkinsUserLinkKey(jenkinsUsername))
+	if appErr != nil {
+		return appErr
+	}
+	if string(linkedUserID) != userID {
+		return nil
+	}
+	if appErr := p.API.KVDelete(jenkinsUserLinkKey(jenkinsUsername)); appErr != nil {
+		return appErr
+	}
+	return nil
+}
+
+// linkJenkinsUser links a Jenkins user ID to a Mattermost user, so the user is mentioned for the changes
+// of the Jenkins user. Users can only link the Jenkins account they connected, system admins any Jenkins user ID.
+// Returns a message describing the outcome.
+func (p *Plugin) linkJenkinsUser(userID, jenkinsUserID string) (string, error) {
+	if jenkinsUserID == "" || len(jenkinsUserID) > maxJenkinsUserIDLength {
+		return fmt.Sprintf("'%s' isn't a valid Jenkins user ID.", jenkinsUserID), nil
+	}
+	if !p.isSystemAdmin(userID) {
+		userInfo, err := p.getJenkinsUserInfo(userID)
+		if err != nil {
+			return "Please connect your Jenkins account with `/jenkins connect` before linking it.", nil
+		}
+		if !strings.EqualFold(userInfo.Username, jenkinsUserID) {
+			return fmt.Sprintf("You can only link the Jenkins account you are connected with, '%s'.", userInfo.Username), nil
+		}
+	}
+	if appErr := p.API.KVSet(jenkinsUserLinkKey(jenkinsUserID), []byte(userID)); appErr != nil {
+		return "", errors.Wrap(appErr, "Error linking the Jenkins user")
+	}
+	return fmt.Sprintf("The Jenkins user '%s' has been linked to your account.", jenkinsUserID), nil
+}
+
+// unlinkJenkinsUser removes the link of a Jenkins user ID. Only the linked user and system admins can remove it.
+// Returns a message describing the outcome.
+func (p *Plugin) unlinkJenkinsUser(userID, jenkinsUserID string) (string, error) {
+	linked := p.getLinkedUser(jenkinsUserID)
+	if linked == nil {
+		return fmt.Sprintf("The Jenkins user '%s' isn't linked.", jenkinsUserID), nil
+	}
+	if linked.Id != userID && !p.isSystemAdmin(userID) {
+		return fmt.Sprintf("The Jenkins user '%s' is linked to @%s.", jenkinsUserID, linked.Username), nil
+	}
+	if appErr := p.API.KVDelete(jenkinsUserLinkKey(jenkinsUserID)); appErr != nil {
+		return "", errors.Wrap(appErr, "Error unlinking the Jenkins user")
+	}
+	return fmt.Sprintf("The Jenkins user '%s' has been unlinked.", jenkinsUserID), nil
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const testJobChanges = `{
+  "lastSuccessfulBuild": {"number": 40},
+  "builds": [
+    {
+      "number": 43,
+      "changeSets": [{"kind": "git", "items": [
+        {"commitId": "d1", "msg": "Not built yet", "authorEmail": "dave@example.com", "author": {"fullName": "Dave", "absoluteUrl": "https://jenkins.example.com/user/dave"}}
+      ]}]
+    },
+    {
+      "number": 42,
+      "changeSets": [{"kind": "git", "items": [
+        {"commitId": "a2", "msg": "Fix the build", "authorEmail": "alice@example.com", "author": {"fullName": "Alice Doe", "absoluteUrl": "https://jenkins.example.com/user/alice"}}
+      ]}],
+      "culprits": [
+        {"fullName": "Alice Doe", "absoluteUrl": "https://jenkins.example.com/user/alice"},
+        {"fullName": "Carol", "absoluteUrl": "https://jenkins.example.com/user/carol"}
+      ]
+    },
+    {
+      "number": 41,
+      "changeSets": [{"kind": "git", "items": [
+        {"commitId": "b1", "msg": "Break the build", "authorEmail": "bob@example.com", "author": {"fullName": "Bob Smith", "absoluteUrl": "https://jenkins.example.com/user/Bob"}},
+        {"commitId": "a1", "msg": "Refactor", "authorEmail": "alice@example.com", "author": {"fullName": "Alice Doe", "absoluteUrl": "https://jenkins.example.com/user/alice"}}
+      ]}]
+    },
+    {
+      "number": 40,
+      "changeSets": [{"kind": "git", "items": [
+        {"commitId": "e1", "msg": "Green", "authorEmail": "erin@example.com", "author": {"fullName": "Erin", "absoluteUrl": "https://jenkins.example.com/user/erin"}}
+      ]}]
+    }
+  ]
+}`
+
+func TestCollectCulprits(t *testing.T) {
+	changes := &jobChanges{}
+	require.NoError(t, json.Unmarshal([]byte(testJobChanges), changes))
+
+	culprits := collectCulprits(changes, 42)
+	ids := []string{}
+	for _, c := range culprits {
+		ids = append(ids, c.User.ID())
+	}
+	assert.Equal(t, []string{"alice", "Bob", "carol"}, ids)
+	assert.Equal(t, "alice@example.com", culprits[0].Email)
+	assert.Equal(t, "", culprits[2].Email)
+
+	// Without a successful build, all the fetched builds are searched.
+	changes.LastSuccessfulBuild = nil
+	assert.Len(t, collectCulprits(changes, 42), 4)
+
+	assert.Empty(t, collectCulprits(&jobChanges{}, 1))
+}
+
+func TestFormatFailureMentions(t *testing.T) {
+	for name, test := range map[string]struct {
+		Culprits  []string
+		Initiator string
+		Expected  string
+	}{
+		"culprits and initiator": {
+			Culprits:  []string{"@alice", "", "@bob", "@alice"},
+			Initiator: "@carol",
+			Expected:  "Changes since the last successful build by @alice, @bob\nTriggered by @carol",
+		},
+		"initiator is a culprit": {
+			Culprits:  []string{"@alice", "@carol"},
+			Initiator: "@carol",
+			Expected:  "Changes since the last successful build by @alice\nTriggered by @carol",
+		},
+		"initiator opted out": {
+			Culprits: []string{"@alice"},
+			Expected: "Changes since the last successful build by @alice",
+		},
+		"nobody to mention": {
+			Culprits: []string{"", ""},
+			Expected: "",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.Expected, formatFailureMentions(test.Culprits, test.Initiator))
+		})
+	}
+}
+
+func TestIsFailedResult(t *testing.T) {
+	assert.True(t, isFailedResult("FAILURE"))
+	assert.True(t, isFailedResult("UNSTABLE"))
+	assert.False(t, isFailedResult("SUCCESS"))
+	assert.False(t, isFailedResult("ABORTED"))
+	assert.False(t, isFailedResult(""))
+}
+
+func TestJenkinsUserLinkKey(t *testing.T) {
+	assert.Equal(t, jenkinsUserLinkKey("alice"), jenkinsUserLinkKey("Alice"))
+}
+
+func TestLinkJenkinsUser(t *testing.T) {
+	p := &Plugin{}
+	api := &plugintest.API{}
+	p.SetAPI(api)
+	p.setConfiguration(&configuration{EncryptionKey: "enckeyenckeyenckeyenckey"}, &model.Config{})
+
+	userInfo, err := json.Marshal(&JenkinsUserInfo{
+		UserID:   "user1",
+		Username: "Alice",
+		Token:    "i1BmOxqUYk_6MtXJNTUtJIQbH2VikZkGPPycfIJhAaY=",
+	})
+	require.NoError(t, err)
+	api.On("KVGet", "user1"+jenkinsTokenKey).Return(userInfo, nil)
+	api.On("KVGet", "user2"+jenkinsTokenKey).Return(nil, nil)
+	api.On("HasPermissionTo", "admin", model.PermissionManageSystem).Return(true)
+	api.On("HasPermissionTo", mock.Anything, model.PermissionManageSystem).Return(false)
+	api.On("KVSet", mock.Anything, mock.Anything).Return(nil)
+
+	msg, err := p.linkJenkinsUser("user1", "alice")
+	require.NoError(t, err)
+	assert.Equal(t, "The Jenkins user 'alice' has been linked to your account.", msg)
+	api.AssertCalled(t, "KVSet", jenkinsUserLinkKey("alice"), []byte("user1"))
+
+	msg, err = p.linkJenkinsUser("user1", "bob")
+	require.NoError(t, err)
+	assert.Equal(t, "You can only link the Jenkins account you are connected with, 'Alice'.", msg)
+
+	msg, err = p.linkJenkinsUser("user2", "bob")
+	require.NoError(t, err)
+	assert.Equal(t, "Please connect your Jenkins account with `/jenkins connect` before linking it.", msg)
+	api.AssertNotCalled(t, "KVSet", jenkinsUserLinkKey("bob"), mock.Anything)
+
+	msg, err = p.linkJenkinsUser("admin", "bob")
+	require.NoError(t, err)
+	assert.Equal(t, "The Jenkins user 'bob' has been linked to your account.", msg)
+	api.AssertCalled(t, "KVSet", jenkinsUserLinkKey("bob"), []byte("admin"))
+}
+
+func TestUnlinkConnectedJenkinsUser(t *testing.T) {
+	p := &Plugin{}
+	api := &plugintest.API{}
+	p.SetAPI(api)
+	api.On("KVGet", jenkinsUserLinkKey("alice")).Return([]byte("user1"), nil)
+	api.On("KVDelete", jenkinsUserLinkKey("alice")).Return(nil)
+
+	// The link is kept if it has been replaced by another user.
+	require.NoError(t, p.unlinkConnectedJenkinsUser("user2", "Alice"))
+	api.AssertNotCalled(t, "KVDelete", mock.Anything)
+
+	require.NoError(t, p.unlinkConnectedJenkinsUser("user1", "Alice"))
+	api.AssertCalled(t, "KVDelete", jenkinsUserLinkKey("alice"))
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/waseem18/gojenkins"
+)
+
+const (
+	// scanTimeout is the time after which a branch indexing run is no longer waited for.
+	scanTimeout = 30 * time.Minute
+	// maxBranchesInPost is the maximum number of branches and pull requests listed in a post.
+	maxBranchesInPost = 50
+)
+
+// branchNameEncoder encodes branch names like Jenkins does for the names of the branch jobs
+// of multibranch projects, so that branches such as feature/x become a single job.
+var branchNameEncoder = strings.NewReplacer("%", "%25", "/", "%2F")
+
+// jenkinsJobPath converts a job name as given in the slash commands to the path of the job expected by gojenkins.
+// Folders are separated by slashes, and the branch of a multibranch project can be given after an @,
+// e.g. project@feature/x.
+func jenkinsJobPath(jobName string) string {
+	project, branch, isBranch := strings.Cut(jobName, "@")
+	path := strings.ReplaceAll(project, "/", "/job/")
+	if isBranch {
+		path += "/job/" + url.PathEscape(branchNameEncoder.Replace(branch))
+	}
+	return path
+}
+
+// branchJob is a branch, pull request or tag job of a multibranch project.
+type branchJob struct {
+	Name      string `json:"name"`
+	Color     string `json:"color"`
+	LastBuild *struct {
+		Number    int64  `json:"number"`
+		Result    string `json:"result"`
+		Building  bool   `json:"building"`
+		Timestamp int64  `json:"timestamp"`
+	} `json:"lastBuild"`
+}
+
+// multibranchProject is a multibranch project with its branch jobs.
+type multibranchProject struct {
+	Class string      `json:"_class"`
+	Jobs  []branchJob `json:"jobs"`
+}
+
+func (m *multibranchProject) isMultibranch() bool {
+	return strings.Contains(m.Class, "MultiBranchProject")
+}
+
+func (m *multibranchProject) jobNames() []string {
+	names := []string{}
+	for _, j := range m.Jobs {
+		names = append(names, j.branchName())
+	}
+	return names
+}
+
+// branchName returns the name of the branch as used in the project@branch syntax.
+func (b *branchJob) branchName() string {
+	name, err := url.PathUnescape(b.Name)
+	if err != nil {
+		return b.Name
+	}
+	return name
+}
+
+// isPullRequest returns whether the job builds a pull or merge request,
+// which the common branch sources name PR-<number> and MR-<number>.
+func (b *branchJob) isPullRequest() bool {
+	return strings.HasPrefix(b.Name, "PR-") || strings.HasPrefix(b.Name, "MR-")
+}
+
+func (b *branchJob) lastResult() string {
+	switch {
+	case b.LastBuild == nil:
+		return "NOT BUILT"
+	case b.LastBuild.Building:
+		return "RUNNING"
+	default:
+		return b.LastBuild.Result
+	}
+}
+
+// formatBranches renders the branches and pull requests of a multibranch project as markdown tables.
+func formatBranches(projectName string, project *multibranchProject) string {
+	if len(project.Jobs) == 0 {
+		return fmt.Sprintf("No branches found in the project '%s'. Use `/jenkins scan %s` to scan the repository.", projectName, quoteJobName(projectName))
+	}
+
+	branches, pullRequests := []branchJob{}, []branchJob{}
+	for _, j := range project.Jobs {
+		if j.isPullRequest() {
+			pullRequests = append(pullRequests, j)
+		} else {
+			branches = append(branches, j)
+		}
+	}
+
+	var sb strings.Builder
+	listed := 0
+	writeTable := func(title string, jobs []branchJob) {
+		if len(jobs) == 0 {
+			return
+		}
+		sort.Slice(jobs, func(i, k int) bool { return jobs[i].branchName() < jobs[k].branchName() })
+		fmt.Fprintf(&sb, "**%s**\n\n", title)
+		sb.WriteString("| Name | Last build | Result | Started |\n")
+		sb.WriteString("|:-----|:-----------|:-------|:--------|\n")
+		for _, j := range jobs {
+			if listed == maxBranchesInPost {
+				break
+			}
+			listed++
+			if j.LastBuild == nil {
+				fmt.Fprintf(&sb, "| `%s` | - | %s | - |\n", escapeTableCell(j.branchName()), j.lastResult())
+				continue
+			}
+			fmt.Fprintf(&sb, "| `%s` | #%d | %s | %s |\n",
+				escapeTableCell(j.branchName()),
+				j.LastBuild.Number,
+				j.lastResult(),
+				time.UnixMilli(j.LastBuild.Timestamp).UTC().Format("2006-01-02 15:04"),
+			)
+		}
+		sb.WriteString("\n")
+	}
+
+	fmt.Fprintf(&sb, "Branches of the project '%s'\n\n", projectName)
+	writeTable("Branches", branches)
+	writeTable("Pull requests", pullRequests)
+	if len(project.Jobs) > listed {
+		fmt.Fprintf(&sb, "...and %d more.\n\n", len(project.Jobs)-listed)
+	}
+	fmt.Fprintf(&sb, "Use `%s@<branch>` as job name to build a branch or get its logs, e.g. `/jenkins build %s@%s`.", projectName, projectName, project.Jobs[0].branchName())
+	return sb.String()
+}
+
+// diffBranches returns the branches which have been added to and removed from a project.
+func diffBranches(before, after []string) ([]string, []string) {
+	added, removed := []string{}, []string{}
+	for _, name := range after {
+		if !containsString(before, name) {
+			added = append(added, name)
+		}
+	}
+	for _, name := range before {
+		if !containsString(after, name) {
+			removed = append(removed, name)
+		}
+	}
+	return added, removed
+}
+
+// scanResult returns the result of a finished branch indexing run from its log.
+func scanResult(log string) (string, bool) {
+	log = strings.TrimRight(log, "\r\n")
+	idx := strings.LastIndex(log, "\n")
+	lastLine := log[idx+1:]
+	if !strings.HasPrefix(lastLine, "Finished: ") {
+		return "", false
+	}
+	return strings.TrimPrefix(lastLine, "Finished: "), true
+}
+
+// formatScanReport renders the post sent when a branch indexing run has finished.
+func formatScanReport(projectName, result string, added, removed []string) string {
+	icon := ":white_check_mark:"
+	if result != "SUCCESS" {
+		icon = ":x:"
+	}
+	msg := fmt.Sprintf("%s Scan of the project '%s' finished: %s", icon, projectName, result)
+	if len(added) == 0 && len(removed) == 0 {
+		return msg + "\nNo branches have been added or removed."
+	}
+	if len(added) > 0 {
+		msg += fmt.Sprintf("\nAdded: `%s`", strings.Join(added, "`, `"))
+	}
+	if len(removed) > 0 {
+		msg += fmt.Sprintf("\nRemoved: `%s`", strings.Join(removed, "`, `"))
+	}
+	return msg
+}
+
+// getMultibranchProject fetches the given multibranch project with its branch jobs.
+func (p *Plugin) getMultibranchProject(userID, projectName string) (*gojenkins.Job, *multibranchProject, error) {
+	job, jobErr := p.getJob(userID, projectName)
+	if jobErr != nil {
+		return nil, nil, jobErr
+	}
+
+	project := &multibranchProject{}
+	query := map[string]string{"tree": "_class,jobs[name,color,lastBuild[number,result,building,timestamp]]"}
+	resp, err := job.Jenkins.Requester.GetJSON(job.Base, project, query)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "Error fetching branches")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, errors.Errorf("Error fetching branches: %s", resp.Status)
+	}
+	return job, project, nil
+}
+
+// postBranches creates a post with the branches and pull requests of a multibranch project.
+func (p *Plugin) postBranches(userID, channelID, projectName string) error {
+	_, project, err := p.getMultibranchProject(userID, projectName)
+	if err != nil {
+		return err
+	}
+	if !project.isMultibranch() {
+		p.createPost(userID, channelID, fmt.Sprintf("The job '%s' isn't a multibranch project.", projectName))
+		return nil
+	}
+	p.createPost(userID, channelID, formatBranches(projectName, project))
+	return nil
+}
+
+// getScanLog fetches the log of the last branch indexing run of a project.
+func getScanLog(job *gojenkins.Job) (string, error) {
+	var log string
+	resp, err := job.Jenkins.Requester.Get(job.Base+"/indexing/consoleText", &log, nil)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return "", nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.Errorf("unexpected response from Jenkins: %s", resp.Status)
+	}
+	return log, nil
+}
+
+// scanProject triggers a branch indexing run of a multibranch project and posts a report once it has finished.
+func (p *Plugin) scanProject(userID, channelID, projectName string) error {
+	job, project, err := p.getMultibranchProject(userID, projectName)
+	if err != nil {
+		return err
+	}
+	if !project.isMultibranch() {
+		p.createPost(userID, channelID, fmt.Sprintf("The job '%s' isn't a multibranch project.", projectName))
+		return nil
+	}
+
+	previousLog, err := getScanLog(job)
+	if err != nil {
+		return errors.Wrap(err, "Error fetching the scan log")
+	}
+	if err := postJenkinsForm(job.Jenkins, job.Base+"/build", url.Values{}); err != nil {
+		return errors.Wrap(err, "Error triggering the scan")
+	}
+	p.createPost(userID, channelID, fmt.Sprintf("Scan of the project '%s' has been triggered.", projectName))
+
+	go func() {
+		deadline := time.Now().Add(scanTimeout)
+		for time.Now().Before(deadline) {
+			if !p.sleep(pollingSleepTime * time.Second) {
+				return
+			}
+			log, err := getScanLog(job)
+			if err != nil {
+				p.API.LogWarn("Error fetching the scan log", "job_name", projectName, "err", err.Error())
+				continue
+			}
+			result, finished := scanResult(log)
+			if !finished || log == previousLog {
+				continue
+			}
+
+			_, scanned, err := p.getMultibranchProject(userID, projectName)
+			if err != nil {
+				p.API.LogWarn("Error fetching branches after scan", "job_name", projectName, "err", err.Error())
+				scanned = project
+			}
+			added, removed := diffBranches(project.jobNames(), scanned.jobNames())
+			p.createPost(userID, channelID, formatScanReport(projectName, result, added, removed))
+			return
+		}
+		p.createPost(userID, channelID, fmt.Sprintf("Scan of the project '%s' didn't finish within %s.", projectName, formatDuration(scanTimeout)))
+	}()
+	return nil
+}
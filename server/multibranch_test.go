@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJenkinsJobPath(t *testing.T) {
+	for jobName, expected := range map[string]string{
+		"job1":                      "job1",
+		"folder/job1":               "folder/job/job1",
+		"project@main":              "project/job/main",
+		"folder/project@feature/x":  "folder/job/project/job/feature%252Fx",
+		"project@fix/100%-coverage": "project/job/fix%252F100%2525-coverage",
+	} {
+		assert.Equal(t, expected, jenkinsJobPath(jobName), jobName)
+	}
+}
+
+const testMultibranchProject = `{
+	"_class": "org.jenkinsci.plugins.workflow.multibranch.WorkflowMultiBranchProject",
+	"jobs": [
+		{"name": "main", "lastBuild": {"number": 8, "result": "SUCCESS", "timestamp": 1700000000000}},
+		{"name": "PR-12", "lastBuild": {"number": 2, "building": true, "timestamp": 1700000000000}},
+		{"name": "feature%2Fx"}
+	]
+}`
+
+func TestFormatBranches(t *testing.T) {
+	project := &multibranchProject{}
+	require.Nil(t, json.Unmarshal([]byte(testMultibranchProject), project))
+	assert.True(t, project.isMultibranch())
+
+	msg := formatBranches("repo", project)
+	assert.Contains(t, msg, "**Branches**\n\n| Name | Last build | Result | Started |\n|:-----|:-----------|:-------|:--------|\n| `feature/x` | - | NOT BUILT | - |\n| `main` | #8 | SUCCESS | 2023-11-14 22:13 |\n")
+	assert.Contains(t, msg, "**Pull requests**\n\n| Name | Last build | Result | Started |\n|:-----|:-----------|:-------|:--------|\n| `PR-12` | #2 | RUNNING | 2023-11-14 22:13 |\n")
+
+	assert.Equal(t, "No branches found in the project 'repo'. Use `/jenkins scan repo` to scan the repository.", formatBranches("repo", &multibranchProject{}))
+}
+
+func TestScanResult(t *testing.T) {
+	result, finished := scanResult("Started\n[Mon] Starting branch indexing...\nFinished: SUCCESS\n")
+	assert.True(t, finished)
+	assert.Equal(t, "SUCCESS", result)
+
+	_, finished = scanResult("Started\n[Mon] Starting branch indexing...\n")
+	assert.False(t, finished)
+}
+
+func TestFormatScanReport(t *testing.T) {
+	added, removed := diffBranches([]string{"main", "old"}, []string{"main", "feature/x", "PR-3"})
+	assert.Equal(t, []string{"feature/x", "PR-3"}, added)
+	assert.Equal(t, []string{"old"}, removed)
+
+	assert.Equal(t, ":white_check_mark: Scan of the project 'repo' finished: SUCCESS\nAdded: `feature/x`, `PR-3`\nRemoved: `old`", formatScanReport("repo", "SUCCESS", added, removed))
+	assert.Equal(t, ":x: Scan of the project 'repo' finished: FAILURE\nNo branches have been added or removed.", formatScanReport("repo", "FAILURE", nil, nil))
+}
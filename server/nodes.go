@@ -0,0 +1,284 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+	"github.com/waseem18/gojenkins"
+)
+
+const (
+	nodesTreeQuery = "computer[_class,displayName,numExecutors,offline,temporarilyOffline,offlineCauseReason," +
+		"assignedLabels[name],executors[idle],monitorData[*]]"
+
+	diskSpaceMonitor    = "hudson.node_monitors.DiskSpaceMonitor"
+	clockMonitor        = "hudson.node_monitors.ClockMonitor"
+	responseTimeMonitor = "hudson.node_monitors.ResponseTimeMonitor"
+
+	// builtInNodeName is the name of the built-in node in the URLs of Jenkins.
+	builtInNodeName = "(built-in)"
+)
+
+// jenkinsNode is an agent or the built-in node, as returned by the /computer API.
+type jenkinsNode struct {
+	Class              string `json:"_class"`
+	DisplayName        string `json:"displayName"`
+	NumExecutors       int    `json:"numExecutors"`
+	Offline            bool   `json:"offline"`
+	TemporarilyOffline bool   `json:"temporarilyOffline"`
+	OfflineCauseReason string `json:"offlineCauseReason"`
+	AssignedLabels     []struct {
+		Name string `json:"name"`
+	} `json:"assignedLabels"`
+	Executors []struct {
+		Idle bool `json:"idle"`
+	} `json:"executors"`
+	MonitorData map[string]interface{} `json:"monitorData"`
+}
+
+// nodeFilter holds the filters of the nodes command.
+type nodeFilter struct {
+	Label   string
+	Offline bool
+}
+
+func (n *jenkinsNode) isBuiltIn() bool {
+	return strings.HasSuffix(n.Class, "MasterComputer")
+}
+
+// urlName returns the name of the node in the URLs of Jenkins.
+func (n *jenkinsNode) urlName() string {
+	if n.isBuiltIn() {
+		return builtInNodeName
+	}
+	return n.DisplayName
+}
+
+func (n *jenkinsNode) busyExecutors() int {
+	busy := 0
+	for _, e := range n.Executors {
+		if !e.Idle {
+			busy++
+		}
+	}
+	return busy
+}
+
+// labels returns the labels of the node, without the implicit label named after the node itself.
+func (n *jenkinsNode) labels() []string {
+	labels := []string{}
+	for _, l := range n.AssignedLabels {
+		if l.Name != n.DisplayName && l.Name != "built-in" && l.Name != "master" {
+			labels = append(labels, l.Name)
+		}
+	}
+	return labels
+}
+
+func (n *jenkinsNode) hasLabel(label string) bool {
+	for _, l := range n.AssignedLabels {
+		if l.Name == label {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether the node is referred to by the given name.
+func (n *jenkinsNode) matches(name string) bool {
+	name = strings.Trim(name, "()")
+	return n.DisplayName == name || (n.isBuiltIn() && (name == "built-in" || name == "master"))
+}
+
+func (n *jenkinsNode) status() string {
+	switch {
+	case n.TemporarilyOffline:
+		return ":no_entry: Offline"
+	case n.Offline:
+		return ":red_circle: Disconnected"
+	default:
+		return ":large_green_circle: Online"
+	}
+}
+
+// monitorValue returns a numeric field of the data of the given node monitor.
+func (n *jenkinsNode) monitorValue(monitor, field string) (float64, bool) {
+	data, ok := n.MonitorData[monitor].(map[string]interface{})
+	if !ok {
+		return 0, false
+	}
+	value, ok := data[field].(float64)
+	return value, ok
+}
+
+func (n *jenkinsNode) diskSpace() string {
+	size, ok := n.monitorValue(diskSpaceMonitor, "size")
+	if !ok {
+		return "-"
+	}
+	return formatBytes(int64(size))
+}
+
+func (n *jenkinsNode) clockDrift() string {
+	diff, ok := n.monitorValue(clockMonitor, "diff")
+	if !ok {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f ms", diff)
+}
+
+func (n *jenkinsNode) responseTime() string {
+	average, ok := n.monitorValue(responseTimeMonitor, "average")
+	if !ok {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f ms", average)
+}
+
+// formatBytes renders a size in bytes with a binary unit.
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// filterNodes returns the nodes matching the filter.
+func filterNodes(nodes []jenkinsNode, filter *nodeFilter) []jenkinsNode {
+	filtered := []jenkinsNode{}
+	for _, n := range nodes {
+		if filter.Label != "" && !n.hasLabel(filter.Label) {
+			continue
+		}
+		if filter.Offline && !n.Offline {
+			continue
+		}
+		filtered = append(filtered, n)
+	}
+	return filtered
+}
+
+// formatNodes renders the nodes as a markdown table.
+func formatNodes(nodes []jenkinsNode) string {
+	if len(nodes) == 0 {
+		return "No nodes match the given filters."
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d node(s)\n\n", len(nodes))
+	sb.WriteString("| Node | Status | Busy executors | Labels | Free disk space | Clock drift | Response time |\n")
+	sb.WriteString("|:-----|:-------|:---------------|:-------|:----------------|:------------|:--------------|\n")
+	for _, n := range nodes {
+		status := n.status()
+		if n.Offline && n.OfflineCauseReason != "" {
+			status += ": " + n.OfflineCauseReason
+		}
+		fmt.Fprintf(&sb, "| %s | %s | %d/%d | %s | %s | %s | %s |\n",
+			escapeTableCell(n.DisplayName),
+			escapeTableCell(status),
+			n.busyExecutors(),
+			n.NumExecutors,
+			escapeTableCell(strings.Join(n.labels(), ", ")),
+			n.diskSpace(),
+			n.clockDrift(),
+			n.responseTime(),
+		)
+	}
+	return sb.String()
+}
+
+// getNodes fetches the nodes of the Jenkins server.
+func getNodes(jenkins *gojenkins.Jenkins) ([]jenkinsNode, error) {
+	var computers struct {
+		Computer []jenkinsNode `json:"computer"`
+	}
+	resp, err := jenkins.Requester.GetJSON("/computer", &computers, map[string]string{"tree": nodesTreeQuery})
+	if err != nil {
+		return nil, errors.Wrap(err, "Error fetching nodes")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("Error fetching nodes: %s", resp.Status)
+	}
+	return computers.Computer, nil
+}
+
+// postNodes creates a post with the nodes matching the filter.
+func (p *Plugin) postNodes(userID, channelID string, filter *nodeFilter) error {
+	jenkins, err := p.getJenkinsClient(userID)
+	if err != nil {
+		return errors.Wrap(err, "Error creating Jenkins client")
+	}
+	nodes, err := getNodes(jenkins)
+	if err != nil {
+		return err
+	}
+	p.createPost(userID, channelID, formatNodes(filterNodes(nodes, filter)))
+	return nil
+}
+
+// isSystemAdmin reports whether the Mattermost user is a system admin.
+func (p *Plugin) isSystemAdmin(userID string) bool {
+	return p.API.HasPermissionTo(userID, model.PermissionManageSystem)
+}
+
+// manageNode takes the node with the given name offline, brings it back online or disconnects it.
+// Returns a message describing the outcome.
+func (p *Plugin) manageNode(userID, action, nodeName, reason string) (string, error) {
+	jenkins, err := p.getJenkinsClient(userID)
+	if err != nil {
+		return "", errors.Wrap(err, "Error creating Jenkins client")
+	}
+	nodes, err := getNodes(jenkins)
+	if err != nil {
+		return "", err
+	}
+
+	var node *jenkinsNode
+	for i := range nodes {
+		if nodes[i].matches(nodeName) {
+			node = &nodes[i]
+			break
+		}
+	}
+	if node == nil {
+		return fmt.Sprintf("No node named '%s' found.", nodeName), nil
+	}
+
+	base := "/computer/" + url.PathEscape(node.urlName())
+	form := url.Values{"offlineMessage": {reason}}
+	switch action {
+	case "offline":
+		if node.TemporarilyOffline {
+			return fmt.Sprintf("Node '%s' is already offline: %s", node.DisplayName, node.OfflineCauseReason), nil
+		}
+		if err := postJenkinsForm(jenkins, base+"/toggleOffline", form); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Node '%s' has been taken offline: %s", node.DisplayName, reason), nil
+	case "online":
+		if !node.TemporarilyOffline {
+			return fmt.Sprintf("Node '%s' isn't marked offline.", node.DisplayName), nil
+		}
+		if err := postJenkinsForm(jenkins, base+"/toggleOffline", url.Values{}); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Node '%s' has been brought back online.", node.DisplayName), nil
+	case "disconnect":
+		if err := postJenkinsForm(jenkins, base+"/doDisconnect", form); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Node '%s' has been disconnected.", node.DisplayName), nil
+	default:
+		return "", errors.Errorf("unknown node action %q", action)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testNodes = `[
+	{"_class": "hudson.model.Hudson$MasterComputer", "displayName": "Built-In Node", "numExecutors": 2,
+	 "assignedLabels": [{"name": "built-in"}], "executors": [{"idle": false}, {"idle": true}],
+	 "monitorData": {"hudson.node_monitors.DiskSpaceMonitor": {"size": 53687091200},
+	                 "hudson.node_monitors.ClockMonitor": {"diff": 0},
+	                 "hudson.node_monitors.ResponseTimeMonitor": {"average": 12}}},
+	{"_class": "hudson.slaves.SlaveComputer", "displayName": "linux-1", "numExecutors": 4,
+	 "offline": true, "temporarilyOffline": true, "offlineCauseReason": "Disk | full",
+	 "assignedLabels": [{"name": "docker"}, {"name": "linux"}, {"name": "linux-1"}],
+	 "executors": [{"idle": true}, {"idle": true}, {"idle": true}, {"idle": true}],
+	 "monitorData": {"hudson.node_monitors.DiskSpaceMonitor": null}},
+	{"_class": "hudson.slaves.SlaveComputer", "displayName": "windows-1", "numExecutors": 1, "offline": true,
+	 "assignedLabels": [{"name": "windows"}, {"name": "windows-1"}], "executors": [{"idle": true}]}
+]`
+
+func getTestNodes(t *testing.T) []jenkinsNode {
+	var nodes []jenkinsNode
+	require.Nil(t, json.Unmarshal([]byte(testNodes), &nodes))
+	return nodes
+}
+
+func TestFilterNodes(t *testing.T) {
+	nodes := getTestNodes(t)
+	for _, test := range []struct {
+		filter   nodeFilter
+		expected []string
+	}{
+		{nodeFilter{}, []string{"Built-In Node", "linux-1", "windows-1"}},
+		{nodeFilter{Label: "linux"}, []string{"linux-1"}},
+		{nodeFilter{Offline: true}, []string{"linux-1", "windows-1"}},
+		{nodeFilter{Label: "windows", Offline: true}, []string{"windows-1"}},
+		{nodeFilter{Label: "macos"}, []string{}},
+	} {
+		names := []string{}
+		for _, n := range filterNodes(nodes, &test.filter) {
+			names = append(names, n.DisplayName)
+		}
+		assert.Equal(t, test.expected, names)
+	}
+}
+
+func TestFormatNodes(t *testing.T) {
+	msg := formatNodes(getTestNodes(t))
+	assert.Contains(t, msg, "3 node(s)\n")
+	assert.Contains(t, msg, "| Built-In Node | :large_green_circle: Online | 1/2 |  | 50.0 GiB | 0 ms | 12 ms |\n")
+	assert.Contains(t, msg, "| linux-1 | :no_entry: Offline: Disk \\| full | 0/4 | docker, linux | - | - | - |\n")
+	assert.Contains(t, msg, "| windows-1 | :red_circle: Disconnected | 0/1 | windows | - | - | - |\n")
+
+	assert.Equal(t, "No nodes match the given filters.", formatNodes(nil))
+}
+
+func TestJenkinsNodeMatches(t *testing.T) {
+	nodes := getTestNodes(t)
+	assert.True(t, nodes[0].matches("built-in"))
+	assert.True(t, nodes[0].matches("(built-in)"))
+	assert.Equal(t, "(built-in)", nodes[0].urlName())
+	assert.True(t, nodes[1].matches("linux-1"))
+	assert.False(t, nodes[1].matches("built-in"))
+	assert.Equal(t, "linux-1", nodes[1].urlName())
+}
+
+func TestFormatBytes(t *testing.T) {
+	assert.Equal(t, "512 B", formatBytes(512))
+	assert.Equal(t, "1.5 KiB", formatBytes(1536))
+	assert.Equal(t, "2.0 GiB", formatBytes(2*1024*1024*1024))
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/pkg/errors"
+)
+
+// pipelineJobTemplate is the config.xml of a Pipeline job running the Jenkinsfile of a Git repository.
+const pipelineJobTemplate = `<?xml version='1.0' encoding='UTF-8'?>
+<flow-definition plugin="workflow-job">
+  <actions/>
+  <description></description>
+  <keepDependencies>false</keepDependencies>
+  <properties/>
+  <definition class="org.jenkinsci.plugins.workflow.cps.CpsScmFlowDefinition" plugin="workflow-cps">
+    <scm class="hudson.plugins.git.GitSCM" plugin="git">
+      <configVersion>2</configVersion>
+      <userRemoteConfigs>
+        <hudson.plugins.git.UserRemoteConfig>
+          <url>{{.RepoURL}}</url>{{if .CredentialsID}}
+          <credentialsId>{{.CredentialsID}}</credentialsId>{{end}}
+        </hudson.plugins.git.UserRemoteConfig>
+      </userRemoteConfigs>
+      <branches>
+        <hudson.plugins.git.BranchSpec>
+          <name>*/{{.Branch}}</name>
+        </hudson.plugins.git.BranchSpec>
+      </branches>
+      <doGenerateSubmoduleConfigurations>false</doGenerateSubmoduleConfigurations>
+      <submoduleCfg class="empty-list"/>
+      <extensions/>
+    </scm>
+    <scriptPath>{{.ScriptPath}}</scriptPath>
+    <lightweight>true</lightweight>
+  </definition>
+  <triggers/>
+  <disabled>false</disabled>
+</flow-definition>
+`
+
+// gitURLPattern matches the URLs of Git repositories, e.g. https://host/repo.git or git@host:repo.git.
+var gitURLPattern = regexp.MustCompile(`^((https?|ssh|git|file)://\S+|[\w.-]+@[\w.-]+:\S+)$`)
+
+// pipelineOptions are the options of the new-pipeline command.
+type pipelineOptions struct {
+	JobName       string
+	RepoURL       string
+	Branch        string
+	ScriptPath    string
+	CredentialsID string
+	Build         bool
+}
+
+// parsePipelineOptions parses the parameters of the new-pipeline command.
+func parsePipelineOptions(parameters []string) (*pipelineOptions, error) {
+	positional, flags, err := parseFlags(parameters, []string{"branch", "script-path", "credentials"}, []string{"build"})
+	if err != nil {
+		return nil, err
+	}
+	jobName, repoURL, ok := parseQuotedArgument(positional)
+	if !ok || repoURL == "" {
+		return nil, errors.New("a job name and a repository URL are required")
+	}
+	if !gitURLPattern.MatchString(repoURL) {
+		return nil, errors.Errorf("invalid repository URL %q", repoURL)
+	}
+
+	options := &pipelineOptions{
+		JobName:       jobName,
+		RepoURL:       repoURL,
+		Branch:        "main",
+		ScriptPath:    "Jenkinsfile",
+		CredentialsID: flags["credentials"],
+	}
+	if branch, ok := flags["branch"]; ok {
+		options.Branch = branch
+	}
+	if scriptPath, ok := flags["script-path"]; ok {
+		options.ScriptPath = scriptPath
+	}
+	if options.Branch == "" || options.ScriptPath == "" {
+		return nil, errors.New("the branch and the script path can't be empty")
+	}
+	_, options.Build = flags["build"]
+	return options, nil
+}
+
+// pipelineConfigXML renders the config.xml of the Pipeline job with the given options.
+func pipelineConfigXML(options *pipelineOptions) (string, error) {
+	t, err := parseJobTemplate("pipeline", pipelineJobTemplate)
+	if err != nil {
+		return "", err
+	}
+	return renderJobTemplate(t, map[string]string{
+		"RepoURL":       options.RepoURL,
+		"Branch":        options.Branch,
+		"ScriptPath":    options.ScriptPath,
+		"CredentialsID": options.CredentialsID,
+	})
+}
+
+// createPipelineJob creates a Pipeline job running the Jenkinsfile of a Git repository, and
+// triggers its first build if requested.
+func (p *Plugin) createPipelineJob(userID, channelID string, options *pipelineOptions) error {
+	configXML, err := pipelineConfigXML(options)
+	if err != nil {
+		return err
+	}
+	jenkins, err := p.getJenkinsClient(userID)
+	if err != nil {
+		return errors.Wrap(err, "Error creating Jenkins client")
+	}
+	if _, err := createJobWithFolders(jenkins, options.JobName, configXML); err != nil {
+		return errors.Wrap(err, "Error creating the job")
+	}
+	p.createPost(userID, channelID, fmt.Sprintf("Pipeline job '%s' has been created for `%s` on branch `%s`.", options.JobName, options.RepoURL, options.Branch))
+
+	if !options.Build {
+		return nil
+	}
+	build, err := p.triggerJenkinsJob(userID, channelID, options.JobName, nil)
+	if err != nil {
+		return errors.Wrap(err, "Error triggering the first build")
+	}
+	p.createPost(userID, channelID, fmt.Sprintf("Job '%s' - #%d has been started\nBuild URL : %s", options.JobName, build.GetBuildNumber(), build.GetUrl()))
+	return nil
+}
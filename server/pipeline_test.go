@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePipelineOptions(t *testing.T) {
+	for params, expected := range map[string]*pipelineOptions{
+		"team/app https://github.com/org/app.git": {
+			JobName: "team/app", RepoURL: "https://github.com/org/app.git", Branch: "main", ScriptPath: "Jenkinsfile",
+		},
+		`"my team/my app" git@github.com:org/app.git --branch develop --script-path ci/Jenkinsfile --credentials github-ssh --build`: {
+			JobName: "my team/my app", RepoURL: "git@github.com:org/app.git", Branch: "develop", ScriptPath: "ci/Jenkinsfile",
+			CredentialsID: "github-ssh", Build: true,
+		},
+	} {
+		options, err := parsePipelineOptions(strings.Fields(params))
+		require.Nil(t, err, params)
+		assert.Equal(t, expected, options, params)
+	}
+
+	for _, params := range []string{
+		"",
+		"app",
+		"app not-a-url",
+		"app https://github.com/org/app.git extra",
+		"app https://github.com/org/app.git --branch",
+		"app https://github.com/org/app.git --unknown x",
+		`app https://github.com/org/app.git --branch ""`,
+	} {
+		_, err := parsePipelineOptions(strings.Fields(params))
+		assert.NotNil(t, err, params)
+	}
+}
+
+func TestPipelineConfigXML(t *testing.T) {
+	configXML, err := pipelineConfigXML(&pipelineOptions{
+		RepoURL: "https://github.com/org/app.git?a=1&b=2", Branch: "release/1.x", ScriptPath: "ci/Jenkinsfile", CredentialsID: "github",
+	})
+	require.Nil(t, err)
+
+	var config struct {
+		Definition struct {
+			Class      string `xml:"class,attr"`
+			URL        string `xml:"scm>userRemoteConfigs>hudson.plugins.git.UserRemoteConfig>url"`
+			Credential string `xml:"scm>userRemoteConfigs>hudson.plugins.git.UserRemoteConfig>credentialsId"`
+			Branch     string `xml:"scm>branches>hudson.plugins.git.BranchSpec>name"`
+			ScriptPath string `xml:"scriptPath"`
+		} `xml:"definition"`
+	}
+	require.Nil(t, xml.Unmarshal([]byte(configXML), &config))
+	assert.Equal(t, "org.jenkinsci.plugins.workflow.cps.CpsScmFlowDefinition", config.Definition.Class)
+	assert.Equal(t, "https://github.com/org/app.git?a=1&b=2", config.Definition.URL)
+	assert.Equal(t, "github", config.Definition.Credential)
+	assert.Equal(t, "*/release/1.x", config.Definition.Branch)
+	assert.Equal(t, "ci/Jenkinsfile", config.Definition.ScriptPath)
+
+	configXML, err = pipelineConfigXML(&pipelineOptions{RepoURL: "https://github.com/org/app.git", Branch: "main", ScriptPath: "Jenkinsfile"})
+	require.Nil(t, err)
+	assert.NotContains(t, configXML, "credentialsId")
+}
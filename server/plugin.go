@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -10,6 +11,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -19,6 +21,7 @@ import (
 	"github.com/mattermost/mattermost/server/public/model"
 	"github.com/mattermost/mattermost/server/public/plugin"
 	"github.com/mattermost/mattermost/server/public/pluginapi"
+	"github.com/mattermost/mattermost/server/public/pluginapi/cluster"
 )
 
 const (
@@ -42,6 +45,22 @@ type Plugin struct {
 	configuration *configuration
 
 	botUserID string
+
+	// healthCheckJob is the background job probing the Jenkins server.
+	healthCheckJob *cluster.Job
+	// pluginDigestJob is the background job posting the weekly plugin digest.
+	pluginDigestJob *cluster.Job
+
+	// updateCenterCache caches the data of the update site used by the plugins commands.
+	updateCenterCache updateCenterCache
+
+	// watchedBuilds is the number of builds currently watched for their completion.
+	watchedBuilds atomic.Int32
+
+	// backgroundCtx is cancelled when the plugin is deactivated, to stop the goroutines
+	// polling Jenkins in the background.
+	backgroundCtx    context.Context
+	cancelBackground context.CancelFunc
 }
 
 type JenkinsUserInfo struct {
@@ -52,6 +71,7 @@ type JenkinsUserInfo struct {
 
 func (p *Plugin) OnActivate() error {
 	p.client = pluginapi.NewClient(p.API, p.Driver)
+	p.backgroundCtx, p.cancelBackground = context.WithCancel(context.Background())
 
 	botUserID, err := p.client.Bot.EnsureBot(&model.Bot{
 		Username:    botUserName,
@@ -91,9 +111,47 @@ func (p *Plugin) OnActivate() error {
 	if err := p.IsValid(conf); err != nil {
 		return err
 	}
+
+	if err := p.scheduleHealthCheck(); err != nil {
+		return err
+	}
+	return p.schedulePluginDigest()
+}
+
+func (p *Plugin) OnDeactivate() error {
+	if p.cancelBackground != nil {
+		p.cancelBackground()
+	}
+	for _, job := range []*cluster.Job{p.healthCheckJob, p.pluginDigestJob} {
+		if job == nil {
+			continue
+		}
+		if err := job.Close(); err != nil {
+			p.API.LogWarn("Error closing background job", "err", err.Error())
+		}
+	}
 	return nil
 }
 
+// stopped returns a channel which is closed once the plugin is deactivated.
+func (p *Plugin) stopped() <-chan struct{} {
+	if p.backgroundCtx == nil {
+		return nil
+	}
+	return p.backgroundCtx.Done()
+}
+
+// sleep pauses a background goroutine for the given duration.
+// Returns false if the plugin has been deactivated in the meantime, in which case the goroutine has to stop.
+func (p *Plugin) sleep(d time.Duration) bool {
+	select {
+	case <-p.stopped():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
 func (p *Plugin) IsValid(configuration *configuration) error {
 	if configuration.JenkinsURL == "" {
 		return fmt.Errorf("please add Jenkins URL in plugin settings")
@@ -190,15 +248,21 @@ func (p *Plugin) createEphemeralPost(userID, channelID, message string) {
 }
 
 // createPost creates a non epehemeral post
-func (p *Plugin) createPost(userID, channelID, message string, fileIds ...string) {
+func (p *Plugin) createPost(userID, channelID, message string, fileIds ...string) *model.Post {
+	return p.createAttachmentPost(userID, channelID, generateSlackAttachment(message), fileIds...)
+}
+
+// createAttachmentPost creates a non ephemeral post with the given attachment.
+// Returns nil if the post couldn't be created.
+func (p *Plugin) createAttachmentPost(userID, channelID string, slackAttachment *model.SlackAttachment, fileIds ...string) *model.Post {
 	userInfo, userInfoErr := p.getJenkinsUserInfo(userID)
 	if userInfoErr != nil {
 		p.API.LogError("Error fetching Jenkins user details", "err", userInfoErr.Error())
-		return
+		return nil
 	}
 
-	slackAttachment := generateSlackAttachment(message)
 	slackAttachment.Pretext = fmt.Sprintf("Initiated by Jenkins user: %s", userInfo.Username)
+	slackAttachment.Text = p.getRedactor().redact(slackAttachment.Text)
 	post := &model.Post{
 		UserId:    p.botUserID,
 		ChannelId: channelID,
@@ -212,8 +276,39 @@ func (p *Plugin) createPost(userID, channelID, message string, fileIds ...string
 		post.FileIds = append(post.FileIds, fileIds[0])
 	}
 
-	if _, err := p.API.CreatePost(post); err != nil {
+	createdPost, err := p.API.CreatePost(post)
+	if err != nil {
 		p.API.LogError("Could not create a post", "user_id", userID, "err", err.Error())
+		return nil
+	}
+	return createdPost
+}
+
+// closeActionPost removes the buttons from the attachments of the given post and shows the footer instead.
+func (p *Plugin) closeActionPost(post *model.Post, footer string) {
+	post = post.Clone()
+	attachments := post.Attachments()
+	for _, a := range attachments {
+		a.Actions = nil
+		a.Footer = footer
+	}
+	post.AddProp("attachments", attachments)
+	if _, appErr := p.API.UpdatePost(post); appErr != nil {
+		p.API.LogWarn("Error updating post", "post_id", post.Id, "err", appErr.Error())
+	}
+}
+
+// createReply creates a post by the bot in the thread of the given post.
+func (p *Plugin) createReply(rootPost *model.Post, message string) {
+	post := &model.Post{
+		UserId:    p.botUserID,
+		ChannelId: rootPost.ChannelId,
+		RootId:    rootPost.Id,
+		Message:   p.getRedactor().redact(message),
+		Type:      model.PostTypeDefault,
+	}
+	if _, err := p.API.CreatePost(post); err != nil {
+		p.API.LogError("Could not create a reply", "root_id", rootPost.Id, "err", err.Error())
 	}
 }
 
@@ -241,12 +336,7 @@ func (p *Plugin) getJob(userID, jobName string) (*gojenkins.Job, error) {
 		return nil, errors.Wrap(jenkinsErr, "Error creating Jenkins client")
 	}
 
-	containsSlash := strings.Contains(jobName, "/")
-	if containsSlash {
-		jobName = strings.ReplaceAll(jobName, "/", "/job/")
-	}
-
-	job, jobErr := jenkins.GetJob(jobName)
+	job, jobErr := jenkins.GetJob(jenkinsJobPath(jobName))
 	if jobErr != nil {
 		return nil, errors.Wrap(jobErr, "Error fetching job")
 	}
@@ -285,35 +375,34 @@ func (p *Plugin) triggerJenkinsJob(userID, channelID, jobName string, parameters
 	if jenkinsErr != nil {
 		return nil, errors.Wrap(jenkinsErr, "Error creating Jenkins client")
 	}
-	containsSlash := strings.Contains(jobName, "/")
-	if containsSlash {
-		jobName = strings.ReplaceAll(jobName, "/", "/job/")
-	}
 	buildQueueID, buildErr := p.buildJenkinsJob(jenkins, userID, channelID, jobName, parameters)
 	if buildErr != nil {
 		return nil, buildErr
 	}
-	build, err := p.checkIfJobHasStarted(jenkins, jobName, buildQueueID)
+	build, err := p.checkIfJobHasStarted(jenkins, jenkinsJobPath(jobName), buildQueueID)
 	if err != nil {
 		return nil, err
 	}
+	if p.getConfiguration().PostBuildCompletion {
+		go p.watchBuild(userID, channelID, jobName, build.GetBuildNumber())
+	}
 	return build, nil
 }
 
 // buildJenkinsJob starts a given Jenkins build and
 // creates an ephemeral post once the build has been successfully triggered.
 func (p *Plugin) buildJenkinsJob(jenkins *gojenkins.Jenkins, userID, channelID, jobName string, parameters map[string]string) (int64, error) {
-	buildQueueID, buildErr := jenkins.BuildJob(jobName, parameters)
+	buildQueueID, buildErr := jenkins.BuildJob(jenkinsJobPath(jobName), parameters)
 	if buildErr != nil {
 		return -1, errors.Wrap(buildErr, "Error building job")
 	}
 
 	if buildQueueID == 0 {
-		p.createEphemeralPost(userID, channelID, "A build of this job is still in queue.\n Please trigger the job after the job's build queue is free.")
+		p.postStillInQueue(jenkins, userID, channelID, jobName)
 		return -1, errors.Wrap(buildErr, "error building the job as a previous build is still in queue")
 	}
 
-	p.createPost(userID, channelID, fmt.Sprintf("Job '%s' has been triggered and is in queue.", strings.ReplaceAll(jobName, "/job/", "/")))
+	p.createPost(userID, channelID, fmt.Sprintf("Job '%s' has been triggered and is in queue.", jobName))
 	return buildQueueID, nil
 }
 
@@ -372,34 +461,6 @@ func (p *Plugin) fetchAndUploadArtifactsOfABuild(userID, channelID, jobName, bui
 	return nil
 }
 
-// getBuildTestResultsURL checks if the specified job and build has test results and
-// creates a post with the test results URL if the  build has test results.
-// If build number is not specified, the method checks the last build of the job for test results.
-func (p *Plugin) getBuildTestResultsURL(userID, channelID, jobName, buildID string) error {
-	build, buildErr := p.getBuild(jobName, userID, buildID)
-	if buildErr != nil {
-		return buildErr
-	}
-
-	hasTestResults, hasTestErr := build.HasTestResults()
-	if hasTestErr != nil {
-		return errors.Wrap(hasTestErr, "Error checking for test results")
-	}
-	msg := ""
-	if hasTestResults {
-		job, jobErr := p.getJob(userID, jobName)
-		if jobErr != nil {
-			return jobErr
-		}
-		testReportsURL := fmt.Sprintf("%s%d/testReport", job.Raw.URL, build.GetBuildNumber())
-		msg = fmt.Sprintf("Test reports for the build #%d of the job '%s': %s", build.GetBuildNumber(), jobName, testReportsURL)
-	} else {
-		msg = fmt.Sprintf("Build #%d of the job '%s' doesn't have test reports.", build.GetBuildNumber(), jobName)
-	}
-	p.createPost(userID, channelID, msg)
-	return nil
-}
-
 // disableJob disables a given job.
 // Returns an error if the operation is not successful.
 func (p *Plugin) disableJob(userID, jobName string) error {
@@ -488,26 +549,6 @@ func (p *Plugin) createDialogForParameters(userID, triggerID, jobName, channelID
 	return nil
 }
 
-// fetchAndUploadBuildLog fetches console log of the given job and build.
-// and uploads the console log as file to Mattermost server.
-func (p *Plugin) fetchAndUploadBuildLog(userID, channelID, jobName, buildID string) error {
-	build, buildErr := p.getBuild(jobName, userID, buildID)
-	if buildErr != nil {
-		return buildErr
-	}
-
-	consoleOutput := build.GetConsoleOutput()
-	filename := fmt.Sprintf("%s-%d", jobName, build.GetBuildNumber())
-	fileInfo, fileUploadErr := p.API.UploadFile([]byte(consoleOutput), channelID, filename)
-	if fileUploadErr != nil {
-		return errors.Wrap(fileUploadErr, "Error uploading file")
-	}
-
-	msg := fmt.Sprintf("Console log of the build #%d of the job '%s'", build.GetBuildNumber(), jobName)
-	p.createPost(userID, channelID, msg, fileInfo.Id)
-	return nil
-}
-
 // abortBuild aborts a given build.
 // If the build ID is specified as an empty string, method fetches and aborts the last build of the job.
 func (p *Plugin) abortBuild(userID, jobName, buildID string) error {
@@ -541,41 +582,6 @@ func (p *Plugin) deleteJob(userID, jobName string) error {
 	return nil
 }
 
-// safeRestart safe restarts the Jenkins server.
-// Returns an error if the operation fails.
-func (p *Plugin) safeRestart(userID string) error {
-	jenkins, jenkinsErr := p.getJenkinsClient(userID)
-	if jenkinsErr != nil {
-		return errors.Wrap(jenkinsErr, "Error creating Jenkins client")
-	}
-	if err := jenkins.SafeRestart(); err != nil {
-		return err
-	}
-	return nil
-}
-
-// getListOfInstalledPlugins fetches the list of installed plugins on the Jenkins server.
-func (p *Plugin) getListOfInstalledPlugins(userID, channelID string) error {
-	jenkins, jenkinsErr := p.getJenkinsClient(userID)
-	if jenkinsErr != nil {
-		return errors.Wrap(jenkinsErr, "Error creating Jenkins client")
-	}
-	plugins, pluginsErr := jenkins.GetPlugins(1)
-	if pluginsErr != nil {
-		return pluginsErr
-	}
-	msg := ""
-	for k, v := range plugins.Raw.Plugins {
-		status := "Disabled"
-		if v.Enabled {
-			status = "Enabled"
-		}
-		msg += fmt.Sprintf("%d. %s - %s - %s\n", k+1, v.LongName, v.Version, status)
-	}
-	p.createPost(userID, channelID, msg)
-	return nil
-}
-
 func (p *Plugin) createJob(userID, channelID, triggerID string) error {
 	if err := p.createDialogForJobCreation(userID, channelID, triggerID); err != nil {
 		return err
@@ -620,50 +626,61 @@ func (p *Plugin) createDialogForJobCreation(userID, channelID, triggerID string)
 // sendJobCreateRequest first parses the job name to analyze the folder and job names to be created
 // and triggers a job creation request using the contents of config.xml pasted in the dialog.
 func (p *Plugin) sendJobCreateRequest(userID, channelID string, parameters map[string]string) error {
-	jobName := parameters["JobName"]
-	configXML := parameters["ConfigXml"]
+	msg, err := p.createJobFromConfig(userID, channelID, parameters["JobName"], parameters["ConfigXml"])
+	if msg != "" {
+		p.createEphemeralPost(userID, channelID, msg)
+	}
+	return err
+}
 
+// createJobFromConfig creates a job with the given config.xml and posts about it in the channel.
+// Returns a message for the user if the job couldn't be created.
+func (p *Plugin) createJobFromConfig(userID, channelID, jobName, configXML string) (string, error) {
 	jenkins, jenkinsErr := p.getJenkinsClient(userID)
 	if jenkinsErr != nil {
-		return errors.Wrap(jenkinsErr, "Error creating Jenkins client")
+		return "", errors.Wrap(jenkinsErr, "Error creating Jenkins client")
 	}
 
 	jobName, extraParam, ok := parseBuildParameters(strings.Split(jobName, " "))
 	if !ok || extraParam != "" {
-		p.createEphemeralPost(userID, channelID, "Please check `/jenkins help` to find help on how to create a job.")
-		return errors.New("error while creating the job")
-	}
-	if strings.Contains(jobName, "/") {
-		splitString := strings.Split(jobName, "/")
-		jobName = splitString[len(splitString)-1]
-		folderList := splitString[:len(splitString)-1]
-		parentFolders := []string{}
-		for _, v := range folderList {
-			_, fErr := jenkins.GetFolder(v, parentFolders...)
-			if fErr != nil {
-				_, err := jenkins.CreateFolder(v, parentFolders...)
-				if err != nil {
-					p.createEphemeralPost(userID, channelID, "Error creating the job.")
-					return err
-				}
-			}
-			parentFolders = append(parentFolders, v)
-		}
-
-		job, jobErr := jenkins.CreateJobInFolder(configXML, jobName, folderList...)
-		if jobErr != nil {
-			p.createEphemeralPost(userID, channelID, "Error creating the job.")
-			return jobErr
-		}
-		p.createPost(userID, channelID, fmt.Sprintf("Job '%s' has been created.", job.GetName()))
-		return nil
+		return "Please check `/jenkins help` to find help on how to create a job.", errors.New("error while creating the job")
 	}
-	job, err := jenkins.CreateJob(configXML, jobName)
+	job, err := createJobWithFolders(jenkins, jobName, configXML)
 	if err != nil {
-		p.createEphemeralPost(userID, channelID, "Error creating the job.")
-		return err
+		return "Error creating the job.", err
+	}
+	p.createPost(userID, channelID, fmt.Sprintf("Job '%s' has been created.", job.GetName()))
+
+	return "", nil
+}
+
+// createJobWithFolders creates a job with the given config.xml. If the job name is given as
+// folder/jobname, the folders which don't exist yet are created first.
+func createJobWithFolders(jenkins *gojenkins.Jenkins, jobName, configXML string) (*gojenkins.Job, error) {
+	if !strings.Contains(jobName, "/") {
+		return jenkins.CreateJob(configXML, jobName)
+	}
+
+	splitString := strings.Split(jobName, "/")
+	jobName = splitString[len(splitString)-1]
+	folderList := splitString[:len(splitString)-1]
+	if err := ensureFolders(jenkins, folderList); err != nil {
+		return nil, err
 	}
-	p.createPost(userID, channelID, fmt.Sprintf("Job '%s' has been created", job.GetName()))
+	return jenkins.CreateJobInFolder(configXML, jobName, folderList...)
+}
 
+// ensureFolders creates the given nested folders which don't exist yet.
+func ensureFolders(jenkins *gojenkins.Jenkins, folders []string) error {
+	parentFolders := []string{}
+	for _, v := range folders {
+		_, fErr := jenkins.GetFolder(v, parentFolders...)
+		if fErr != nil {
+			if _, err := jenkins.CreateFolder(v, parentFolders...); err != nil {
+				return err
+			}
+		}
+		parentFolders = append(parentFolders, v)
+	}
 	return nil
 }
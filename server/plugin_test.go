@@ -1,10 +1,12 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/mattermost/mattermost/server/public/model"
 	"github.com/mattermost/mattermost/server/public/plugin/plugintest"
@@ -49,3 +51,13 @@ func TestGetJob(t *testing.T) {
 	assert.NotNil(t, j)
 	assert.Equal(t, "/job/job1", j.Base)
 }
+
+func TestSleepStopsOnDeactivate(t *testing.T) {
+	p := &Plugin{}
+	assert.True(t, p.sleep(time.Millisecond))
+
+	p.backgroundCtx, p.cancelBackground = context.WithCancel(context.Background())
+	assert.True(t, p.sleep(time.Millisecond))
+	assert.NoError(t, p.OnDeactivate())
+	assert.False(t, p.sleep(time.Hour))
+}
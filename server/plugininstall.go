@@ -0,0 +1,424 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/waseem18/gojenkins"
+)
+
+const (
+	pluginsInstall = "plugins-install"
+	pluginsUpdate  = "plugins-update"
+	pluginsEnable  = "plugins-enable"
+	pluginsDisable = "plugins-disable"
+
+	updateCenterJobsTreeQuery = "restartRequiredForCompletion,jobs[id,type,errorMessage,plugin[name,version],status[type,success]]"
+
+	// pluginInstallTimeout is the time after which the installation of plugins is no longer tracked.
+	pluginInstallTimeout = 30 * time.Minute
+)
+
+var (
+	pluginNamePattern    = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+	pluginVersionPattern = regexp.MustCompile(`^[A-Za-z0-9_.+-]+$`)
+)
+
+// pluginSpec is a plugin to install, with the version to install or latest.
+type pluginSpec struct {
+	Name    string
+	Version string
+}
+
+func (s pluginSpec) String() string {
+	return s.Name + "@" + s.Version
+}
+
+// updateCenterJob is a job of the update center, such as the installation of a plugin.
+type updateCenterJob struct {
+	ID           int64  `json:"id"`
+	Type         string `json:"type"`
+	ErrorMessage string `json:"errorMessage"`
+	Plugin       *struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	} `json:"plugin"`
+	Status *struct {
+		Type    string `json:"type"`
+		Success bool   `json:"success"`
+	} `json:"status"`
+}
+
+// isFinished reports whether the job has succeeded, failed or has been skipped.
+func (j *updateCenterJob) isFinished() bool {
+	if j.Status == nil {
+		return false
+	}
+	switch j.Status.Type {
+	case "Pending", "Installing":
+		return false
+	default:
+		return true
+	}
+}
+
+// updateCenter is the state of the update center jobs.
+type updateCenter struct {
+	RestartRequiredForCompletion bool              `json:"restartRequiredForCompletion"`
+	Jobs                         []updateCenterJob `json:"jobs"`
+}
+
+// parsePluginSpecs parses plugins given as shortName or shortName@version.
+func parsePluginSpecs(parameters []string) ([]pluginSpec, error) {
+	specs := []pluginSpec{}
+	for _, param := range parameters {
+		name, version, hasVersion := strings.Cut(param, "@")
+		if !hasVersion {
+			version = "latest"
+		}
+		if !pluginNamePattern.MatchString(name) || !pluginVersionPattern.MatchString(version) {
+			return nil, fmt.Errorf("invalid plugin %q", param)
+		}
+		specs = append(specs, pluginSpec{Name: name, Version: version})
+	}
+	return specs, nil
+}
+
+// formatPluginSpecs joins the plugins as they are given in the plugins install command.
+func formatPluginSpecs(specs []pluginSpec) string {
+	names := []string{}
+	for _, s := range specs {
+		names = append(names, s.String())
+	}
+	return strings.Join(names, " ")
+}
+
+// pluginInstallRequest creates the request of installNecessaryPlugins installing the given plugins.
+func pluginInstallRequest(specs []pluginSpec) string {
+	var sb strings.Builder
+	sb.WriteString("<jenkins>")
+	for _, s := range specs {
+		fmt.Fprintf(&sb, `<install plugin="%s" />`, s)
+	}
+	sb.WriteString("</jenkins>")
+	return sb.String()
+}
+
+// pluginUpdateSpecs returns the plugins of the report with an update, at their available version.
+func pluginUpdateSpecs(report *pluginReport) []pluginSpec {
+	specs := []pluginSpec{}
+	for _, ip := range report.Plugins {
+		version, hasUpdate := report.Updates[ip.ShortName]
+		if !hasUpdate {
+			continue
+		}
+		if version == "" {
+			version = "latest"
+		}
+		specs = append(specs, pluginSpec{Name: ip.ShortName, Version: version})
+	}
+	return specs
+}
+
+// resolvePluginSpecs checks the plugins to install against the installed plugins and the update center,
+// as Jenkins starts no installation job for plugins which are up to date or unknown. Jenkins always installs
+// the latest version of a plugin, so a requested version other than the latest one is skipped as well.
+// Returns the plugins to install, and the reasons why the other plugins are skipped.
+// Without update center data, the plugins which aren't installed are assumed to be available.
+func resolvePluginSpecs(specs []pluginSpec, plugins []installedPlugin, data *updateCenterData) ([]pluginSpec, []string) {
+	installed := make(map[string]installedPlugin)
+	for _, ip := range plugins {
+		installed[ip.ShortName] = ip
+	}
+
+	toInstall := []pluginSpec{}
+	skipped := []string{}
+	for _, s := range specs {
+		ip, isInstalled := installed[s.Name]
+		available, isAvailable := "", data == nil
+		if data != nil {
+			if plugin, ok := data.Plugins[s.Name]; ok {
+				available, isAvailable = plugin.Version, true
+			}
+		}
+
+		switch {
+		case !isInstalled && !isAvailable:
+			skipped = append(skipped, fmt.Sprintf("`%s` - not found in the update center", s.Name))
+		case isInstalled && s.Version != "latest" && compareVersions(s.Version, ip.Version) <= 0:
+			skipped = append(skipped, fmt.Sprintf("`%s` - %s is already installed", s.Name, ip.Version))
+		case s.Version != "latest" && available != "" && compareVersions(s.Version, available) != 0:
+			skipped = append(skipped, fmt.Sprintf("`%s` - only the latest version %s can be installed", s, available))
+		case !isInstalled, s.Version != "latest":
+			toInstall = append(toInstall, s)
+		case available != "" && compareVersions(available, ip.Version) > 0, available == "" && ip.HasUpdate:
+			toInstall = append(toInstall, s)
+		default:
+			skipped = append(skipped, fmt.Sprintf("`%s` - the latest version %s is already installed", s.Name, ip.Version))
+		}
+	}
+	return toInstall, skipped
+}
+
+// formatSkippedPlugins renders the post listing the plugins which are not installed.
+func formatSkippedPlugins(skipped []string) string {
+	return fmt.Sprintf("%d plugin(s) skipped:\n* %s", len(skipped), strings.Join(skipped, "\n* "))
+}
+
+// installationJobs returns the installation jobs started after the job with the given ID, including
+// the jobs of the dependencies Jenkins installs along with the requested plugins, and whether all of them have finished.
+// Jenkins creates the jobs when the installation is requested, and none for plugins it finds nothing to install for.
+func installationJobs(center *updateCenter, lastJobID int64) ([]updateCenterJob, bool) {
+	jobs := []updateCenterJob{}
+	for _, job := range center.Jobs {
+		if job.ID <= lastJobID || job.Plugin == nil {
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	for i := range jobs {
+		if !jobs[i].isFinished() {
+			return jobs, false
+		}
+	}
+	return jobs, true
+}
+
+// formatInstallationReport renders the post sent once the installation jobs have finished.
+// The plugins which aren't among the given plugins are marked as dependencies, and the given plugins
+// Jenkins started no job for are listed as not installed.
+func formatInstallationReport(jobs []updateCenterJob, specs []pluginSpec, restartRequired bool) string {
+	var sb strings.Builder
+	failed := 0
+	for _, job := range jobs {
+		if !job.Status.Success {
+			failed++
+		}
+	}
+	if failed == 0 {
+		sb.WriteString(":white_check_mark: The installation of the plugins has finished.\n")
+	} else {
+		fmt.Fprintf(&sb, ":x: The installation of %d of %d plugins has failed.\n", failed, len(jobs))
+	}
+
+	requested := make(map[string]bool)
+	for _, s := range specs {
+		requested[s.Name] = true
+	}
+	for _, job := range jobs {
+		status := job.Status.Type
+		if job.ErrorMessage != "" {
+			status += ": " + job.ErrorMessage
+		}
+		dependency := ""
+		if !requested[job.Plugin.Name] {
+			dependency = " (dependency)"
+		}
+		fmt.Fprintf(&sb, "* `%s` %s%s - %s\n", job.Plugin.Name, job.Plugin.Version, dependency, status)
+	}
+	started := make(map[string]bool)
+	for _, job := range jobs {
+		started[job.Plugin.Name] = true
+	}
+	for _, s := range specs {
+		if !started[s.Name] {
+			fmt.Fprintf(&sb, "* `%s` - Not installed: Jenkins found nothing to install\n", s.Name)
+		}
+	}
+
+	if restartRequired {
+		sb.WriteString("\n:arrows_counterclockwise: Jenkins has to be restarted to complete the installation. Use `/jenkins restart --safe` to restart it.")
+	} else {
+		sb.WriteString("\nNo restart is required.")
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// getUpdateCenter fetches the jobs of the update center.
+func getUpdateCenter(jenkins *gojenkins.Jenkins) (*updateCenter, error) {
+	center := &updateCenter{}
+	resp, err := jenkins.Requester.GetJSON("/updateCenter", center, map[string]string{"tree": updateCenterJobsTreeQuery})
+	if err != nil {
+		return nil, errors.Wrap(err, "Error fetching update center jobs")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("Error fetching update center jobs: %s", resp.Status)
+	}
+	return center, nil
+}
+
+// askPluginInstallConfirmation asks the user to confirm the installation of the given plugins.
+func (p *Plugin) askPluginInstallConfirmation(userID, channelID string, specs []pluginSpec) {
+	names := []string{}
+	for _, s := range specs {
+		names = append(names, fmt.Sprintf("`%s`", s))
+	}
+	p.askConfirmation(userID, channelID, &confirmationRequest{
+		Message: fmt.Sprintf("Install %s on Jenkins?", strings.Join(names, ", ")),
+		Action:  pluginsInstall,
+		Args:    map[string]string{"plugins": formatPluginSpecs(specs)},
+	})
+}
+
+// askPluginUpdateConfirmation asks the user to confirm the update of all plugins with an update.
+func (p *Plugin) askPluginUpdateConfirmation(userID, channelID string) error {
+	jenkins, err := p.getJenkinsClient(userID)
+	if err != nil {
+		return errors.Wrap(err, "Error creating Jenkins client")
+	}
+	report, err := p.getPluginReport(jenkins)
+	if err != nil {
+		return err
+	}
+	specs := pluginUpdateSpecs(report)
+	if len(specs) == 0 {
+		p.createEphemeralPost(userID, channelID, "All plugins are up to date.")
+		return nil
+	}
+
+	names := []string{}
+	for _, s := range specs {
+		names = append(names, fmt.Sprintf("`%s`", s))
+	}
+	p.askConfirmation(userID, channelID, &confirmationRequest{
+		Message: fmt.Sprintf("Update %d plugins on Jenkins?\n%s", len(specs), strings.Join(names, ", ")),
+		Action:  pluginsUpdate,
+		Args:    map[string]string{"plugins": formatPluginSpecs(specs)},
+	})
+	return nil
+}
+
+// askPluginToggleConfirmation asks the user to confirm enabling or disabling the given plugin.
+func (p *Plugin) askPluginToggleConfirmation(userID, channelID, action, name string) {
+	verb := "Enable"
+	if action == pluginsDisable {
+		verb = "Disable"
+	}
+	p.askConfirmation(userID, channelID, &confirmationRequest{
+		Message: fmt.Sprintf("%s the plugin `%s` on Jenkins? Jenkins has to be restarted to apply the change.", verb, name),
+		Action:  action,
+		Args:    map[string]string{"name": name},
+	})
+}
+
+// installPlugins installs the given plugins through the update center in the background.
+// Returns a message describing the outcome.
+func (p *Plugin) installPlugins(userID, channelID string, specs []pluginSpec) (string, error) {
+	if !p.isSystemAdmin(userID) {
+		return "Only system admins can manage plugins.", nil
+	}
+
+	jenkins, err := p.getJenkinsClient(userID)
+	if err != nil {
+		return "", errors.Wrap(err, "Error creating Jenkins client")
+	}
+	go func() {
+		if err := p.runPluginInstallation(jenkins, userID, channelID, specs); err != nil {
+			p.API.LogError("Error installing plugins", "err", err.Error())
+			p.createPost(userID, channelID, "Encountered an error installing the plugins.")
+		}
+	}()
+	return fmt.Sprintf("Installing %d plugin(s)...", len(specs)), nil
+}
+
+// runPluginInstallation requests the installation of the given plugins, and posts the outcome once the
+// installation jobs have finished. The plugins which are up to date or unknown are reported right away.
+func (p *Plugin) runPluginInstallation(jenkins *gojenkins.Jenkins, userID, channelID string, specs []pluginSpec) error {
+	plugins, err := getInstalledPlugins(jenkins)
+	if err != nil {
+		return err
+	}
+	data, err := p.getUpdateCenterData(jenkins)
+	if err != nil {
+		p.API.LogWarn("Error fetching the update center data", "err", err.Error())
+	}
+	specs, skipped := resolvePluginSpecs(specs, plugins, data)
+	if len(skipped) > 0 {
+		p.createPost(userID, channelID, formatSkippedPlugins(skipped))
+	}
+	if len(specs) == 0 {
+		p.createPost(userID, channelID, "No plugins have to be installed.")
+		return nil
+	}
+
+	center, err := getUpdateCenter(jenkins)
+	if err != nil {
+		return err
+	}
+	lastJobID := int64(0)
+	for _, job := range center.Jobs {
+		if job.ID > lastJobID {
+			lastJobID = job.ID
+		}
+	}
+
+	if err := requestPluginInstallation(jenkins, specs); err != nil {
+		return err
+	}
+	p.createPost(userID, channelID, fmt.Sprintf("Installation of %d plugin(s) has been triggered.", len(specs)))
+	p.trackPluginInstallation(jenkins, userID, channelID, lastJobID, specs)
+	return nil
+}
+
+// requestPluginInstallation asks Jenkins to install the given plugins with their dependencies.
+func requestPluginInstallation(jenkins *gojenkins.Jenkins, specs []pluginSpec) (err error) {
+	defer recoverJenkinsPanic(&err)
+	resp, err := jenkins.Requester.PostXML("/pluginManager/installNecessaryPlugins", pluginInstallRequest(specs), nil, nil)
+	if err != nil {
+		return errors.Wrap(err, "Error installing plugins")
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return errors.Errorf("Error installing plugins: %s", resp.Status)
+	}
+	return nil
+}
+
+// trackPluginInstallation polls the update center jobs until the installation of the given plugins has finished,
+// or the plugin is deactivated.
+func (p *Plugin) trackPluginInstallation(jenkins *gojenkins.Jenkins, userID, channelID string, lastJobID int64, specs []pluginSpec) {
+	deadline := time.Now().Add(pluginInstallTimeout)
+	for time.Now().Before(deadline) {
+		if !p.sleep(pollingSleepTime * time.Second) {
+			return
+		}
+		center, err := getUpdateCenter(jenkins)
+		if err != nil {
+			p.API.LogWarn("Error tracking plugin installation", "err", err.Error())
+			continue
+		}
+		jobs, finished := installationJobs(center, lastJobID)
+		if finished {
+			p.createPost(userID, channelID, formatInstallationReport(jobs, specs, center.RestartRequiredForCompletion))
+			return
+		}
+	}
+	p.createPost(userID, channelID, fmt.Sprintf("The installation of the plugins didn't finish within %s.", formatDuration(pluginInstallTimeout)))
+}
+
+// togglePlugin enables or disables the given plugin. Returns a message describing the outcome.
+func (p *Plugin) togglePlugin(userID, channelID, action, name string) (string, error) {
+	if !p.isSystemAdmin(userID) {
+		return "Only system admins can manage plugins.", nil
+	}
+
+	jenkins, err := p.getJenkinsClient(userID)
+	if err != nil {
+		return "", errors.Wrap(err, "Error creating Jenkins client")
+	}
+	endpoint, done := "/makeEnabled", "enabled"
+	if action == pluginsDisable {
+		endpoint, done = "/makeDisabled", "disabled"
+	}
+	if err := postJenkinsForm(jenkins, "/pluginManager/plugin/"+url.PathEscape(name)+endpoint, url.Values{}); err != nil {
+		return "", errors.Wrapf(err, "Error updating plugin %s", name)
+	}
+
+	msg := fmt.Sprintf("Plugin `%s` has been %s.\n:arrows_counterclockwise: Jenkins has to be restarted to apply the change. Use `/jenkins restart --safe` to restart it.", name, done)
+	p.createPost(userID, channelID, msg)
+	return fmt.Sprintf("Plugin `%s` has been %s.", name, done), nil
+}
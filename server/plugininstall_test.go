@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testUpdateCenterJobs = `{"restartRequiredForCompletion": true, "jobs": [
+	{"id": 1, "type": "ConnectionCheckJob"},
+	{"id": 2, "type": "InstallationJob", "plugin": {"name": "git", "version": "5.2.0"}, "status": {"type": "Success", "success": true}},
+	{"id": 3, "type": "InstallationJob", "plugin": {"name": "git-client", "version": "4.6.0"}, "status": {"type": "Success", "success": true}},
+	{"id": 4, "type": "InstallationJob", "plugin": {"name": "git", "version": "5.2.1"}, "status": {"type": "SuccessButRequiresRestart", "success": true}},
+	{"id": 5, "type": "InstallationJob", "plugin": {"name": "docker-workflow", "version": "572.v950f58993843"},
+	 "status": {"type": "Failure", "success": false}, "errorMessage": "Failed to download"}
+]}`
+
+func TestParsePluginSpecs(t *testing.T) {
+	specs, err := parsePluginSpecs([]string{"git", "docker-workflow@572.v950f58993843"})
+	require.Nil(t, err)
+	assert.Equal(t, []pluginSpec{{Name: "git", Version: "latest"}, {Name: "docker-workflow", Version: "572.v950f58993843"}}, specs)
+	assert.Equal(t, "git@latest docker-workflow@572.v950f58993843", formatPluginSpecs(specs))
+	assert.Equal(t, `<jenkins><install plugin="git@latest" /><install plugin="docker-workflow@572.v950f58993843" /></jenkins>`, pluginInstallRequest(specs))
+
+	for _, invalid := range []string{"git@", "@1.0", `git"/><install plugin="evil`, "git@1.0@2.0"} {
+		_, err := parsePluginSpecs([]string{invalid})
+		assert.NotNil(t, err, invalid)
+	}
+}
+
+func TestPluginUpdateSpecs(t *testing.T) {
+	report := &pluginReport{
+		Plugins: []installedPlugin{{ShortName: "credentials"}, {ShortName: "git"}, {ShortName: "script-security"}},
+		Updates: map[string]string{"git": "5.2.1", "script-security": ""},
+	}
+	assert.Equal(t, []pluginSpec{{Name: "git", Version: "5.2.1"}, {Name: "script-security", Version: "latest"}}, pluginUpdateSpecs(report))
+}
+
+func TestInstallationJobs(t *testing.T) {
+	var center updateCenter
+	require.Nil(t, json.Unmarshal([]byte(testUpdateCenterJobs), &center))
+
+	jobs, finished := installationJobs(&center, 3)
+	assert.True(t, finished)
+	require.Len(t, jobs, 2)
+	assert.Equal(t, int64(4), jobs[0].ID)
+
+	// The dependencies installed along with the plugins are tracked as well.
+	jobs, finished = installationJobs(&center, 1)
+	assert.True(t, finished)
+	assert.Len(t, jobs, 4)
+
+	// Jenkins starts no job for plugins it finds nothing to install for.
+	jobs, finished = installationJobs(&center, 5)
+	assert.True(t, finished)
+	assert.Empty(t, jobs)
+
+	center.Jobs[4].Status.Type = "Installing"
+	_, finished = installationJobs(&center, 3)
+	assert.False(t, finished)
+	_, finished = installationJobs(&center, 1)
+	assert.False(t, finished)
+}
+
+func TestResolvePluginSpecs(t *testing.T) {
+	plugins := []installedPlugin{
+		{ShortName: "git", Version: "5.2.1"},
+		{ShortName: "credentials", Version: "1309.v8835d63eb_d8a_"},
+		{ShortName: "matrix-auth", Version: "3.1", HasUpdate: true},
+	}
+	data := &updateCenterData{}
+	require.Nil(t, json.Unmarshal([]byte(`{"plugins": {
+		"git": {"version": "5.2.1"},
+		"credentials": {"version": "1319.v7eb_51b_3a_c97b_"},
+		"docker-workflow": {"version": "572.v950f58993843"}
+	}}`), data))
+	specs := []pluginSpec{
+		{Name: "git", Version: "latest"},
+		{Name: "git", Version: "5.0.0"},
+		{Name: "credentials", Version: "latest"},
+		{Name: "docker-workflow", Version: "latest"},
+		{Name: "no-such-plugin", Version: "latest"},
+		{Name: "matrix-auth", Version: "3.2"},
+		{Name: "credentials", Version: "1319.v7eb_51b_3a_c97b_"},
+		{Name: "docker-workflow", Version: "1.0"},
+	}
+
+	toInstall, skipped := resolvePluginSpecs(specs, plugins, data)
+	assert.Equal(t, []pluginSpec{
+		{Name: "credentials", Version: "latest"},
+		{Name: "docker-workflow", Version: "latest"},
+		{Name: "matrix-auth", Version: "3.2"},
+		{Name: "credentials", Version: "1319.v7eb_51b_3a_c97b_"},
+	}, toInstall)
+	assert.Equal(t, []string{
+		"`git` - the latest version 5.2.1 is already installed",
+		"`git` - 5.2.1 is already installed",
+		"`no-such-plugin` - not found in the update center",
+		"`docker-workflow@1.0` - only the latest version 572.v950f58993843 can be installed",
+	}, skipped)
+	assert.Equal(t, "2 plugin(s) skipped:\n* `git` - the latest version 5.2.1 is already installed\n"+
+		"* `no-such-plugin` - not found in the update center", formatSkippedPlugins([]string{skipped[0], skipped[2]}))
+
+	// Without update center data, the updates known to the plugin manager are installed.
+	toInstall, skipped = resolvePluginSpecs([]pluginSpec{{Name: "git", Version: "latest"}, {Name: "matrix-auth", Version: "latest"}, {Name: "no-such-plugin", Version: "latest"}}, plugins, nil)
+	assert.Equal(t, []pluginSpec{{Name: "matrix-auth", Version: "latest"}, {Name: "no-such-plugin", Version: "latest"}}, toInstall)
+	assert.Equal(t, []string{"`git` - the latest version 5.2.1 is already installed"}, skipped)
+}
+
+func TestFormatInstallationReport(t *testing.T) {
+	var center updateCenter
+	require.Nil(t, json.Unmarshal([]byte(testUpdateCenterJobs), &center))
+
+	msg := formatInstallationReport(center.Jobs[3:], []pluginSpec{{Name: "git"}, {Name: "docker-workflow"}}, true)
+	assert.Equal(t, ":x: The installation of 1 of 2 plugins has failed.\n"+
+		"* `git` 5.2.1 - SuccessButRequiresRestart\n"+
+		"* `docker-workflow` 572.v950f58993843 - Failure: Failed to download\n\n"+
+		":arrows_counterclockwise: Jenkins has to be restarted to complete the installation. Use `/jenkins restart --safe` to restart it.", msg)
+
+	msg = formatInstallationReport(center.Jobs[1:3], []pluginSpec{{Name: "git"}, {Name: "matrix-auth"}}, false)
+	assert.Contains(t, msg, ":white_check_mark: The installation of the plugins has finished.\n")
+	assert.Contains(t, msg, "* `git-client` 4.6.0 (dependency) - Success\n")
+	assert.Contains(t, msg, "* `matrix-auth` - Not installed: Jenkins found nothing to install\n")
+	assert.Contains(t, msg, "\nNo restart is required.")
+}
@@ -0,0 +1,461 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/pluginapi/cluster"
+	"github.com/pkg/errors"
+	"github.com/waseem18/gojenkins"
+)
+
+const (
+	pluginsTreeQuery = "plugins[shortName,longName,version,enabled,active,hasUpdate,url,dependencies[shortName,version,optional]]"
+
+	pluginDigestJobKey   = "plugin_digest"
+	pluginDigestInterval = 7 * 24 * time.Hour
+
+	// maxPluginsInPost is the maximum number of plugins listed in a table of a post.
+	maxPluginsInPost = 50
+	// updateCenterTimeout is the time after which the download of the update center data fails.
+	updateCenterTimeout = time.Minute
+	// maxUpdateCenterSize is the maximum size of the update center data which is read.
+	maxUpdateCenterSize = 64 * 1024 * 1024
+	// updateCenterCacheTTL is the time the data of the update site is cached for.
+	updateCenterCacheTTL = 6 * time.Hour
+	// updateCenterRetryInterval is the time after which the download of the update site data is retried
+	// once it has failed, so an unreachable update site doesn't slow down every plugins command.
+	updateCenterRetryInterval = 15 * time.Minute
+)
+
+// installedPlugin is a plugin installed on the Jenkins server, as returned by the plugin manager API.
+type installedPlugin struct {
+	ShortName    string `json:"shortName"`
+	LongName     string `json:"longName"`
+	Version      string `json:"version"`
+	Enabled      bool   `json:"enabled"`
+	Active       bool   `json:"active"`
+	HasUpdate    bool   `json:"hasUpdate"`
+	URL          string `json:"url"`
+	Dependencies []struct {
+		ShortName string `json:"shortName"`
+		Version   string `json:"version"`
+		Optional  bool   `json:"optional"`
+	} `json:"dependencies"`
+}
+
+func (ip *installedPlugin) status() string {
+	switch {
+	case !ip.Enabled:
+		return "Disabled"
+	case !ip.Active:
+		return "Restart required"
+	default:
+		return "Enabled"
+	}
+}
+
+// updateCenterData is the part of the data of the update site used by the plugins command.
+type updateCenterData struct {
+	Plugins map[string]struct {
+		Version string `json:"version"`
+	} `json:"plugins"`
+	Warnings []securityWarning `json:"warnings"`
+}
+
+// securityWarning is a security warning published by the update site. It applies to the versions
+// of the plugin matching one of its patterns.
+type securityWarning struct {
+	Type     string `json:"type"`
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Message  string `json:"message"`
+	URL      string `json:"url"`
+	Versions []struct {
+		Pattern string `json:"pattern"`
+	} `json:"versions"`
+}
+
+// appliesTo reports whether the warning applies to the given version of the given plugin.
+func (w *securityWarning) appliesTo(shortName, version string) bool {
+	if w.Type != "plugin" || w.Name != shortName {
+		return false
+	}
+	for _, v := range w.Versions {
+		// The patterns are Java regular expressions which have to match the whole version.
+		pattern, err := regexp.Compile("^(?:" + v.Pattern + ")$")
+		if err == nil && pattern.MatchString(version) {
+			return true
+		}
+	}
+	return false
+}
+
+// updateCenterCache is the data of an update site downloaded last, or the error of the download.
+type updateCenterCache struct {
+	lock      sync.Mutex
+	siteURL   string
+	data      *updateCenterData
+	err       error
+	expiresAt time.Time
+}
+
+// get returns the cached data of the update site, and downloads it if it isn't cached or has expired.
+func (c *updateCenterCache) get(siteURL string, download func(siteURL string) (*updateCenterData, error)) (*updateCenterData, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.siteURL == siteURL && time.Now().Before(c.expiresAt) {
+		return c.data, c.err
+	}
+	c.siteURL = siteURL
+	c.data, c.err = download(siteURL)
+	if c.err != nil {
+		c.expiresAt = time.Now().Add(updateCenterRetryInterval)
+	} else {
+		c.expiresAt = time.Now().Add(updateCenterCacheTTL)
+	}
+	return c.data, c.err
+}
+
+// pluginFilter holds the filters of the plugins command.
+type pluginFilter struct {
+	Updates  bool
+	Disabled bool
+	Text     string
+}
+
+func (f *pluginFilter) isEmpty() bool {
+	return !f.Updates && !f.Disabled && f.Text == ""
+}
+
+// pluginReport is the state of the installed plugins with their updates, security warnings and dependency problems.
+type pluginReport struct {
+	Plugins []installedPlugin
+	// Updates maps the plugins with an update to the available version, which is empty if unknown.
+	Updates            map[string]string
+	Warnings           map[string][]securityWarning
+	DependencyProblems map[string][]string
+}
+
+func (r *pluginReport) matches(ip *installedPlugin, filter *pluginFilter) bool {
+	if _, hasUpdate := r.Updates[ip.ShortName]; filter.Updates && !hasUpdate {
+		return false
+	}
+	if filter.Disabled && ip.Enabled {
+		return false
+	}
+	text := strings.ToLower(filter.Text)
+	return text == "" || strings.Contains(strings.ToLower(ip.ShortName), text) || strings.Contains(strings.ToLower(ip.LongName), text)
+}
+
+func (r *pluginReport) disabledCount() int {
+	count := 0
+	for _, ip := range r.Plugins {
+		if !ip.Enabled {
+			count++
+		}
+	}
+	return count
+}
+
+// compareVersions compares two plugin versions part by part, numerically where both parts are numbers.
+// Returns a negative number if a is older than b, a positive number if a is newer and 0 if they are equal.
+func compareVersions(a, b string) int {
+	split := func(v string) []string {
+		return strings.FieldsFunc(v, func(r rune) bool { return r == '.' || r == '-' || r == '_' })
+	}
+	partsA, partsB := split(a), split(b)
+	for i := 0; i < len(partsA) && i < len(partsB); i++ {
+		numA, errA := strconv.Atoi(partsA[i])
+		numB, errB := strconv.Atoi(partsB[i])
+		switch {
+		case errA == nil && errB == nil && numA != numB:
+			return numA - numB
+		case (errA != nil || errB != nil) && partsA[i] != partsB[i]:
+			return strings.Compare(partsA[i], partsB[i])
+		}
+	}
+	return len(partsA) - len(partsB)
+}
+
+// buildPluginReport finds the updates, security warnings and dependency problems of the installed plugins.
+// Without update center data, the updates are taken from the plugin manager and the available versions are unknown.
+func buildPluginReport(plugins []installedPlugin, data *updateCenterData) *pluginReport {
+	sort.Slice(plugins, func(i, j int) bool { return plugins[i].ShortName < plugins[j].ShortName })
+	report := &pluginReport{
+		Plugins:            plugins,
+		Updates:            make(map[string]string),
+		Warnings:           make(map[string][]securityWarning),
+		DependencyProblems: make(map[string][]string),
+	}
+
+	installed := make(map[string]installedPlugin)
+	for _, ip := range plugins {
+		installed[ip.ShortName] = ip
+	}
+
+	for _, ip := range plugins {
+		if data != nil {
+			if available, ok := data.Plugins[ip.ShortName]; ok && compareVersions(available.Version, ip.Version) > 0 {
+				report.Updates[ip.ShortName] = available.Version
+			}
+			for _, w := range data.Warnings {
+				if w.appliesTo(ip.ShortName, ip.Version) {
+					report.Warnings[ip.ShortName] = append(report.Warnings[ip.ShortName], w)
+				}
+			}
+		} else if ip.HasUpdate {
+			report.Updates[ip.ShortName] = ""
+		}
+
+		if !ip.Enabled {
+			continue
+		}
+		for _, dep := range ip.Dependencies {
+			if dep.Optional {
+				continue
+			}
+			depPlugin, ok := installed[dep.ShortName]
+			switch {
+			case !ok:
+				report.DependencyProblems[ip.ShortName] = append(report.DependencyProblems[ip.ShortName], fmt.Sprintf("`%s` %s isn't installed", dep.ShortName, dep.Version))
+			case !depPlugin.Enabled:
+				report.DependencyProblems[ip.ShortName] = append(report.DependencyProblems[ip.ShortName], fmt.Sprintf("`%s` is disabled", dep.ShortName))
+			case compareVersions(depPlugin.Version, dep.Version) < 0:
+				report.DependencyProblems[ip.ShortName] = append(report.DependencyProblems[ip.ShortName], fmt.Sprintf("`%s` %s is required, but %s is installed", dep.ShortName, dep.Version, depPlugin.Version))
+			}
+		}
+	}
+	return report
+}
+
+// writePluginWarnings writes the security warnings and the dependency problems of the report.
+func writePluginWarnings(sb *strings.Builder, report *pluginReport) {
+	if len(report.Warnings) > 0 {
+		sb.WriteString("\n**:rotating_light: Security warnings**\n\n")
+		for _, ip := range report.Plugins {
+			for _, w := range report.Warnings[ip.ShortName] {
+				fmt.Fprintf(sb, "* `%s` %s - [%s](%s): %s\n", ip.ShortName, ip.Version, w.ID, w.URL, w.Message)
+			}
+		}
+	}
+	if len(report.DependencyProblems) > 0 {
+		sb.WriteString("\n**:warning: Dependency problems**\n\n")
+		for _, ip := range report.Plugins {
+			if problems := report.DependencyProblems[ip.ShortName]; len(problems) > 0 {
+				fmt.Fprintf(sb, "* `%s`: %s\n", ip.ShortName, strings.Join(problems, ", "))
+			}
+		}
+	}
+}
+
+// writePluginTable writes a table of the given plugins, truncated to maxPluginsInPost rows.
+func writePluginTable(sb *strings.Builder, report *pluginReport, plugins []installedPlugin) {
+	sb.WriteString("| Plugin | Version | Status | Update |\n")
+	sb.WriteString("|:-------|:--------|:-------|:-------|\n")
+	for i, ip := range plugins {
+		if i == maxPluginsInPost {
+			fmt.Fprintf(sb, "\n...and %d more.\n", len(plugins)-maxPluginsInPost)
+			break
+		}
+		update := ""
+		if available, ok := report.Updates[ip.ShortName]; ok {
+			update = available
+			if update == "" {
+				update = "Available"
+			}
+		}
+		if len(report.Warnings[ip.ShortName]) > 0 {
+			update = strings.TrimSpace(":rotating_light: " + update)
+		}
+		fmt.Fprintf(sb, "| %s (`%s`) | %s | %s | %s |\n", escapeTableCell(ip.LongName), ip.ShortName, escapeTableCell(ip.Version), ip.status(), update)
+	}
+}
+
+// formatPluginReport renders the report of the installed plugins. Without filters, a summary is rendered with the
+// security warnings, the dependency problems and the available updates. With filters, the matching plugins are listed.
+func formatPluginReport(report *pluginReport, filter *pluginFilter) string {
+	var sb strings.Builder
+	if filter.isEmpty() {
+		fmt.Fprintf(&sb, "%d plugins installed: %d with updates, %d with security warnings, %d with dependency problems, %d disabled.\n",
+			len(report.Plugins), len(report.Updates), len(report.Warnings), len(report.DependencyProblems), report.disabledCount())
+		writePluginWarnings(&sb, report)
+		if len(report.Updates) > 0 {
+			sb.WriteString("\n**Updates**\n\n")
+			writePluginTable(&sb, report, filterPlugins(report, &pluginFilter{Updates: true}))
+		}
+		sb.WriteString("\nUse `/jenkins plugins --filter <text>` to search the installed plugins.")
+		return sb.String()
+	}
+
+	plugins := filterPlugins(report, filter)
+	if len(plugins) == 0 {
+		return "No plugins match the given filters."
+	}
+	fmt.Fprintf(&sb, "%d plugin(s)\n\n", len(plugins))
+	writePluginTable(&sb, report, plugins)
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// formatPluginDigest renders the weekly digest of outdated and vulnerable plugins.
+// Returns false if all plugins are up to date and without problems.
+func formatPluginDigest(report *pluginReport) (string, bool) {
+	if len(report.Updates) == 0 && len(report.Warnings) == 0 && len(report.DependencyProblems) == 0 {
+		return "", false
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "#### Weekly Jenkins plugin report\nOutdated plugins: %d of %d. Plugins with security warnings: %d.\n",
+		len(report.Updates), len(report.Plugins), len(report.Warnings))
+	writePluginWarnings(&sb, report)
+	if len(report.Updates) > 0 {
+		sb.WriteString("\n**Updates**\n\n")
+		writePluginTable(&sb, report, filterPlugins(report, &pluginFilter{Updates: true}))
+	}
+	return strings.TrimSuffix(sb.String(), "\n"), true
+}
+
+// filterPlugins returns the plugins of the report matching the filter.
+func filterPlugins(report *pluginReport, filter *pluginFilter) []installedPlugin {
+	plugins := []installedPlugin{}
+	for i := range report.Plugins {
+		if report.matches(&report.Plugins[i], filter) {
+			plugins = append(plugins, report.Plugins[i])
+		}
+	}
+	return plugins
+}
+
+// parseUpdateCenterData decodes the data of an update site, which is usually wrapped in a JSONP callback.
+func parseUpdateCenterData(body []byte) (*updateCenterData, error) {
+	start := strings.IndexByte(string(body), '{')
+	end := strings.LastIndexByte(string(body), '}')
+	if start < 0 || end < start {
+		return nil, errors.New("no JSON found in the update center data")
+	}
+	data := &updateCenterData{}
+	if err := json.Unmarshal(body[start:end+1], data); err != nil {
+		return nil, errors.Wrap(err, "Error decoding the update center data")
+	}
+	return data, nil
+}
+
+// getInstalledPlugins fetches the plugins installed on the Jenkins server.
+func getInstalledPlugins(jenkins *gojenkins.Jenkins) ([]installedPlugin, error) {
+	var manager struct {
+		Plugins []installedPlugin `json:"plugins"`
+	}
+	resp, err := jenkins.Requester.GetJSON("/pluginManager", &manager, map[string]string{"tree": pluginsTreeQuery})
+	if err != nil {
+		return nil, errors.Wrap(err, "Error fetching plugins")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("Error fetching plugins: %s", resp.Status)
+	}
+	return manager.Plugins, nil
+}
+
+// getUpdateCenterData returns the data of the default update site configured in Jenkins.
+// The data is cached for updateCenterCacheTTL, as it is large and changes rarely.
+func (p *Plugin) getUpdateCenterData(jenkins *gojenkins.Jenkins) (*updateCenterData, error) {
+	var updateCenter struct {
+		Sites []struct {
+			ID  string `json:"id"`
+			URL string `json:"url"`
+		} `json:"sites"`
+	}
+	if err := getJenkinsJSON(jenkins, "/updateCenter/api/json?tree=sites[id,url]", &updateCenter); err != nil {
+		return nil, errors.Wrap(err, "Error fetching update sites")
+	}
+	siteURL := ""
+	for _, site := range updateCenter.Sites {
+		if site.ID == "default" || siteURL == "" {
+			siteURL = site.URL
+		}
+	}
+	if siteURL == "" {
+		return nil, errors.New("no update site configured")
+	}
+	return p.updateCenterCache.get(siteURL, downloadUpdateCenterData)
+}
+
+// downloadUpdateCenterData downloads the data of the update site at the given URL.
+func downloadUpdateCenterData(siteURL string) (*updateCenterData, error) {
+	client := &http.Client{Timeout: updateCenterTimeout}
+	resp, err := client.Get(siteURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error downloading the update center data")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("Error downloading the update center data: %s", resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxUpdateCenterSize))
+	if err != nil {
+		return nil, errors.Wrap(err, "Error downloading the update center data")
+	}
+	return parseUpdateCenterData(body)
+}
+
+// getPluginReport fetches the installed plugins and checks them against the data of the update site.
+// If the update site can't be reached, the report is built from the plugin manager alone.
+func (p *Plugin) getPluginReport(jenkins *gojenkins.Jenkins) (*pluginReport, error) {
+	plugins, err := getInstalledPlugins(jenkins)
+	if err != nil {
+		return nil, err
+	}
+	data, err := p.getUpdateCenterData(jenkins)
+	if err != nil {
+		p.API.LogWarn("Error fetching the update center data", "err", err.Error())
+	}
+	return buildPluginReport(plugins, data), nil
+}
+
+// postPlugins creates a post with the report of the installed plugins matching the filter.
+func (p *Plugin) postPlugins(userID, channelID string, filter *pluginFilter) error {
+	jenkins, err := p.getJenkinsClient(userID)
+	if err != nil {
+		return errors.Wrap(err, "Error creating Jenkins client")
+	}
+	report, err := p.getPluginReport(jenkins)
+	if err != nil {
+		return err
+	}
+	p.createPost(userID, channelID, formatPluginReport(report, filter))
+	return nil
+}
+
+// schedulePluginDigest starts the background job posting the weekly plugin digest to the admin channel.
+func (p *Plugin) schedulePluginDigest() error {
+	job, err := cluster.Schedule(p.API, pluginDigestJobKey, cluster.MakeWaitForInterval(pluginDigestInterval), p.postPluginDigest)
+	if err != nil {
+		return errors.Wrap(err, "failed to schedule the plugin digest")
+	}
+	p.pluginDigestJob = job
+	return nil
+}
+
+// postPluginDigest posts the outdated and vulnerable plugins to the admin channel.
+func (p *Plugin) postPluginDigest() {
+	config := p.getConfiguration()
+	if config.JenkinsURL == "" || config.AdminChannelID == "" {
+		return
+	}
+	report, err := p.getPluginReport(p.getHealthCheckClient())
+	if err != nil {
+		p.API.LogWarn("Error creating the plugin digest", "err", err.Error())
+		return
+	}
+	if msg, ok := formatPluginDigest(report); ok {
+		p.postAdminMessage(msg)
+	}
+}
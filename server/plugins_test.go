@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testInstalledPlugins = `[
+	{"shortName": "git", "longName": "Git plugin", "version": "5.2.0", "enabled": true, "active": true, "hasUpdate": true,
+	 "dependencies": [{"shortName": "git-client", "version": "4.6.0"}, {"shortName": "credentials", "version": "1311.vcf0a_900b_37c2"},
+	                  {"shortName": "token-macro", "version": "1.0", "optional": true}]},
+	{"shortName": "git-client", "longName": "Git client plugin", "version": "4.5.0", "enabled": true, "active": true},
+	{"shortName": "credentials", "longName": "Credentials Plugin", "version": "1311.vcf0a_900b_37c2", "enabled": false, "active": false},
+	{"shortName": "script-security", "longName": "Script Security Plugin", "version": "1.75", "enabled": true, "active": true}
+]`
+
+const testUpdateCenter = `updateCenter.post(
+{"plugins": {"git": {"version": "5.2.1"}, "git-client": {"version": "4.5.0"}, "script-security": {"version": "1.78"}},
+ "warnings": [
+	{"type": "plugin", "id": "SECURITY-2824", "name": "script-security", "message": "Sandbox bypass vulnerability",
+	 "url": "https://www.jenkins.io/security/advisory/2022-10-19/", "versions": [{"lastVersion": "1.75", "pattern": "1[.]([0-6]?[0-9]|7[0-5])(|[.-].*)"}]},
+	{"type": "plugin", "id": "SECURITY-1", "name": "git", "message": "Old issue", "url": "https://example.com", "versions": [{"pattern": "[0-3][.].*"}]},
+	{"type": "core", "id": "SECURITY-2", "name": "core", "message": "Core issue", "url": "https://example.com", "versions": [{"pattern": ".*"}]}
+ ]}
+);`
+
+func getTestPluginReport(t *testing.T) *pluginReport {
+	var plugins []installedPlugin
+	require.Nil(t, json.Unmarshal([]byte(testInstalledPlugins), &plugins))
+	data, err := parseUpdateCenterData([]byte(testUpdateCenter))
+	require.Nil(t, err)
+	return buildPluginReport(plugins, data)
+}
+
+func TestCompareVersions(t *testing.T) {
+	for _, test := range []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.10", "1.9", 1},
+		{"1.9", "1.10", -1},
+		{"2.0", "2.0", 0},
+		{"2.0.1", "2.0", 1},
+		{"4.13.3-1", "4.13.3", 1},
+		{"1311.vcf0a_900b_37c2", "1311.vcf0a_900b_37c2", 0},
+		{"1318.v1", "1311.vcf0a_900b_37c2", 1},
+	} {
+		result := compareVersions(test.a, test.b)
+		switch {
+		case test.expected > 0:
+			assert.Positive(t, result, "%s > %s", test.a, test.b)
+		case test.expected < 0:
+			assert.Negative(t, result, "%s < %s", test.a, test.b)
+		default:
+			assert.Zero(t, result, "%s == %s", test.a, test.b)
+		}
+	}
+}
+
+func TestBuildPluginReport(t *testing.T) {
+	report := getTestPluginReport(t)
+
+	assert.Equal(t, "credentials", report.Plugins[0].ShortName)
+	assert.Equal(t, map[string]string{"git": "5.2.1", "script-security": "1.78"}, report.Updates)
+	require.Len(t, report.Warnings["script-security"], 1)
+	assert.Equal(t, "SECURITY-2824", report.Warnings["script-security"][0].ID)
+	assert.Len(t, report.Warnings, 1)
+	assert.Equal(t, map[string][]string{
+		"git": {"`git-client` 4.6.0 is required, but 4.5.0 is installed", "`credentials` is disabled"},
+	}, report.DependencyProblems)
+
+	var plugins []installedPlugin
+	require.Nil(t, json.Unmarshal([]byte(testInstalledPlugins), &plugins))
+	report = buildPluginReport(plugins, nil)
+	assert.Equal(t, map[string]string{"git": ""}, report.Updates)
+	assert.Empty(t, report.Warnings)
+}
+
+func TestFormatPluginReport(t *testing.T) {
+	report := getTestPluginReport(t)
+
+	msg := formatPluginReport(report, &pluginFilter{})
+	assert.Contains(t, msg, "4 plugins installed: 2 with updates, 1 with security warnings, 1 with dependency problems, 1 disabled.\n")
+	assert.Contains(t, msg, "* `script-security` 1.75 - [SECURITY-2824](https://www.jenkins.io/security/advisory/2022-10-19/): Sandbox bypass vulnerability\n")
+	assert.Contains(t, msg, "* `git`: `git-client` 4.6.0 is required, but 4.5.0 is installed, `credentials` is disabled\n")
+	assert.Contains(t, msg, "| Git plugin (`git`) | 5.2.0 | Enabled | 5.2.1 |\n")
+	assert.Contains(t, msg, "| Script Security Plugin (`script-security`) | 1.75 | Enabled | :rotating_light: 1.78 |\n")
+	assert.NotContains(t, msg, "Git client plugin")
+
+	msg = formatPluginReport(report, &pluginFilter{Disabled: true})
+	assert.Equal(t, "1 plugin(s)\n\n| Plugin | Version | Status | Update |\n|:-------|:--------|:-------|:-------|\n| Credentials Plugin (`credentials`) | 1311.vcf0a_900b_37c2 | Disabled |  |", msg)
+
+	msg = formatPluginReport(report, &pluginFilter{Text: "GIT"})
+	assert.Contains(t, msg, "2 plugin(s)\n")
+
+	assert.Equal(t, "No plugins match the given filters.", formatPluginReport(report, &pluginFilter{Updates: true, Disabled: true}))
+}
+
+func TestFormatPluginDigest(t *testing.T) {
+	msg, ok := formatPluginDigest(getTestPluginReport(t))
+	assert.True(t, ok)
+	assert.Contains(t, msg, "Outdated plugins: 2 of 4. Plugins with security warnings: 1.\n")
+
+	_, ok = formatPluginDigest(buildPluginReport([]installedPlugin{{ShortName: "git", Version: "1.0", Enabled: true}}, &updateCenterData{}))
+	assert.False(t, ok)
+}
+
+func TestParseUpdateCenterData(t *testing.T) {
+	_, err := parseUpdateCenterData([]byte("<html>Not found</html>"))
+	assert.NotNil(t, err)
+
+	data, err := parseUpdateCenterData([]byte(`{"plugins": {"git": {"version": "1.0"}}}`))
+	require.Nil(t, err)
+	assert.Equal(t, "1.0", data.Plugins["git"].Version)
+}
+
+func TestUpdateCenterCache(t *testing.T) {
+	downloads := 0
+	download := func(siteURL string) (*updateCenterData, error) {
+		downloads++
+		if siteURL == "https://offline.example.com/update-center.json" {
+			return nil, errors.New("unreachable")
+		}
+		return &updateCenterData{}, nil
+	}
+
+	cache := &updateCenterCache{}
+	data, err := cache.get("https://updates.example.com/update-center.json", download)
+	require.NoError(t, err)
+	assert.NotNil(t, data)
+	_, err = cache.get("https://updates.example.com/update-center.json", download)
+	require.NoError(t, err)
+	assert.Equal(t, 1, downloads)
+
+	// Failed downloads are cached as well, and another site is downloaded again.
+	_, err = cache.get("https://offline.example.com/update-center.json", download)
+	assert.Error(t, err)
+	_, err = cache.get("https://offline.example.com/update-center.json", download)
+	assert.Error(t, err)
+	assert.Equal(t, 2, downloads)
+
+	// Expired data is downloaded again.
+	cache.expiresAt = time.Now().Add(-time.Second)
+	_, err = cache.get("https://offline.example.com/update-center.json", download)
+	assert.Error(t, err)
+	assert.Equal(t, 3, downloads)
+}
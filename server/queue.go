@@ -0,0 +1,250 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+	"github.com/waseem18/gojenkins"
+)
+
+// queueTreeQuery selects the fields of the queue items shown by the queue command.
+const queueTreeQuery = "items[id,inQueueSince,why,blocked,stuck,task[name,url],actions[parameters[_class,name,value]]]"
+
+// queueItem is an item of the Jenkins build queue.
+type queueItem struct {
+	ID           int64  `json:"id"`
+	InQueueSince int64  `json:"inQueueSince"`
+	Why          string `json:"why"`
+	Blocked      bool   `json:"blocked"`
+	Stuck        bool   `json:"stuck"`
+	Task         struct {
+		Name string `json:"name"`
+		URL  string `json:"url"`
+	} `json:"task"`
+	Actions []struct {
+		Parameters []buildParameter `json:"parameters"`
+	} `json:"actions"`
+}
+
+// jobSegments returns the names of the folders and the job of the item as they appear in its URL.
+func (q *queueItem) jobSegments() []string {
+	u, err := url.Parse(q.Task.URL)
+	if err != nil {
+		return []string{q.Task.Name}
+	}
+	segments := []string{}
+	parts := strings.Split(strings.Trim(u.EscapedPath(), "/"), "/")
+	for i := 0; i < len(parts)-1; i++ {
+		if parts[i] == "job" {
+			segments = append(segments, parts[i+1])
+			i++
+		}
+	}
+	if len(segments) == 0 {
+		return []string{q.Task.Name}
+	}
+	return segments
+}
+
+// jobPath returns the path of the job of the item in the format of jenkinsJobPath.
+func (q *queueItem) jobPath() string {
+	return strings.Join(q.jobSegments(), "/job/")
+}
+
+// jobName returns the full name of the job of the item, with folders separated by slashes.
+func (q *queueItem) jobName() string {
+	names := []string{}
+	for _, segment := range q.jobSegments() {
+		name, err := url.PathUnescape(segment)
+		if err != nil {
+			name = segment
+		}
+		names = append(names, name)
+	}
+	return strings.Join(names, "/")
+}
+
+// matchesJob reports whether the item is a build of the job with the given name, as given in the slash commands.
+func (q *queueItem) matchesJob(jobName string) bool {
+	return q.jobName() == jobName || q.jobPath() == jenkinsJobPath(jobName)
+}
+
+func (q *queueItem) parameters() string {
+	params := []buildParameter{}
+	for _, a := range q.Actions {
+		params = append(params, a.Parameters...)
+	}
+	return formatBuildParameters(params)
+}
+
+// parseQueueFilter validates the job glob passed to the queue command.
+func parseQueueFilter(flags map[string]string) (string, error) {
+	glob := flags["job"]
+	if _, err := path.Match(glob, ""); err != nil {
+		return "", fmt.Errorf("invalid job pattern %q", glob)
+	}
+	return glob, nil
+}
+
+// filterQueue returns the items whose job name matches the glob. An empty glob matches all items.
+func filterQueue(items []queueItem, glob string) []queueItem {
+	if glob == "" {
+		return items
+	}
+	filtered := []queueItem{}
+	for _, item := range items {
+		if matchJobGlob(glob, item.jobName()) {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered
+}
+
+// formatQueue renders the queue items as a markdown table.
+func formatQueue(items []queueItem, now time.Time) string {
+	if len(items) == 0 {
+		return "The build queue is empty."
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d item(s) in the build queue\n\n", len(items))
+	sb.WriteString("| ID | Job | Waiting | Why | Parameters |\n")
+	sb.WriteString("|:---|:----|:--------|:----|:-----------|\n")
+	for _, item := range items {
+		why := item.Why
+		if item.Stuck {
+			why = ":warning: " + why
+		}
+		fmt.Fprintf(&sb, "| %d | %s | %s | %s | %s |\n",
+			item.ID,
+			escapeTableCell(item.jobName()),
+			formatDuration(now.Sub(time.UnixMilli(item.InQueueSince))),
+			escapeTableCell(why),
+			escapeTableCell(item.parameters()),
+		)
+	}
+	sb.WriteString("\nUse `/jenkins queue cancel <id|jobname>` to cancel an item.")
+	return sb.String()
+}
+
+// getQueueItems fetches the items of the build queue.
+func getQueueItems(jenkins *gojenkins.Jenkins) ([]queueItem, error) {
+	var queue struct {
+		Items []queueItem `json:"items"`
+	}
+	resp, err := jenkins.Requester.GetJSON(jenkins.GetQueueUrl(), &queue, map[string]string{"tree": queueTreeQuery})
+	if err != nil {
+		return nil, errors.Wrap(err, "Error fetching the build queue")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("Error fetching the build queue: %s", resp.Status)
+	}
+	return queue.Items, nil
+}
+
+// cancelQueueItem removes the item with the given ID from the build queue.
+func cancelQueueItem(jenkins *gojenkins.Jenkins, id int64) error {
+	query := map[string]string{"id": strconv.FormatInt(id, 10)}
+	resp, err := jenkins.Requester.Post(jenkins.GetQueueUrl()+"/cancelItem", nil, nil, query)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return errors.Errorf("unexpected response from Jenkins: %s", resp.Status)
+	}
+	return nil
+}
+
+// postQueue creates a post with the items of the build queue whose job matches the glob.
+func (p *Plugin) postQueue(userID, channelID, glob string) error {
+	jenkins, err := p.getJenkinsClient(userID)
+	if err != nil {
+		return errors.Wrap(err, "Error creating Jenkins client")
+	}
+	items, err := getQueueItems(jenkins)
+	if err != nil {
+		return err
+	}
+	p.createPost(userID, channelID, formatQueue(filterQueue(items, glob), time.Now()))
+	return nil
+}
+
+// cancelQueueItems cancels the queue item with the given ID, or all queue items of the job with the given name.
+// Returns a message describing the outcome.
+func (p *Plugin) cancelQueueItems(userID, target string) (string, error) {
+	jenkins, err := p.getJenkinsClient(userID)
+	if err != nil {
+		return "", errors.Wrap(err, "Error creating Jenkins client")
+	}
+	items, err := getQueueItems(jenkins)
+	if err != nil {
+		return "", err
+	}
+
+	id, parseErr := strconv.ParseInt(target, 10, 64)
+	cancelled := []string{}
+	for _, item := range items {
+		if (parseErr == nil && item.ID == id) || (parseErr != nil && item.matchesJob(target)) {
+			if err := cancelQueueItem(jenkins, item.ID); err != nil {
+				return "", errors.Wrapf(err, "Error cancelling queue item %d", item.ID)
+			}
+			cancelled = append(cancelled, fmt.Sprintf("#%d (%s)", item.ID, item.jobName()))
+		}
+	}
+
+	if len(cancelled) == 0 {
+		return fmt.Sprintf("No queue item found for '%s'.", target), nil
+	}
+	return fmt.Sprintf("Cancelled queue item(s) %s.", strings.Join(cancelled, ", ")), nil
+}
+
+// postStillInQueue tells the user that the job couldn't be triggered because a build of it is still in queue,
+// with the reason the queued build is waiting and a button to cancel it.
+func (p *Plugin) postStillInQueue(jenkins *gojenkins.Jenkins, userID, channelID, jobName string) {
+	msg := "A build of this job is still in queue.\n Please trigger the job after the job's build queue is free."
+	items, err := getQueueItems(jenkins)
+	if err != nil {
+		p.API.LogWarn("Error fetching the build queue", "err", err.Error())
+		p.createEphemeralPost(userID, channelID, msg)
+		return
+	}
+
+	var item *queueItem
+	for i := range items {
+		if items[i].matchesJob(jobName) {
+			item = &items[i]
+			break
+		}
+	}
+	if item == nil {
+		p.createEphemeralPost(userID, channelID, msg)
+		return
+	}
+
+	attachment := generateSlackAttachment(fmt.Sprintf("%s\nQueue item #%d is waiting: %s", msg, item.ID, item.Why))
+	attachment.Actions = []*model.PostAction{{
+		Name:  "Cancel",
+		Type:  model.PostActionTypeButton,
+		Style: "danger",
+		Integration: &model.PostActionIntegration{
+			URL:     fmt.Sprintf("%s/plugins/jenkins/queue/cancel", *p.API.GetConfig().ServiceSettings.SiteURL),
+			Context: map[string]interface{}{"queue_id": strconv.FormatInt(item.ID, 10)},
+		},
+	}}
+	post := &model.Post{
+		UserId:    p.botUserID,
+		ChannelId: channelID,
+		Type:      model.PostTypeDefault,
+		Props: map[string]interface{}{
+			"attachments": []*model.SlackAttachment{attachment},
+		},
+	}
+	p.API.SendEphemeralPost(userID, post)
+}
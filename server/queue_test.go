@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testQueue = `[
+	{"id": 101, "inQueueSince": 1700000000000, "why": "Waiting for next available executor", "stuck": true,
+	 "task": {"name": "job1", "url": "http://jenkins/job/job1/"},
+	 "actions": [{"parameters": [{"name": "BRANCH", "value": "main"}, {"_class": "hudson.model.PasswordParameterValue", "name": "TOKEN"}]}]},
+	{"id": 102, "inQueueSince": 1700000090000, "why": "Build #4 is already in progress",
+	 "task": {"name": "feature/x", "url": "http://jenkins/ci/job/team/job/repo/job/feature%252Fx/"}}
+]`
+
+func getTestQueue(t *testing.T) []queueItem {
+	var items []queueItem
+	require.Nil(t, json.Unmarshal([]byte(testQueue), &items))
+	return items
+}
+
+func TestQueueItemJobName(t *testing.T) {
+	items := getTestQueue(t)
+	assert.Equal(t, "job1", items[0].jobName())
+	assert.Equal(t, "team/repo/feature%2Fx", items[1].jobName())
+	assert.Equal(t, "team/job/repo/job/feature%252Fx", items[1].jobPath())
+
+	assert.True(t, items[0].matchesJob("job1"))
+	assert.True(t, items[1].matchesJob("team/repo@feature/x"))
+	assert.False(t, items[1].matchesJob("team/repo"))
+}
+
+func TestFilterQueue(t *testing.T) {
+	items := getTestQueue(t)
+	assert.Len(t, filterQueue(items, ""), 2)
+	assert.Len(t, filterQueue(items, "team/*/*"), 1)
+	assert.Len(t, filterQueue(items, "team/*"), 0)
+	assert.Len(t, filterQueue(items, "job?"), 1)
+
+	_, err := parseQueueFilter(map[string]string{"job": "[a-"})
+	assert.NotNil(t, err)
+}
+
+func TestFormatQueue(t *testing.T) {
+	msg := formatQueue(getTestQueue(t), time.UnixMilli(1700000120000))
+	assert.Contains(t, msg, "2 item(s) in the build queue\n")
+	assert.Contains(t, msg, "| 101 | job1 | 2m 0s | :warning: Waiting for next available executor | BRANCH=main, TOKEN=******** |\n")
+	assert.Contains(t, msg, "| 102 | team/repo/feature%2Fx | 30s | Build #4 is already in progress |  |\n")
+
+	assert.Equal(t, "The build queue is empty.", formatQueue(nil, time.Now()))
+}
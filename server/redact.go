@@ -0,0 +1,177 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/waseem18/gojenkins"
+)
+
+const (
+	redactedText = "********"
+	// minSecretLength is the length below which parameter values are not redacted,
+	// to avoid masking every occurrence of short values like "1" or "yes".
+	minSecretLength = 4
+
+	passwordParameterDefinitionType = "PasswordParameterDefinition"
+	passwordParameterValueClass     = "hudson.model.PasswordParameterValue"
+)
+
+// builtinSecretPatterns detect common secrets printed by build scripts.
+// When a pattern has a capture group, only the first group is redacted.
+var builtinSecretPatterns = []*regexp.Regexp{
+	// AWS access key IDs.
+	regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+	// AWS secret access keys assigned to a variable.
+	regexp.MustCompile(`(?i)aws_?secret_?(?:access_?)?key["']?\s*[=:]\s*["']?([A-Za-z0-9/+=]{40})`),
+	// JSON Web Tokens.
+	regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`),
+	// Private key blocks. A block without an end is redacted up to the end of the text,
+	// as the rest of it may have been cut off.
+	regexp.MustCompile(`(?s)-----BEGIN [A-Z0-9 ]*PRIVATE KEY(?: BLOCK)?-----.*?(?:-----END [A-Z0-9 ]*PRIVATE KEY(?: BLOCK)?-----|\z)`),
+	// password=value and password: value pairs.
+	regexp.MustCompile(`(?i)\b(?:password|passwd|pwd)["']?\s*[=:]\s*("[^"\n]*"|'[^'\n]*'|[^\s&;,"']+)`),
+}
+
+var (
+	privateKeyBeginPattern = regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY(?: BLOCK)?-----`)
+	privateKeyEndPattern   = regexp.MustCompile(`-----END [A-Z0-9 ]*PRIVATE KEY(?: BLOCK)?-----`)
+)
+
+// redactor masks secrets in text before it is posted or uploaded.
+type redactor struct {
+	patterns []*regexp.Regexp
+	// secrets are literal values to redact, longest first.
+	secrets []string
+}
+
+// compileRedactionPatterns compiles the admin configured redaction patterns, one per line.
+// Empty lines are ignored.
+func compileRedactionPatterns(patterns string) ([]*regexp.Regexp, error) {
+	compiled := []*regexp.Regexp{}
+	for _, line := range strings.Split(patterns, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		re, err := regexp.Compile(line)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid redaction pattern %q", line)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// newRedactor creates a redactor for the built-in detectors, the given patterns and the given literal secrets.
+func newRedactor(patterns []*regexp.Regexp, secrets ...string) *redactor {
+	r := &redactor{patterns: append(append([]*regexp.Regexp{}, builtinSecretPatterns...), patterns...)}
+	for _, s := range secrets {
+		if len(s) >= minSecretLength && !containsString(r.secrets, s) {
+			r.secrets = append(r.secrets, s)
+		}
+	}
+	// Replace longer secrets first, so a secret containing another one is fully masked.
+	sort.Slice(r.secrets, func(i, j int) bool { return len(r.secrets[i]) > len(r.secrets[j]) })
+	return r
+}
+
+// redact returns the text with all secrets masked.
+func (r *redactor) redact(text string) string {
+	for _, s := range r.secrets {
+		text = strings.ReplaceAll(text, s, redactedText)
+	}
+	for _, re := range r.patterns {
+		text = redactPattern(re, text)
+	}
+	return text
+}
+
+// redactPrivateKeyContinuation masks the rest of a private key block which began in a previous chunk of text,
+// up to and including its end line. Without an end line, the whole text is masked.
+func redactPrivateKeyContinuation(text string) string {
+	end := privateKeyEndPattern.FindStringIndex(text)
+	if end == nil {
+		return redactedText
+	}
+	return redactedText + text[end[1]:]
+}
+
+// isPrivateKeyOpen reports whether a private key block is still open after text, given whether
+// a block was open before it.
+func isPrivateKeyOpen(text string, openBefore bool) bool {
+	lastBegin, lastEnd := -1, -1
+	if matches := privateKeyBeginPattern.FindAllStringIndex(text, -1); matches != nil {
+		lastBegin = matches[len(matches)-1][0]
+	}
+	if matches := privateKeyEndPattern.FindAllStringIndex(text, -1); matches != nil {
+		lastEnd = matches[len(matches)-1][0]
+	}
+	if lastBegin < 0 && lastEnd < 0 {
+		return openBefore
+	}
+	return lastBegin > lastEnd
+}
+
+// redactPattern masks the matches of re in text. If re has a capture group,
+// only the first group of every match is masked.
+func redactPattern(re *regexp.Regexp, text string) string {
+	if re.NumSubexp() == 0 {
+		return re.ReplaceAllLiteralString(text, redactedText)
+	}
+
+	var sb strings.Builder
+	last := 0
+	for _, match := range re.FindAllStringSubmatchIndex(text, -1) {
+		start, end := match[2], match[3]
+		if start < 0 || start == end {
+			continue
+		}
+		sb.WriteString(text[last:start])
+		sb.WriteString(redactedText)
+		last = end
+	}
+	if last == 0 {
+		return text
+	}
+	sb.WriteString(text[last:])
+	return sb.String()
+}
+
+// getRedactor returns a redactor for the configured patterns and the given literal secrets.
+func (p *Plugin) getRedactor(secrets ...string) *redactor {
+	return newRedactor(p.getConfiguration().redactionPatterns, secrets...)
+}
+
+// getBuildRedactor returns a redactor which, in addition to the configured patterns, masks the values
+// of the password parameters of the build, as far as Jenkins exposes them.
+// Errors are logged and result in a redactor without parameter values, as redaction must not prevent posting.
+func (p *Plugin) getBuildRedactor(userID, jobName string, build *gojenkins.Build) *redactor {
+	secrets := []string{}
+	job, err := p.getJob(userID, jobName)
+	if err != nil {
+		p.API.LogWarn("Error fetching job parameters for redaction", "job_name", jobName, "err", err.Error())
+		return p.getRedactor()
+	}
+
+	passwordParameters := make(map[string]bool)
+	for _, property := range job.Raw.Property {
+		for _, definition := range property.ParameterDefinitions {
+			if definition.Type != passwordParameterDefinitionType {
+				continue
+			}
+			passwordParameters[definition.Name] = true
+			if value, ok := definition.DefaultParameterValue.Value.(string); ok {
+				secrets = append(secrets, value)
+			}
+		}
+	}
+	for _, param := range build.GetParameters() {
+		if passwordParameters[param.Name] {
+			secrets = append(secrets, param.Value)
+		}
+	}
+	return p.getRedactor(secrets...)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// parseJobNamePair parses two names given to the copy, rename and move commands.
+// Each name may be wrapped in double quotes if it has spaces in it.
+func parseJobNamePair(parameters []string) (string, string, bool) {
+	first, rest, ok := parseQuotedArgument(parameters)
+	if !ok || rest == "" {
+		return "", "", false
+	}
+	second, extra, ok := parseQuotedArgument(strings.Fields(rest))
+	if !ok || extra != "" {
+		return "", "", false
+	}
+	return first, second, true
+}
+
+// splitJobName splits a job name given as folder1/folder2/jobname into its folders and its name.
+func splitJobName(jobName string) ([]string, string) {
+	parts := strings.Split(strings.Trim(jobName, "/"), "/")
+	return parts[:len(parts)-1], parts[len(parts)-1]
+}
+
+// folderBase returns the base path of the given nested folders, or an empty string for the root.
+func folderBase(folders []string) string {
+	base := ""
+	for _, folder := range folders {
+		base += "/job/" + url.PathEscape(folder)
+	}
+	return base
+}
+
+// isValidItemName reports whether a name can be used as the name of a job or folder.
+func isValidItemName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, `/\@`)
+}
+
+// copyJob copies a job, creating the destination folders which don't exist yet.
+// Returns a message for the user if the destination can't be used.
+func (p *Plugin) copyJob(userID, channelID, source, destination string) (string, error) {
+	folders, name := splitJobName(destination)
+	if !isValidItemName(name) {
+		return fmt.Sprintf("'%s' isn't a valid job name.", name), nil
+	}
+	job, err := p.getJob(userID, source)
+	if err != nil {
+		return "", err
+	}
+	configXML, err := job.GetConfig()
+	if err != nil {
+		return "", errors.Wrap(err, "Error fetching the job configuration")
+	}
+	if err := ensureFolders(job.Jenkins, folders); err != nil {
+		return "", errors.Wrap(err, "Error creating the destination folders")
+	}
+
+	query := map[string]string{"name": name, "mode": "copy", "from": "/" + job.Raw.FullName}
+	resp, err := job.Jenkins.Requester.Post(folderBase(folders)+"/createItem", nil, nil, query)
+	if err != nil {
+		return "", errors.Wrap(err, "Error copying the job")
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return "", errors.Errorf("Error copying the job: %s", resp.Status)
+	}
+
+	// Jenkins disables copies until their configuration is saved. Saving the configuration of the source
+	// makes the copy buildable, and keeps it disabled only if the source is disabled.
+	if err := postJenkinsXML(job.Jenkins, folderBase(append(folders, name))+"/config.xml", configXML); err != nil {
+		return "", errors.Wrap(err, "Error saving the configuration of the copy")
+	}
+
+	p.createPost(userID, channelID, fmt.Sprintf("Job '%s' has been copied to '%s'.", source, destination))
+	return "", nil
+}
+
+// renameJob renames a job within its folder. Returns a message for the user if the new name can't be used.
+func (p *Plugin) renameJob(userID, channelID, jobName, newName string) (string, error) {
+	if !isValidItemName(newName) {
+		return fmt.Sprintf("'%s' isn't a valid job name. To move a job to another folder, use `/jenkins move`.", newName), nil
+	}
+	job, err := p.getJob(userID, jobName)
+	if err != nil {
+		return "", err
+	}
+	if err := postJenkinsForm(job.Jenkins, job.Base+"/confirmRename", url.Values{"newName": {newName}}); err != nil {
+		return "", errors.Wrap(err, "Error renaming the job")
+	}
+
+	p.createPost(userID, channelID, fmt.Sprintf("Job '%s' has been renamed to '%s'.", jobName, newName))
+	return "", nil
+}
+
+// moveJob moves a job to the given folder, creating it if it doesn't exist yet.
+// The folder / is the root of Jenkins.
+func (p *Plugin) moveJob(userID, channelID, jobName, folder string) error {
+	job, err := p.getJob(userID, jobName)
+	if err != nil {
+		return err
+	}
+	folders := []string{}
+	if strings.Trim(folder, "/") != "" {
+		folders = strings.Split(strings.Trim(folder, "/"), "/")
+	}
+	if err := ensureFolders(job.Jenkins, folders); err != nil {
+		return errors.Wrap(err, "Error creating the destination folder")
+	}
+
+	// The move action is provided by the Folders plugin.
+	form := url.Values{"destination": {"/" + strings.Join(folders, "/")}}
+	if err := postJenkinsForm(job.Jenkins, job.Base+"/move/move", form); err != nil {
+		return errors.Wrap(err, "Error moving the job")
+	}
+
+	_, name := splitJobName(jobName)
+	destination := strings.Join(append(folders, name), "/")
+	p.createPost(userID, channelID, fmt.Sprintf("Job '%s' has been moved to '%s'.", jobName, destination))
+	return nil
+}
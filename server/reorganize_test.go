@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseJobNamePair(t *testing.T) {
+	for params, expected := range map[string][2]string{
+		"job copy":                              {"job", "copy"},
+		"folder/job other/folder/copy":          {"folder/job", "other/folder/copy"},
+		`"my folder/my job" "other folder/job"`: {"my folder/my job", "other folder/job"},
+		`job "new name"`:                        {"job", "new name"},
+		`"job name" /`:                          {"job name", "/"},
+	} {
+		first, second, ok := parseJobNamePair(strings.Fields(params))
+		assert.True(t, ok, params)
+		assert.Equal(t, expected, [2]string{first, second}, params)
+	}
+
+	for _, params := range []string{"", "job", "job copy extra", `"job name copy`, `job "new name" extra`} {
+		_, _, ok := parseJobNamePair(strings.Fields(params))
+		assert.False(t, ok, params)
+	}
+}
+
+func TestSplitJobName(t *testing.T) {
+	folders, name := splitJobName("job")
+	assert.Empty(t, folders)
+	assert.Equal(t, "job", name)
+
+	folders, name = splitJobName("/folder/sub folder/job/")
+	assert.Equal(t, []string{"folder", "sub folder"}, folders)
+	assert.Equal(t, "job", name)
+}
+
+func TestFolderBase(t *testing.T) {
+	assert.Equal(t, "", folderBase(nil))
+	assert.Equal(t, "/job/folder/job/sub%20folder", folderBase([]string{"folder", "sub folder"}))
+}
+
+func TestIsValidItemName(t *testing.T) {
+	assert.True(t, isValidItemName("job name"))
+	for _, name := range []string{"", "folder/job", `a\b`, "project@branch"} {
+		assert.False(t, isValidItemName(name), name)
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	replayMainScriptField = "mainScript"
+	// maxReplayScriptLength is the maximum length of a script edited in the replay dialog.
+	maxReplayScriptLength = 100000
+	// replayStartTimeout is the time to wait for the replayed build to start.
+	replayStartTimeout = 10 * time.Minute
+	// replayCauseClass is the class of the cause of replayed builds.
+	replayCauseClass = "org.jenkinsci.plugins.workflow.cps.replay.ReplayCause"
+	// replayedBuildsTreeQuery selects the causes of the latest builds of a job, to find the replayed build.
+	replayedBuildsTreeQuery = "builds[number,actions[causes[_class,originalNumber,shortDescription]]]{0,20}"
+)
+
+// replayOriginPattern matches the build number in the description of a replay cause, e.g. Replayed #12.
+var replayOriginPattern = regexp.MustCompile(`#(\d+)\b`)
+
+// replayTextareaPattern matches the script text areas of the replay page of a build.
+var replayTextareaPattern = regexp.MustCompile(`(?s)<textarea[^>]*\bname="([^"]+)"[^>]*>(.*?)</textarea>`)
+
+// replayScript is a script of a build which can be edited before replaying it.
+type replayScript struct {
+	// Field is the name of the form field of the script, mainScript for the Jenkinsfile
+	// and the class name of loaded scripts with dots replaced by underscores.
+	Field  string
+	Script string
+}
+
+// replayState is the state of the replay dialog.
+type replayState struct {
+	JobName     string   `json:"job_name"`
+	BuildNumber int64    `json:"build_number"`
+	Fields      []string `json:"fields"`
+}
+
+// buildCauses are the causes of the builds of a job.
+type buildCauses struct {
+	Builds []struct {
+		Number  int64 `json:"number"`
+		Actions []struct {
+			Causes []struct {
+				Class            string `json:"_class"`
+				OriginalNumber   int64  `json:"originalNumber"`
+				ShortDescription string `json:"shortDescription"`
+			} `json:"causes"`
+		} `json:"actions"`
+	} `json:"builds"`
+}
+
+// findReplayedBuild returns the number of the build, not older than minNumber, which replays the build
+// with the given number. Builds started in the meantime by other causes are skipped.
+func findReplayedBuild(causes *buildCauses, minNumber, originalNumber int64) (int64, bool) {
+	for _, build := range causes.Builds {
+		if build.Number < minNumber {
+			continue
+		}
+		for _, action := range build.Actions {
+			for _, cause := range action.Causes {
+				if cause.Class != replayCauseClass {
+					continue
+				}
+				if cause.OriginalNumber == originalNumber {
+					return build.Number, true
+				}
+				// Older versions of Pipeline don't export the original number, only its description.
+				if match := replayOriginPattern.FindStringSubmatch(cause.ShortDescription); match != nil && match[1] == strconv.FormatInt(originalNumber, 10) {
+					return build.Number, true
+				}
+			}
+		}
+	}
+	return 0, false
+}
+
+// parseReplayScripts extracts the main script and the loaded scripts from the replay page of a build.
+// The main script comes first.
+func parseReplayScripts(page string) []replayScript {
+	scripts := []replayScript{}
+	for _, match := range replayTextareaPattern.FindAllStringSubmatch(page, -1) {
+		field := strings.TrimPrefix(match[1], "_.")
+		// Text areas start with a newline which isn't part of their value.
+		script := strings.TrimPrefix(html.UnescapeString(match[2]), "\n")
+		if field == replayMainScriptField {
+			scripts = append([]replayScript{{Field: field, Script: script}}, scripts...)
+			continue
+		}
+		scripts = append(scripts, replayScript{Field: field, Script: script})
+	}
+	if len(scripts) == 0 || scripts[0].Field != replayMainScriptField {
+		return nil
+	}
+	return scripts
+}
+
+// replayDialogElements creates a text area for every script of the build.
+func replayDialogElements(scripts []replayScript) []model.DialogElement {
+	elements := []model.DialogElement{}
+	for _, s := range scripts {
+		displayName := "Main script"
+		if s.Field != replayMainScriptField {
+			displayName = fmt.Sprintf("Loaded script %s", s.Field)
+		}
+		elements = append(elements, model.DialogElement{
+			DisplayName: displayName,
+			Name:        s.Field,
+			Type:        "textarea",
+			Default:     s.Script,
+			MaxLength:   maxReplayScriptLength,
+			Optional:    s.Field != replayMainScriptField,
+		})
+	}
+	return elements
+}
+
+// replayForm creates the form submitted to the replay endpoint with the edited scripts.
+func replayForm(fields []string, submission map[string]interface{}) (url.Values, error) {
+	scripts := make(map[string]string)
+	for _, field := range fields {
+		script, _ := submission[field].(string)
+		scripts[field] = script
+	}
+	data, err := json.Marshal(scripts)
+	if err != nil {
+		return nil, err
+	}
+	return url.Values{"json": {string(data)}, replayMainScriptField: {scripts[replayMainScriptField]}}, nil
+}
+
+// openReplayDialog opens a dialog pre-filled with the main script and the loaded scripts of the given build.
+// If build number is not specified, the last build of the job is replayed.
+func (p *Plugin) openReplayDialog(userID, triggerID, jobName, buildID string) error {
+	build, buildErr := p.getBuild(jobName, userID, buildID)
+	if buildErr != nil {
+		return buildErr
+	}
+
+	var page string
+	resp, err := build.Jenkins.Requester.Get(build.Base+"/replay/", &page, nil)
+	if err != nil {
+		return errors.Wrap(err, "Error fetching the scripts of the build")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("Error fetching the scripts of the build: %s", resp.Status)
+	}
+	scripts := parseReplayScripts(page)
+	if scripts == nil {
+		return errors.New("no scripts found on the replay page")
+	}
+
+	state := replayState{JobName: jobName, BuildNumber: build.GetBuildNumber()}
+	for _, s := range scripts {
+		state.Fields = append(state.Fields, s.Field)
+	}
+	stateBytes, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	siteURL := *p.API.GetConfig().ServiceSettings.SiteURL
+	dialog := model.OpenDialogRequest{
+		TriggerId: triggerID,
+		URL:       fmt.Sprintf("%s/plugins/jenkins/replay", siteURL),
+		Dialog: model.Dialog{
+			Title:       fmt.Sprintf("Replay #%d", build.GetBuildNumber()),
+			CallbackId:  userID,
+			SubmitLabel: "Run",
+			State:       string(stateBytes),
+			Elements:    replayDialogElements(scripts),
+		},
+	}
+	if appErr := p.API.OpenInteractiveDialog(dialog); appErr != nil {
+		return errors.Wrap(appErr, "Error opening the interactive dialog")
+	}
+	return nil
+}
+
+// replayBuild submits the edited scripts to the replay endpoint of the build. The build whose replay cause
+// points to the original build is posted once it has started, and watched like a triggered build.
+func (p *Plugin) replayBuild(userID, channelID string, state *replayState, submission map[string]interface{}) error {
+	job, jobErr := p.getJob(userID, state.JobName)
+	if jobErr != nil {
+		return jobErr
+	}
+	nextBuildNumber := job.Raw.NextBuildNumber
+
+	form, err := replayForm(state.Fields, submission)
+	if err != nil {
+		return err
+	}
+	if err := postJenkinsForm(job.Jenkins, fmt.Sprintf("%s/%d/replay/run", job.Base, state.BuildNumber), form); err != nil {
+		return errors.Wrap(err, "Error replaying the build")
+	}
+	p.createPost(userID, channelID, fmt.Sprintf("Replay of the build #%d of the job '%s' has been triggered and is in queue.", state.BuildNumber, state.JobName))
+
+	go func() {
+		deadline := time.Now().Add(replayStartTimeout)
+		for time.Now().Before(deadline) {
+			if !p.sleep(pollingSleepTime * time.Second) {
+				return
+			}
+			causes := &buildCauses{}
+			if _, err := job.Jenkins.Requester.GetJSON(job.Base, causes, map[string]string{"tree": replayedBuildsTreeQuery}); err != nil {
+				p.API.LogWarn("Error polling the job for the replayed build", "job_name", state.JobName, "err", err.Error())
+				continue
+			}
+			number, ok := findReplayedBuild(causes, nextBuildNumber, state.BuildNumber)
+			if !ok {
+				continue
+			}
+			build, err := job.GetBuild(number)
+			if err != nil {
+				p.API.LogWarn("Error fetching the replayed build", "job_name", state.JobName, "err", err.Error())
+				return
+			}
+			p.createPost(userID, channelID, fmt.Sprintf("Job '%s' - #%d has been started as a replay of #%d\nBuild URL : %s", state.JobName, build.GetBuildNumber(), state.BuildNumber, build.GetUrl()))
+			if p.getConfiguration().PostBuildCompletion {
+				p.watchBuild(userID, channelID, state.JobName, build.GetBuildNumber())
+			}
+			return
+		}
+		p.API.LogWarn("Replayed build didn't start in time", "job_name", state.JobName, "build_number", state.BuildNumber)
+	}()
+	return nil
+}
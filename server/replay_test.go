@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testReplayPage = `<form method="post" action="run" name="config">
+<textarea name="_.Script1" class="setting-input">
+def helper() { echo &quot;help&quot; }</textarea>
+<textarea name="_.mainScript" class="setting-input">
+pipeline {
+  agent any
+  stages { stage('Build') { steps { sh 'make &amp;&amp; make test' } } }
+}</textarea>
+</form>`
+
+func TestParseReplayScripts(t *testing.T) {
+	scripts := parseReplayScripts(testReplayPage)
+	require.Len(t, scripts, 2)
+	assert.Equal(t, "mainScript", scripts[0].Field)
+	assert.Contains(t, scripts[0].Script, "sh 'make && make test'")
+	assert.True(t, len(scripts[0].Script) > 0 && scripts[0].Script[0] == 'p')
+	assert.Equal(t, replayScript{Field: "Script1", Script: `def helper() { echo "help" }`}, scripts[1])
+
+	assert.Nil(t, parseReplayScripts("<html>Not found</html>"))
+}
+
+func TestReplayDialogElements(t *testing.T) {
+	elements := replayDialogElements(parseReplayScripts(testReplayPage))
+	require.Len(t, elements, 2)
+	assert.Equal(t, "Main script", elements[0].DisplayName)
+	assert.False(t, elements[0].Optional)
+	assert.Equal(t, "textarea", elements[0].Type)
+	assert.Equal(t, "Loaded script Script1", elements[1].DisplayName)
+	assert.True(t, elements[1].Optional)
+}
+
+func TestReplayForm(t *testing.T) {
+	form, err := replayForm([]string{"mainScript", "Script1"}, map[string]interface{}{"mainScript": "node {}"})
+	require.Nil(t, err)
+	assert.Equal(t, "node {}", form.Get("mainScript"))
+
+	scripts := map[string]string{}
+	require.Nil(t, json.Unmarshal([]byte(form.Get("json")), &scripts))
+	assert.Equal(t, map[string]string{"mainScript": "node {}", "Script1": ""}, scripts)
+}
+
+func TestFindReplayedBuild(t *testing.T) {
+	causes := &buildCauses{}
+	require.NoError(t, json.Unmarshal([]byte(`{"builds": [
+		{"number": 15, "actions": [{"causes": [{"_class": "org.jenkinsci.plugins.workflow.cps.replay.ReplayCause", "shortDescription": "Replayed #9"}]}]},
+		{"number": 14, "actions": [{}, {"causes": [{"_class": "org.jenkinsci.plugins.workflow.cps.replay.ReplayCause", "originalNumber": 12, "shortDescription": "Replayed #12"}]}]},
+		{"number": 13, "actions": [{"causes": [{"_class": "hudson.triggers.SCMTrigger$SCMTriggerCause", "shortDescription": "Started by an SCM change"}]}]},
+		{"number": 12, "actions": [{"causes": [{"_class": "hudson.model.Cause$UserIdCause", "shortDescription": "Started by user admin"}]}]}
+	]}`), causes))
+
+	number, ok := findReplayedBuild(causes, 13, 12)
+	assert.True(t, ok)
+	assert.Equal(t, int64(14), number)
+
+	number, ok = findReplayedBuild(causes, 13, 9)
+	assert.True(t, ok)
+	assert.Equal(t, int64(15), number)
+
+	_, ok = findReplayedBuild(causes, 13, 11)
+	assert.False(t, ok)
+	_, ok = findReplayedBuild(causes, 16, 12)
+	assert.False(t, ok)
+}
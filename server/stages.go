@@ -0,0 +1,255 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/waseem18/gojenkins"
+)
+
+const (
+	stageStatusSuccess     = "SUCCESS"
+	stageStatusFailed      = "FAILED"
+	stageStatusUnstable    = "UNSTABLE"
+	stageStatusAborted     = "ABORTED"
+	stageStatusInProgress  = "IN_PROGRESS"
+	stageStatusPaused      = "PAUSED_PENDING_INPUT"
+	stageStatusNotExecuted = "NOT_EXECUTED"
+)
+
+// htmlTagPattern matches the HTML tags in the logs returned by the Pipeline REST API.
+var htmlTagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// pipelineRun is a build of a Pipeline job, as described by the wfapi/describe endpoint.
+type pipelineRun struct {
+	ID             string          `json:"id"`
+	Name           string          `json:"name"`
+	Status         string          `json:"status"`
+	DurationMillis int64           `json:"durationMillis"`
+	Stages         []pipelineStage `json:"stages"`
+}
+
+// pipelineStage is a stage of a Pipeline build.
+type pipelineStage struct {
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	Status         string `json:"status"`
+	DurationMillis int64  `json:"durationMillis"`
+	// StageFlowNodes are the steps of the stage. They are only returned when describing a single stage.
+	StageFlowNodes []pipelineFlowNode `json:"stageFlowNodes"`
+}
+
+// pipelineFlowNode is a step of a Pipeline stage.
+type pipelineFlowNode struct {
+	ID                   string `json:"id"`
+	Name                 string `json:"name"`
+	Status               string `json:"status"`
+	ParameterDescription string `json:"parameterDescription"`
+}
+
+// pipelineNodeLog is the log of a step, as returned by the wfapi/log endpoint.
+type pipelineNodeLog struct {
+	Text    string `json:"text"`
+	HasMore bool   `json:"hasMore"`
+}
+
+func (s *pipelineStage) isFailed() bool {
+	return s.Status == stageStatusFailed || s.Status == stageStatusUnstable || s.Status == stageStatusAborted
+}
+
+// stageStatusIcon returns the emoji shown next to a stage with the given status.
+func stageStatusIcon(status string) string {
+	switch status {
+	case stageStatusSuccess:
+		return ":white_check_mark:"
+	case stageStatusFailed:
+		return ":x:"
+	case stageStatusUnstable:
+		return ":warning:"
+	case stageStatusAborted:
+		return ":no_entry_sign:"
+	case stageStatusInProgress:
+		return ":arrows_counterclockwise:"
+	case stageStatusPaused:
+		return ":pause_button:"
+	default:
+		return ":white_circle:"
+	}
+}
+
+// findStage returns the stage with the given name, ignoring case, or with the given ID.
+func (r *pipelineRun) findStage(name string) *pipelineStage {
+	for i := range r.Stages {
+		if strings.EqualFold(r.Stages[i].Name, name) || r.Stages[i].ID == name {
+			return &r.Stages[i]
+		}
+	}
+	return nil
+}
+
+func (r *pipelineRun) stageNames() []string {
+	names := []string{}
+	for _, s := range r.Stages {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+// formatStages renders the stages of a Pipeline build as a markdown table, with the failing stages highlighted.
+func formatStages(jobName string, buildNumber int64, run *pipelineRun) string {
+	if len(run.Stages) == 0 {
+		return fmt.Sprintf("No stages found in the build #%d of the job '%s'. Is it a Pipeline job?", buildNumber, jobName)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s Stages of the build #%d of the job '%s': %s in %s\n\n",
+		stageStatusIcon(run.Status), buildNumber, jobName, run.Status, formatDuration(time.Duration(run.DurationMillis)*time.Millisecond))
+	sb.WriteString("| Stage | Status | Duration |\n")
+	sb.WriteString("|:------|:-------|:---------|\n")
+	var failed *pipelineStage
+	for i, s := range run.Stages {
+		name, status := escapeTableCell(s.Name), s.Status
+		duration := formatDuration(time.Duration(s.DurationMillis) * time.Millisecond)
+		if s.Status == stageStatusNotExecuted {
+			duration = "-"
+		}
+		if s.isFailed() {
+			name, status = "**"+name+"**", "**"+status+"**"
+			if failed == nil {
+				failed = &run.Stages[i]
+			}
+		}
+		fmt.Fprintf(&sb, "| %s | %s %s | %s |\n", name, stageStatusIcon(s.Status), status, duration)
+	}
+
+	if failed != nil {
+		fmt.Fprintf(&sb, "\nThe stage **%s** failed. Use `/jenkins stage-log %s %d %s` to get its log.\n", failed.Name, quoteJobName(jobName), buildNumber, failed.Name)
+	}
+	return sb.String()
+}
+
+// quoteJobName wraps job names containing spaces in double quotes, as expected by the slash commands.
+func quoteJobName(jobName string) string {
+	if strings.Contains(jobName, " ") {
+		return `"` + jobName + `"`
+	}
+	return jobName
+}
+
+// htmlLogToText converts a log returned by the Pipeline REST API to plain text.
+func htmlLogToText(log string) string {
+	return stripANSI(html.UnescapeString(htmlTagPattern.ReplaceAllString(log, "")))
+}
+
+// formatStageLog combines the logs of the steps of a stage.
+func formatStageLog(nodes []pipelineFlowNode, logs []*pipelineNodeLog, consoleURL string) string {
+	var sb strings.Builder
+	for i, node := range nodes {
+		header := node.Name
+		if node.ParameterDescription != "" {
+			header = fmt.Sprintf("%s (%s)", node.Name, node.ParameterDescription)
+		}
+		fmt.Fprintf(&sb, "[%s]\n", header)
+		text := strings.TrimRight(htmlLogToText(logs[i].Text), "\n")
+		if text != "" {
+			sb.WriteString(text + "\n")
+		}
+		if logs[i].HasMore {
+			fmt.Fprintf(&sb, "... log truncated, see %sexecution/node/%s/log/\n", consoleURL, node.ID)
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// parseStageLogParameters parses the parameters of the stage-log command: a job name, a build number
+// and a stage name. The job name may be wrapped in double quotes, the stage name is the rest of the parameters.
+func parseStageLogParameters(parameters []string) (string, string, string, bool) {
+	for i := 1; i < len(parameters)-1; i++ {
+		if _, err := strconv.ParseInt(parameters[i], 10, 64); err != nil {
+			continue
+		}
+		// A number inside a quoted job name isn't the build number, try the next one.
+		if strings.Count(strings.Join(parameters[:i], " "), `"`)%2 != 0 {
+			continue
+		}
+		jobName, extraParam, ok := parseBuildParameters(parameters[:i])
+		if !ok || extraParam != "" {
+			continue
+		}
+		stage := strings.Trim(strings.Join(parameters[i+1:], " "), `"`)
+		if stage == "" {
+			return "", "", "", false
+		}
+		return jobName, parameters[i], stage, true
+	}
+	return "", "", "", false
+}
+
+// getPipelineRun fetches the description of a Pipeline build with its stages.
+func getPipelineRun(build *gojenkins.Build) (*pipelineRun, error) {
+	run := &pipelineRun{}
+	if err := getJenkinsJSON(build.Jenkins, build.Base+"/wfapi/describe", run); err != nil {
+		return nil, errors.Wrap(err, "Error fetching the stages of the build")
+	}
+	return run, nil
+}
+
+// postStages creates a post with the stages of the given Pipeline build.
+// If build number is not specified, the stages of the last build of the job are posted.
+func (p *Plugin) postStages(userID, channelID, jobName, buildID string) error {
+	build, buildErr := p.getBuild(jobName, userID, buildID)
+	if buildErr != nil {
+		return buildErr
+	}
+
+	run, err := getPipelineRun(build)
+	if err != nil {
+		return err
+	}
+	p.createPost(userID, channelID, formatStages(jobName, build.GetBuildNumber(), run))
+	return nil
+}
+
+// postStageLog fetches the logs of the steps of a stage of the given Pipeline build and posts them,
+// so only the relevant part of the console log has to be downloaded.
+func (p *Plugin) postStageLog(userID, channelID, jobName, buildID, stageName string) error {
+	build, buildErr := p.getBuild(jobName, userID, buildID)
+	if buildErr != nil {
+		return buildErr
+	}
+
+	run, err := getPipelineRun(build)
+	if err != nil {
+		return err
+	}
+	stage := run.findStage(stageName)
+	if stage == nil {
+		p.createPost(userID, channelID, fmt.Sprintf("No stage '%s' found in the build #%d of the job '%s'. Available stages: %s.",
+			stageName, build.GetBuildNumber(), jobName, strings.Join(run.stageNames(), ", ")))
+		return nil
+	}
+
+	nodeBase := build.Base + "/execution/node/"
+	described := &pipelineStage{}
+	if err := getJenkinsJSON(build.Jenkins, nodeBase+stage.ID+"/wfapi/describe", described); err != nil {
+		return errors.Wrap(err, "Error fetching the steps of the stage")
+	}
+
+	logs := make([]*pipelineNodeLog, len(described.StageFlowNodes))
+	for i, node := range described.StageFlowNodes {
+		logs[i] = &pipelineNodeLog{}
+		if err := getJenkinsJSON(build.Jenkins, nodeBase+node.ID+"/wfapi/log", logs[i]); err != nil {
+			return errors.Wrap(err, "Error fetching the log of a step")
+		}
+	}
+
+	log := p.getBuildRedactor(userID, jobName, build).redact(formatStageLog(described.StageFlowNodes, logs, build.GetUrl()))
+	msg := fmt.Sprintf("%s Log of the stage '%s' of the build #%d of the job '%s'", stageStatusIcon(stage.Status), stage.Name, build.GetBuildNumber(), jobName)
+	filename := fmt.Sprintf("%s-%d-%s.log", jobName, build.GetBuildNumber(), stage.Name)
+	return p.postLog(userID, channelID, msg, filename, log)
+}
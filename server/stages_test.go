@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPipelineRun = `{
+	"id": "12", "name": "#12", "status": "FAILED", "durationMillis": 182000,
+	"stages": [
+		{"id": "6", "name": "Checkout", "status": "SUCCESS", "durationMillis": 5000},
+		{"id": "14", "name": "Unit Tests", "status": "FAILED", "durationMillis": 121000},
+		{"id": "30", "name": "Deploy", "status": "NOT_EXECUTED", "durationMillis": 0}
+	]
+}`
+
+func TestFormatStages(t *testing.T) {
+	run := &pipelineRun{}
+	require.Nil(t, json.Unmarshal([]byte(testPipelineRun), run))
+
+	msg := formatStages("folder/job 1", 12, run)
+	assert.Contains(t, msg, ":x: Stages of the build #12 of the job 'folder/job 1': FAILED in 3m 2s\n")
+	assert.Contains(t, msg, "| Checkout | :white_check_mark: SUCCESS | 5s |\n")
+	assert.Contains(t, msg, "| **Unit Tests** | :x: **FAILED** | 2m 1s |\n")
+	assert.Contains(t, msg, "| Deploy | :white_circle: NOT_EXECUTED | - |\n")
+	assert.Contains(t, msg, "Use `/jenkins stage-log \"folder/job 1\" 12 Unit Tests` to get its log.")
+
+	assert.Equal(t, "No stages found in the build #3 of the job 'job1'. Is it a Pipeline job?", formatStages("job1", 3, &pipelineRun{}))
+}
+
+func TestFindStage(t *testing.T) {
+	run := &pipelineRun{}
+	require.Nil(t, json.Unmarshal([]byte(testPipelineRun), run))
+
+	assert.Equal(t, "14", run.findStage("unit tests").ID)
+	assert.Equal(t, "Deploy", run.findStage("30").Name)
+	assert.Nil(t, run.findStage("Release"))
+}
+
+func TestFormatStageLog(t *testing.T) {
+	nodes := []pipelineFlowNode{
+		{ID: "15", Name: "Shell Script", ParameterDescription: "make test"},
+		{ID: "16", Name: "Print Message"},
+	}
+	logs := []*pipelineNodeLog{
+		{Text: "<span class=\"timestamp\">10:00</span> a &lt; b &amp;&amp; c\n", HasMore: true},
+		{Text: ""},
+	}
+	assert.Equal(t, "[Shell Script (make test)]\n10:00 a < b && c\n... log truncated, see http://jenkins/job/job1/12/execution/node/15/log/\n[Print Message]",
+		formatStageLog(nodes, logs, "http://jenkins/job/job1/12/"))
+}
+
+func TestParseStageLogParameters(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Parameters []string
+		Job        string
+		Build      string
+		Stage      string
+		OK         bool
+	}{
+		"simple":               {[]string{"job1", "12", "Build"}, "job1", "12", "Build", true},
+		"stage with spaces":    {[]string{"folder/job1", "12", "Unit", "Tests"}, "folder/job1", "12", "Unit Tests", true},
+		"quoted job and stage": {[]string{`"my`, `job"`, "3", `"Unit`, `Tests"`}, "my job", "3", "Unit Tests", true},
+		"number in quoted job": {[]string{`"release`, "2024", `build"`, "5", "Deploy"}, "release 2024 build", "5", "Deploy", true},
+		"missing stage":        {[]string{"job1", "12"}, "", "", "", false},
+		"missing build":        {[]string{"job1", "Build"}, "", "", "", false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			job, build, stage, ok := parseStageLogParameters(tc.Parameters)
+			assert.Equal(t, tc.OK, ok)
+			assert.Equal(t, tc.Job, job)
+			assert.Equal(t, tc.Build, build)
+			assert.Equal(t, tc.Stage, stage)
+		})
+	}
+}
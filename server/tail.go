@@ -0,0 +1,293 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+	"github.com/waseem18/gojenkins"
+)
+
+const (
+	tailStopKeyPrefix = "tailstop_"
+	// tailPollInterval is the time between two requests for new log output.
+	tailPollInterval = 3 * time.Second
+	// tailPostInterval is the minimum time between two replies in the thread.
+	tailPostInterval = 15 * time.Second
+	// maxTailDuration is the time after which a log is no longer followed.
+	maxTailDuration = 2 * time.Hour
+	// maxTailChunkLength is the maximum size of the log output in a reply.
+	// Older output which doesn't fit in a reply is skipped.
+	maxTailChunkLength = 3500
+	// tailInitialLines is the number of lines of the existing log posted when following starts.
+	tailInitialLines = 20
+	// tailInitialLogSize is the size of the end of the existing log fetched when following starts.
+	tailInitialLogSize = 16 * 1024
+	// tailInputCheckPolls is the number of polls between two checks for pending input steps.
+	tailInputCheckPolls = 5
+)
+
+// logTail is a console log being followed into a thread.
+type logTail struct {
+	ID       string
+	UserID   string
+	JobName  string
+	RootPost *model.Post
+}
+
+// tailBuffer collects new log output between two replies.
+type tailBuffer struct {
+	lines    []string
+	partial  string
+	skipped  int
+	redactor *redactor
+	// inPrivateKey is set when a private key block began in a previous reply and hasn't ended yet.
+	inPrivateKey bool
+	// skipPartialLine is set when the log is followed from an offset which may be in the middle of a line.
+	skipPartialLine bool
+}
+
+// add appends new log output. An unterminated last line is kept until the rest of it arrives.
+func (b *tailBuffer) add(text string) {
+	if b.skipPartialLine {
+		i := strings.Index(text, "\n")
+		if i < 0 {
+			return
+		}
+		text = text[i+1:]
+		b.skipPartialLine = false
+	}
+	text = b.partial + stripANSI(text)
+	lines := strings.Split(text, "\n")
+	b.partial = lines[len(lines)-1]
+	for _, line := range lines[:len(lines)-1] {
+		b.lines = append(b.lines, strings.TrimRight(line, "\r"))
+	}
+}
+
+// keepLast drops all but the last n complete lines.
+func (b *tailBuffer) keepLast(n int) {
+	if len(b.lines) > n {
+		b.skipped += len(b.lines) - n
+		b.inPrivateKey = isPrivateKeyOpen(strings.Join(b.lines[:len(b.lines)-n], "\n"), b.inPrivateKey)
+		b.lines = b.lines[len(b.lines)-n:]
+	}
+}
+
+// flush returns the buffered output as the message of a reply and empties the buffer.
+// Secrets are masked before the output is wrapped in a code block.
+// Only the newest lines which fit in maxTailChunkLength are kept. If final is set,
+// an unterminated last line is included as well.
+func (b *tailBuffer) flush(final bool) string {
+	if final && b.partial != "" {
+		b.lines = append(b.lines, b.partial)
+		b.partial = ""
+	}
+
+	length := 0
+	first := len(b.lines)
+	for first > 0 && length+len(b.lines[first-1])+1 <= maxTailChunkLength {
+		first--
+		length += len(b.lines[first]) + 1
+	}
+	b.skipped += first
+
+	var sb strings.Builder
+	if b.skipped > 0 {
+		fmt.Fprintf(&sb, "_%d line(s) skipped_\n", b.skipped)
+	}
+	// A private key block may span several replies, so whether one is open is tracked
+	// across the skipped lines and the replies.
+	inPrivateKey := isPrivateKeyOpen(strings.Join(b.lines[:first], "\n"), b.inPrivateKey)
+	if first < len(b.lines) {
+		output := strings.Join(b.lines[first:], "\n")
+		nextInPrivateKey := isPrivateKeyOpen(output, inPrivateKey)
+		if b.redactor != nil {
+			if inPrivateKey {
+				output = redactPrivateKeyContinuation(output)
+			}
+			output = b.redactor.redact(output)
+		}
+		inPrivateKey = nextInPrivateKey
+		fmt.Fprintf(&sb, "```\n%s\n```", strings.ReplaceAll(output, "```", "'''"))
+	}
+	b.inPrivateKey = inPrivateKey
+
+	b.lines = nil
+	b.skipped = 0
+	return strings.TrimSpace(sb.String())
+}
+
+func tailStopKey(tailID string) string {
+	return tailStopKeyPrefix + tailID
+}
+
+// getConsoleLogSize returns the current size of the console log of the build in bytes.
+// Only the X-Text-Size header of the progressive log is read, not the log itself.
+func getConsoleLogSize(build *gojenkins.Build) (int64, error) {
+	resp, err := openJenkinsStream(build.Jenkins, build.Base+"/logText/progressiveText?start=0")
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	size, err := strconv.ParseInt(resp.Header.Get("X-Text-Size"), 10, 64)
+	if err != nil {
+		return 0, errors.Wrap(err, "invalid size of the console log")
+	}
+	return size, nil
+}
+
+// startLogTail creates a post for the given build and follows its console log in the thread of that post.
+// If build number is not specified, the log of the last build of the job is followed.
+func (p *Plugin) startLogTail(userID, channelID, jobName, buildID string) error {
+	build, buildErr := p.getBuild(jobName, userID, buildID)
+	if buildErr != nil {
+		return buildErr
+	}
+
+	tail := &logTail{
+		ID:      model.NewId(),
+		UserID:  userID,
+		JobName: jobName,
+	}
+
+	siteURL := *p.API.GetConfig().ServiceSettings.SiteURL
+	attachment := generateSlackAttachment(fmt.Sprintf("Following the console log of the build #%d of the job '%s' in the thread.\nBuild URL : %s", build.GetBuildNumber(), jobName, build.GetUrl()))
+	attachment.Actions = []*model.PostAction{{
+		Name: "Stop",
+		Type: model.PostActionTypeButton,
+		Integration: &model.PostActionIntegration{
+			URL:     fmt.Sprintf("%s/plugins/jenkins/tail/stop", siteURL),
+			Context: map[string]interface{}{"tail_id": tail.ID, "started_by": userID},
+		},
+	}}
+	tail.RootPost = p.createAttachmentPost(userID, channelID, attachment)
+	if tail.RootPost == nil {
+		return errors.New("Error creating the post to follow the log in")
+	}
+
+	go p.followLog(tail, build, p.getBuildRedactor(userID, jobName, build))
+	return nil
+}
+
+// followLog polls the progressive console log of the build and posts the new output as replies,
+// until the build finishes, the Stop button is clicked, maxTailDuration is reached or the plugin is deactivated.
+// Following starts with the last lines of the existing log, of which only the end is fetched.
+// Input steps the build waits on are posted in the channel with buttons to respond to them.
+func (p *Plugin) followLog(tail *logTail, build *gojenkins.Build, redactor *redactor) {
+	buffer := &tailBuffer{redactor: redactor}
+	deadline := time.Now().Add(maxTailDuration)
+	lastReply := time.Time{}
+	offset := int64(0)
+	postedInputs := make(map[string]bool)
+
+	size, err := getConsoleLogSize(build)
+	if err != nil {
+		p.API.LogWarn("Error fetching the size of the console log", "job_name", tail.JobName, "err", err.Error())
+	}
+	if size > tailInitialLogSize {
+		offset = size - tailInitialLogSize
+		buffer.skipPartialLine = true
+	}
+	initial := true
+
+	reply := func(final bool) {
+		if msg := buffer.flush(final); msg != "" {
+			p.createReply(tail.RootPost, msg)
+			lastReply = time.Now()
+		}
+	}
+
+	for polls := 0; ; polls++ {
+		console, err := build.GetConsoleOutputFromIndex(offset)
+		if err != nil {
+			p.API.LogError("Error fetching console log", "job_name", tail.JobName, "err", err.Error())
+			reply(true)
+			p.finishLogTail(tail, "Stopped following the log: the console log could not be fetched.")
+			return
+		}
+		buffer.add(console.Content)
+		if initial {
+			buffer.keepLast(tailInitialLines)
+			initial = false
+		}
+		offset = console.Offset
+
+		if !console.HasMoreText {
+			reply(true)
+			p.finishLogTail(tail, "The build has finished.")
+			return
+		}
+
+		if stoppedBy, appErr := p.API.KVGet(tailStopKey(tail.ID)); appErr == nil && stoppedBy != nil {
+			reply(true)
+			p.finishLogTail(tail, fmt.Sprintf("Stopped following the log on request of @%s.", p.getUsername(string(stoppedBy))))
+			return
+		}
+
+		if time.Now().After(deadline) {
+			reply(true)
+			p.finishLogTail(tail, fmt.Sprintf("Stopped following the log after %s.", formatDuration(maxTailDuration)))
+			return
+		}
+
+		if time.Since(lastReply) >= tailPostInterval {
+			reply(false)
+		}
+		if polls%tailInputCheckPolls == 0 {
+			p.postNewPendingInputs(tail.UserID, tail.RootPost.ChannelId, tail.JobName, build, postedInputs)
+		}
+		if !p.sleep(tailPollInterval) {
+			return
+		}
+	}
+}
+
+// postNewPendingInputs posts the input steps the build waits on which haven't been posted yet.
+func (p *Plugin) postNewPendingInputs(userID, channelID, jobName string, build *gojenkins.Build, posted map[string]bool) {
+	inputs, err := getPendingInputs(build)
+	if err != nil {
+		p.API.LogWarn("Error checking for pending inputs", "job_name", jobName, "err", err.Error())
+		return
+	}
+	for i := range inputs {
+		if posted[inputs[i].ID] {
+			continue
+		}
+		posted[inputs[i].ID] = true
+		p.postInputRequest(userID, channelID, jobName, build, &inputs[i])
+	}
+}
+
+// finishLogTail removes the Stop button from the post of the tail and adds the reason why following stopped.
+func (p *Plugin) finishLogTail(tail *logTail, reason string) {
+	if appErr := p.API.KVDelete(tailStopKey(tail.ID)); appErr != nil {
+		p.API.LogWarn("Error deleting the stop request of a log tail", "err", appErr.Error())
+	}
+
+	p.closeActionPost(tail.RootPost, reason)
+}
+
+// stopLogTail requests the log tail with the given ID to stop. Only the user who started following the log
+// and system admins can stop it. Returns a message describing the outcome.
+func (p *Plugin) stopLogTail(tailID, startedBy, userID string) (string, error) {
+	if userID != startedBy && !p.isSystemAdmin(userID) {
+		return "Only the user who started following the log or a system admin can stop it.", nil
+	}
+	if appErr := p.API.KVSetWithExpiry(tailStopKey(tailID), []byte(userID), int64((maxTailDuration + time.Hour).Seconds())); appErr != nil {
+		return "", appErr
+	}
+	return "Stopping to follow the log...", nil
+}
+
+// getUsername returns the Mattermost username of the given user, or the user ID if the user can't be fetched.
+func (p *Plugin) getUsername(userID string) string {
+	user, appErr := p.API.GetUser(userID)
+	if appErr != nil {
+		return userID
+	}
+	return user.Username
+}
@@ -0,0 +1,346 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/mattermost/mattermost/server/public/model"
+	"github.com/pkg/errors"
+)
+
+const (
+	jobTemplatesKey = "job_templates"
+
+	// maxAttachedFileSize is the size of the largest file read from a post.
+	maxAttachedFileSize = 1024 * 1024
+)
+
+var templateNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// jobTemplate is a config.xml with placeholders, used to create jobs from a dialog.
+type jobTemplate struct {
+	Name      string   `json:"name"`
+	Content   string   `json:"content"`
+	Variables []string `json:"variables"`
+	// Optional are the variables only used in the conditions and bodies of if and with actions.
+	Optional  []string `json:"optional,omitempty"`
+	CreatedBy string   `json:"created_by"`
+}
+
+// templateVariables collects the variables used in a template.
+type templateVariables struct {
+	// all are the variables in the order of their first use.
+	all []string
+	// required are the variables used outside of if and with actions.
+	required []string
+}
+
+// parseJobTemplate parses a config.xml template and collects the variables it declares,
+// in the order of their first use.
+func parseJobTemplate(name, content string) (*jobTemplate, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(content)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid template")
+	}
+
+	variables := &templateVariables{all: []string{}}
+	if tmpl.Tree != nil {
+		variables.collect(tmpl.Tree.Root, false)
+	}
+	optional := []string{}
+	for _, v := range variables.all {
+		if !containsString(variables.required, v) {
+			optional = append(optional, v)
+		}
+	}
+	return &jobTemplate{Name: name, Content: content, Variables: variables.all, Optional: optional}, nil
+}
+
+// collect adds the fields of the dot used in the given node to the variables.
+// A field used in the condition or the body of if and with actions is conditional,
+// as the template renders without it if it's left empty.
+// The bodies of range and with are skipped, as the dot isn't the template data in them.
+func (v *templateVariables) collect(node parse.Node, conditional bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			v.collect(child, conditional)
+		}
+	case *parse.ActionNode:
+		v.collect(n.Pipe, conditional)
+	case *parse.IfNode:
+		v.collect(n.Pipe, true)
+		v.collect(n.List, true)
+		v.collect(n.ElseList, true)
+	case *parse.RangeNode:
+		v.collect(n.Pipe, conditional)
+	case *parse.WithNode:
+		v.collect(n.Pipe, true)
+	case *parse.TemplateNode:
+		v.collect(n.Pipe, conditional)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			v.collect(cmd, conditional)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			v.collect(arg, conditional)
+		}
+	case *parse.FieldNode:
+		name := n.Ident[0]
+		if !containsString(v.all, name) {
+			v.all = append(v.all, name)
+		}
+		if !conditional && !containsString(v.required, name) {
+			v.required = append(v.required, name)
+		}
+	}
+}
+
+// renderJobTemplate fills in the template with the given values, escaped for XML.
+func renderJobTemplate(t *jobTemplate, values map[string]string) (string, error) {
+	tmpl, err := template.New(t.Name).Option("missingkey=error").Parse(t.Content)
+	if err != nil {
+		return "", errors.Wrap(err, "invalid template")
+	}
+
+	escaped := make(map[string]string, len(values))
+	for k, v := range values {
+		var sb strings.Builder
+		if err := xml.EscapeText(&sb, []byte(v)); err != nil {
+			return "", err
+		}
+		escaped[k] = sb.String()
+	}
+
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, escaped); err != nil {
+		return "", errors.Wrap(err, "error rendering the template")
+	}
+	return sb.String(), nil
+}
+
+// formatJobTemplates renders the list of templates posted by the template list command.
+func formatJobTemplates(templates map[string]*jobTemplate) string {
+	if len(templates) == 0 {
+		return "No job templates have been added yet."
+	}
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	sb.WriteString("Job templates:\n")
+	for _, name := range names {
+		variables := []string{}
+		for _, v := range templates[name].Variables {
+			if containsString(templates[name].Optional, v) {
+				variables = append(variables, fmt.Sprintf("`%s` (optional)", v))
+				continue
+			}
+			variables = append(variables, fmt.Sprintf("`%s`", v))
+		}
+		if len(variables) == 0 {
+			variables = append(variables, "none")
+		}
+		fmt.Fprintf(&sb, "* `%s` - variables: %s\n", name, strings.Join(variables, ", "))
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// parsePostReference returns the ID of a post given as ID or permalink.
+func parsePostReference(ref string) (string, bool) {
+	postID := ref
+	if u, err := url.Parse(ref); err == nil && u.Host != "" {
+		segments := strings.Split(strings.TrimSuffix(u.Path, "/"), "/")
+		postID = segments[len(segments)-1]
+	}
+	return postID, model.IsValidId(postID)
+}
+
+// getAttachedFile fetches the first file attached to the given post, if the user can read it.
+func (p *Plugin) getAttachedFile(userID, postRef string) (*model.FileInfo, []byte, error) {
+	postID, ok := parsePostReference(postRef)
+	if !ok {
+		return nil, nil, errors.Errorf("invalid post %q", postRef)
+	}
+	post, appErr := p.API.GetPost(postID)
+	if appErr != nil {
+		return nil, nil, errors.Wrap(appErr, "Error fetching the post")
+	}
+	if !p.API.HasPermissionToChannel(userID, post.ChannelId, model.PermissionReadChannel) {
+		return nil, nil, errors.New("the post can't be read by the user")
+	}
+	if len(post.FileIds) == 0 {
+		return nil, nil, errors.New("the post has no attached file")
+	}
+
+	info, appErr := p.API.GetFileInfo(post.FileIds[0])
+	if appErr != nil {
+		return nil, nil, errors.Wrap(appErr, "Error fetching the attached file")
+	}
+	if info.Size > maxAttachedFileSize {
+		return nil, nil, errors.Errorf("the attached file is larger than %d bytes", maxAttachedFileSize)
+	}
+	content, appErr := p.API.GetFile(info.Id)
+	if appErr != nil {
+		return nil, nil, errors.Wrap(appErr, "Error reading the attached file")
+	}
+	return info, content, nil
+}
+
+func (p *Plugin) getJobTemplates() (map[string]*jobTemplate, error) {
+	data, appErr := p.API.KVGet(jobTemplatesKey)
+	if appErr != nil {
+		return nil, errors.Wrap(appErr, "Error fetching the job templates")
+	}
+	templates := make(map[string]*jobTemplate)
+	if data == nil {
+		return templates, nil
+	}
+	if err := json.Unmarshal(data, &templates); err != nil {
+		return nil, errors.Wrap(err, "Error decoding the job templates")
+	}
+	return templates, nil
+}
+
+// addJobTemplate registers the config.xml attached to the given post as template.
+// Returns a message describing the outcome.
+func (p *Plugin) addJobTemplate(userID, name, postRef string) (string, error) {
+	_, content, err := p.getAttachedFile(userID, postRef)
+	if err != nil {
+		return fmt.Sprintf("Unable to read the config.xml: %s.", err.Error()), nil
+	}
+	t, err := parseJobTemplate(name, string(content))
+	if err != nil {
+		return fmt.Sprintf("Unable to add the template: %s.", err.Error()), nil
+	}
+	t.CreatedBy = userID
+
+	templates, err := p.getJobTemplates()
+	if err != nil {
+		return "", err
+	}
+	_, replaced := templates[name]
+	templates[name] = t
+	if err := p.storeJSON(jobTemplatesKey, templates); err != nil {
+		return "", errors.Wrap(err, "Error storing the job templates")
+	}
+
+	verb := "added"
+	if replaced {
+		verb = "replaced"
+	}
+	return fmt.Sprintf("Template `%s` has been %s. Use `/jenkins createjob --template %s` to create a job from it.", name, verb, name), nil
+}
+
+// removeJobTemplate removes a template. Returns a message describing the outcome.
+func (p *Plugin) removeJobTemplate(name string) (string, error) {
+	templates, err := p.getJobTemplates()
+	if err != nil {
+		return "", err
+	}
+	if _, ok := templates[name]; !ok {
+		return fmt.Sprintf("Template `%s` doesn't exist.", name), nil
+	}
+	delete(templates, name)
+	if err := p.storeJSON(jobTemplatesKey, templates); err != nil {
+		return "", errors.Wrap(err, "Error storing the job templates")
+	}
+	return fmt.Sprintf("Template `%s` has been removed.", name), nil
+}
+
+// createDialogForTemplate opens a dialog asking for the job name and the variables of the given template.
+// Returns a message for the user if the template doesn't exist.
+func (p *Plugin) createDialogForTemplate(userID, triggerID, name string) (string, error) {
+	templates, err := p.getJobTemplates()
+	if err != nil {
+		return "", err
+	}
+	t, ok := templates[name]
+	if !ok {
+		return fmt.Sprintf("Template `%s` doesn't exist. Use `/jenkins template list` to list the templates.", name), nil
+	}
+
+	elements := []model.DialogElement{{
+		DisplayName: "Job name",
+		Name:        "JobName",
+		Type:        "text",
+		SubType:     "text",
+		HelpText:    "Please use double quotes if the job name has spaces in it.",
+		MaxLength:   10000,
+	}}
+	for _, v := range t.Variables {
+		if v == "JobName" {
+			// The job name is filled in by the first element.
+			continue
+		}
+		elements = append(elements, model.DialogElement{
+			DisplayName: v,
+			Name:        v,
+			Type:        "text",
+			SubType:     "text",
+			MaxLength:   10000,
+			Optional:    containsString(t.Optional, v),
+		})
+	}
+
+	config := p.API.GetConfig()
+	dialog := model.OpenDialogRequest{
+		TriggerId: triggerID,
+		URL:       fmt.Sprintf("%s/plugins/jenkins/createJob/template", *config.ServiceSettings.SiteURL),
+		Dialog: model.Dialog{
+			Title:       fmt.Sprintf("Create a job from %s", name),
+			CallbackId:  userID,
+			SubmitLabel: "Create job",
+			State:       name,
+			Elements:    elements,
+		},
+	}
+	if appErr := p.API.OpenInteractiveDialog(dialog); appErr != nil {
+		return "", errors.Wrap(appErr, "Error opening the interactive dialog")
+	}
+	return "", nil
+}
+
+// createJobFromTemplate renders the template with the values of the dialog and creates the job.
+// Returns a message shown in the dialog if the values can't be used.
+func (p *Plugin) createJobFromTemplate(userID, channelID, name string, submission map[string]interface{}) (string, error) {
+	templates, err := p.getJobTemplates()
+	if err != nil {
+		return "", err
+	}
+	t, ok := templates[name]
+	if !ok {
+		return fmt.Sprintf("Template %s doesn't exist anymore.", name), nil
+	}
+
+	// The optional variables left empty may be missing from the submission.
+	values := make(map[string]string)
+	for _, v := range t.Optional {
+		values[v] = ""
+	}
+	for k, v := range submission {
+		values[k], _ = v.(string)
+	}
+	configXML, err := renderJobTemplate(t, values)
+	if err != nil {
+		return err.Error(), nil
+	}
+	return p.createJobFromConfig(userID, channelID, values["JobName"], configXML)
+}
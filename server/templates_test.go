@@ -0,0 +1,85 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testJobTemplate = `<flow-definition plugin="workflow-job">
+  <description>{{.Description}}</description>
+  <definition class="org.jenkinsci.plugins.workflow.cps.CpsScmFlowDefinition" plugin="workflow-cps">
+    <scm class="hudson.plugins.git.GitSCM" plugin="git">
+      <userRemoteConfigs><hudson.plugins.git.UserRemoteConfig><url>{{.RepoURL}}</url></hudson.plugins.git.UserRemoteConfig></userRemoteConfigs>
+      <branches><hudson.plugins.git.BranchSpec><name>*/{{.Branch}}</name></hudson.plugins.git.BranchSpec></branches>
+    </scm>
+    {{if .Label}}<assignedNode>{{.Label}}</assignedNode>{{end}}
+    {{range $i, $x := .Items}}{{.Ignored}}{{end}}
+    <scriptPath>Jenkinsfile</scriptPath>
+  </definition>
+  <displayName>{{.JobName}} ({{.Branch}})</displayName>
+</flow-definition>`
+
+func TestParseJobTemplate(t *testing.T) {
+	tmpl, err := parseJobTemplate("pipeline", testJobTemplate)
+	require.Nil(t, err)
+	assert.Equal(t, []string{"Description", "RepoURL", "Branch", "Label", "Items", "JobName"}, tmpl.Variables)
+	assert.Equal(t, []string{"Label"}, tmpl.Optional)
+
+	// A variable used both inside and outside of an if action is required.
+	tmpl, err = parseJobTemplate("label", "{{if .Label}}<label>{{.Label}}</label>{{end}}{{with .Owner}}{{.}}{{end}}<node>{{.Label}}</node>")
+	require.Nil(t, err)
+	assert.Equal(t, []string{"Owner"}, tmpl.Optional)
+
+	_, err = parseJobTemplate("broken", "<name>{{.Branch</name>")
+	assert.NotNil(t, err)
+
+	tmpl, err = parseJobTemplate("static", "<project/>")
+	require.Nil(t, err)
+	assert.Empty(t, tmpl.Variables)
+}
+
+func TestRenderJobTemplate(t *testing.T) {
+	tmpl, err := parseJobTemplate("branch", "<name>*/{{.Branch}}</name><url>{{.RepoURL}}</url>")
+	require.Nil(t, err)
+
+	configXML, err := renderJobTemplate(tmpl, map[string]string{"Branch": "feature/<x>&y", "RepoURL": "https://example.com/repo.git"})
+	require.Nil(t, err)
+	assert.Equal(t, "<name>*/feature/&lt;x&gt;&amp;y</name><url>https://example.com/repo.git</url>", configXML)
+
+	_, err = renderJobTemplate(tmpl, map[string]string{"Branch": "main"})
+	assert.NotNil(t, err)
+
+	tmpl, err = parseJobTemplate("label", "<name>{{.JobName}}</name>{{if .Label}}<assignedNode>{{.Label}}</assignedNode>{{end}}")
+	require.Nil(t, err)
+	configXML, err = renderJobTemplate(tmpl, map[string]string{"JobName": "app", "Label": ""})
+	require.Nil(t, err)
+	assert.Equal(t, "<name>app</name>", configXML)
+}
+
+func TestFormatJobTemplates(t *testing.T) {
+	assert.Equal(t, "No job templates have been added yet.", formatJobTemplates(map[string]*jobTemplate{}))
+	assert.Equal(t, "Job templates:\n* `maven` - variables: none\n* `pipeline` - variables: `RepoURL`, `Branch`, `Label` (optional)", formatJobTemplates(map[string]*jobTemplate{
+		"pipeline": {Name: "pipeline", Variables: []string{"RepoURL", "Branch", "Label"}, Optional: []string{"Label"}},
+		"maven":    {Name: "maven"},
+	}))
+}
+
+func TestParsePostReference(t *testing.T) {
+	for ref, expected := range map[string]string{
+		"qhmxmwx5ejbk8bz6fjqghiwbeo":                                      "qhmxmwx5ejbk8bz6fjqghiwbeo",
+		"https://chat.example.com/team/pl/qhmxmwx5ejbk8bz6fjqghiwbeo":     "qhmxmwx5ejbk8bz6fjqghiwbeo",
+		"https://chat.example.com/team/pl/qhmxmwx5ejbk8bz6fjqghiwbeo/":    "qhmxmwx5ejbk8bz6fjqghiwbeo",
+		"https://chat.example.com/team/pl/qhmxmwx5ejbk8bz6fjqghiwbeo?x=1": "qhmxmwx5ejbk8bz6fjqghiwbeo",
+	} {
+		postID, ok := parsePostReference(ref)
+		assert.True(t, ok, ref)
+		assert.Equal(t, expected, postID, ref)
+	}
+
+	for _, ref := range []string{"", "not-a-post", "https://chat.example.com/team/channels/town-square"} {
+		_, ok := parsePostReference(ref)
+		assert.False(t, ok, ref)
+	}
+}
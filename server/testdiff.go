@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/waseem18/gojenkins"
+)
+
+// maxTestsPerDiffSection is the number of tests listed in each section of the test-diff post.
+const maxTestsPerDiffSection = 15
+
+// testReportDiff holds the differences between the test reports of two builds.
+type testReportDiff struct {
+	NewlyFailing []testCase
+	Fixed        []testCase
+	StillFailing []testCase
+	Added        []testCase
+	Removed      []testCase
+}
+
+// diffTestReports compares the test report of a base build with the one of a newer build.
+// Test cases are matched by their full name.
+func diffTestReports(base, target *testReport) *testReportDiff {
+	baseCases := indexTestCases(base)
+	targetCases := indexTestCases(target)
+
+	diff := &testReportDiff{}
+	for name, c := range targetCases {
+		baseCase, ok := baseCases[name]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, c)
+		case c.isFailed() && baseCase.isFailed():
+			diff.StillFailing = append(diff.StillFailing, c)
+		case c.isFailed():
+			diff.NewlyFailing = append(diff.NewlyFailing, c)
+		case baseCase.isFailed():
+			diff.Fixed = append(diff.Fixed, c)
+		}
+	}
+	for name, c := range baseCases {
+		if _, ok := targetCases[name]; !ok {
+			diff.Removed = append(diff.Removed, c)
+		}
+	}
+
+	for _, cases := range [][]testCase{diff.NewlyFailing, diff.Fixed, diff.Added, diff.Removed} {
+		sortTestCases(cases)
+	}
+	// The tests failing for the longest time come first.
+	sort.SliceStable(diff.StillFailing, func(i, j int) bool {
+		if diff.StillFailing[i].Age != diff.StillFailing[j].Age {
+			return diff.StillFailing[i].Age > diff.StillFailing[j].Age
+		}
+		return diff.StillFailing[i].fullName() < diff.StillFailing[j].fullName()
+	})
+	return diff
+}
+
+func indexTestCases(report *testReport) map[string]testCase {
+	cases := make(map[string]testCase)
+	for _, s := range report.Suites {
+		for _, c := range s.Cases {
+			cases[c.fullName()] = c
+		}
+	}
+	return cases
+}
+
+func sortTestCases(cases []testCase) {
+	sort.Slice(cases, func(i, j int) bool { return cases[i].fullName() < cases[j].fullName() })
+}
+
+// formatTestReportDiff renders the differences between two builds as markdown.
+// Failing tests which are known to be flaky are annotated as such.
+func formatTestReportDiff(jobName string, baseNumber, targetNumber int64, diff *testReportDiff, flaky map[string]flakyTest) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Test changes in the job '%s' from build #%d to build #%d\n", jobName, baseNumber, targetNumber)
+
+	sections := []struct {
+		title string
+		cases []testCase
+		line  func(c testCase) string
+	}{
+		{":x: Newly failing", diff.NewlyFailing, func(c testCase) string {
+			if f, ok := flaky[c.fullName()]; ok {
+				return fmt.Sprintf("`%s` %s", c.fullName(), formatFlakyAnnotation(f))
+			}
+			return fmt.Sprintf("`%s`", c.fullName())
+		}},
+		{":white_check_mark: Fixed", diff.Fixed, func(c testCase) string {
+			return fmt.Sprintf("`%s`", c.fullName())
+		}},
+		{":warning: Still failing", diff.StillFailing, func(c testCase) string {
+			return fmt.Sprintf("`%s` - failing for %d build(s)", c.fullName(), c.Age)
+		}},
+		{":heavy_plus_sign: Added", diff.Added, func(c testCase) string {
+			return fmt.Sprintf("`%s` (%s)", c.fullName(), strings.ToLower(c.Status))
+		}},
+		{":heavy_minus_sign: Removed", diff.Removed, func(c testCase) string {
+			return fmt.Sprintf("`%s`", c.fullName())
+		}},
+	}
+
+	empty := true
+	for _, section := range sections {
+		if len(section.cases) == 0 {
+			continue
+		}
+		empty = false
+		fmt.Fprintf(&sb, "\n**%s (%d)**\n", section.title, len(section.cases))
+		for i, c := range section.cases {
+			if i == maxTestsPerDiffSection {
+				fmt.Fprintf(&sb, "* ...and %d more\n", len(section.cases)-maxTestsPerDiffSection)
+				break
+			}
+			fmt.Fprintf(&sb, "* %s\n", section.line(c))
+		}
+	}
+	if empty {
+		sb.WriteString("\nNo test changes between the two builds.\n")
+	}
+	return sb.String()
+}
+
+// parseJobAndBuilds parses a job name followed by up to maxBuilds build numbers.
+// The job name follows the same rules as in parseBuildParameters.
+func parseJobAndBuilds(parameters []string, maxBuilds int) (string, []string, bool) {
+	end := len(parameters)
+	for end > 1 && len(parameters)-end < maxBuilds {
+		if _, err := strconv.ParseInt(parameters[end-1], 10, 64); err != nil {
+			break
+		}
+		end--
+	}
+
+	jobName, extraParam, ok := parseBuildParameters(parameters[:end])
+	if !ok || extraParam != "" {
+		return "", nil, false
+	}
+	return jobName, parameters[end:], true
+}
+
+// getBuildOrDefault returns the build with the given number, or the build returned by fallback
+// if the number is an empty string.
+func getBuildOrDefault(job *gojenkins.Job, buildID string, fallback func() (*gojenkins.Build, error)) (*gojenkins.Build, error) {
+	if buildID == "" {
+		return fallback()
+	}
+	buildIDInt, err := strconv.ParseInt(buildID, 10, 64)
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid build number")
+	}
+	return job.GetBuild(buildIDInt)
+}
+
+// postTestReportDiff compares the test reports of two builds of the given job and creates a post with the differences.
+// The base build defaults to the last successful build and the target build to the last build.
+func (p *Plugin) postTestReportDiff(userID, channelID, jobName, baseBuildID, targetBuildID string) error {
+	job, jobErr := p.getJob(userID, jobName)
+	if jobErr != nil {
+		return jobErr
+	}
+
+	baseBuild, err := getBuildOrDefault(job, baseBuildID, job.GetLastSuccessfulBuild)
+	if err != nil {
+		return errors.Wrap(err, "Error fetching the base build")
+	}
+	targetBuild, err := getBuildOrDefault(job, targetBuildID, job.GetLastBuild)
+	if err != nil {
+		return errors.Wrap(err, "Error fetching the build to compare")
+	}
+
+	reports := []*testReport{}
+	for _, build := range []*gojenkins.Build{baseBuild, targetBuild} {
+		report, err := getTestReport(build)
+		if err != nil {
+			return err
+		}
+		if report == nil {
+			p.createPost(userID, channelID, fmt.Sprintf("Build #%d of the job '%s' doesn't have test reports.", build.GetBuildNumber(), jobName))
+			return nil
+		}
+		p.recordCompletedBuildReport(jobName, build, report)
+		reports = append(reports, report)
+	}
+
+	diff := diffTestReports(reports[0], reports[1])
+	msg := formatTestReportDiff(jobName, baseBuild.GetBuildNumber(), targetBuild.GetBuildNumber(), diff, p.getKnownFlakyTests(jobName))
+	// The errors of the failing tests may contain the secrets of either build.
+	for _, build := range []*gojenkins.Build{baseBuild, targetBuild} {
+		msg = p.getBuildRedactor(userID, jobName, build).redact(msg)
+	}
+	p.createPost(userID, channelID, msg)
+	return nil
+}
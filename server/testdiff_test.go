@@ -0,0 +1,100 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffTestReports(t *testing.T) {
+	base := &testReport{Suites: []testSuite{{Cases: []testCase{
+		{ClassName: "A", Name: "stillPassing", Status: "PASSED"},
+		{ClassName: "A", Name: "breaks", Status: "PASSED"},
+		{ClassName: "A", Name: "fixed", Status: "FAILED"},
+		{ClassName: "A", Name: "stillFailing", Status: "REGRESSION", Age: 1},
+		{ClassName: "A", Name: "oldFailure", Status: "FAILED", Age: 4},
+		{ClassName: "A", Name: "removed", Status: "PASSED"},
+	}}}}
+	target := &testReport{Suites: []testSuite{{Cases: []testCase{
+		{ClassName: "A", Name: "stillPassing", Status: "PASSED"},
+		{ClassName: "A", Name: "breaks", Status: "REGRESSION", Age: 1},
+		{ClassName: "A", Name: "fixed", Status: "FIXED"},
+		{ClassName: "A", Name: "stillFailing", Status: "FAILED", Age: 2},
+		{ClassName: "A", Name: "oldFailure", Status: "FAILED", Age: 5},
+		{ClassName: "A", Name: "added", Status: "PASSED"},
+	}}}}
+
+	names := func(cases []testCase) []string {
+		result := []string{}
+		for _, c := range cases {
+			result = append(result, c.Name)
+		}
+		return result
+	}
+
+	diff := diffTestReports(base, target)
+	assert.Equal(t, []string{"breaks"}, names(diff.NewlyFailing))
+	assert.Equal(t, []string{"fixed"}, names(diff.Fixed))
+	assert.Equal(t, []string{"oldFailure", "stillFailing"}, names(diff.StillFailing))
+	assert.Equal(t, []string{"added"}, names(diff.Added))
+	assert.Equal(t, []string{"removed"}, names(diff.Removed))
+
+	msg := formatTestReportDiff("job1", 10, 11, diff, nil)
+	assert.Contains(t, msg, "Test changes in the job 'job1' from build #10 to build #11")
+	assert.Contains(t, msg, "**:x: Newly failing (1)**\n* `A.breaks`\n")
+	assert.Contains(t, msg, "**:warning: Still failing (2)**\n* `A.oldFailure` - failing for 5 build(s)\n* `A.stillFailing` - failing for 2 build(s)\n")
+	assert.Contains(t, msg, "* `A.added` (passed)\n")
+
+	passing := &testReport{Suites: []testSuite{{Cases: []testCase{{ClassName: "A", Name: "stillPassing", Status: "PASSED"}}}}}
+	msg = formatTestReportDiff("job1", 10, 11, diffTestReports(passing, passing), nil)
+	assert.Contains(t, msg, "No test changes between the two builds.")
+}
+
+func TestParseJobAndBuilds(t *testing.T) {
+	for name, tc := range map[string]struct {
+		Input          []string
+		ExpectedJob    string
+		ExpectedBuilds []string
+		Valid          bool
+	}{
+		"job only": {
+			Input:          []string{"folder/jobname"},
+			ExpectedJob:    "folder/jobname",
+			ExpectedBuilds: []string{},
+			Valid:          true,
+		},
+		"two builds": {
+			Input:          []string{"jobname", "10", "12"},
+			ExpectedJob:    "jobname",
+			ExpectedBuilds: []string{"10", "12"},
+			Valid:          true,
+		},
+		"quoted job with builds": {
+			Input:          []string{`"job`, `with`, `spaces"`, "10", "12"},
+			ExpectedJob:    "job with spaces",
+			ExpectedBuilds: []string{"10", "12"},
+			Valid:          true,
+		},
+		"numeric job name": {
+			Input:          []string{"1234"},
+			ExpectedJob:    "1234",
+			ExpectedBuilds: []string{},
+			Valid:          true,
+		},
+		"too many builds": {
+			Input: []string{"jobname", "10", "11", "12"},
+		},
+		"extra text": {
+			Input: []string{"jobname", "extra", "10"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			job, builds, ok := parseJobAndBuilds(tc.Input, 2)
+			assert.Equal(t, tc.Valid, ok)
+			if tc.Valid {
+				assert.Equal(t, tc.ExpectedJob, job)
+				assert.Equal(t, tc.ExpectedBuilds, builds)
+			}
+		})
+	}
+}
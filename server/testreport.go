@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/waseem18/gojenkins"
+)
+
+const (
+	// maxFailingTestsInPost is the number of failing test cases detailed in the summary post.
+	// The full list is attached as a file when there are more failures.
+	maxFailingTestsInPost   = 5
+	maxErrorDetailsLength   = 300
+	maxStackTraceLines      = 8
+	testReportTreeQuery     = "duration,failCount,passCount,skipCount,suites[name,cases[className,name,status,age,duration,errorDetails,errorStackTrace]]"
+	testCaseStatusFailed    = "FAILED"
+	testCaseStatusRegressed = "REGRESSION"
+	testCaseStatusSkipped   = "SKIPPED"
+)
+
+// testReport is the JUnit test report of a build as returned by testReport/api/json.
+type testReport struct {
+	Duration  float64     `json:"duration"`
+	FailCount int         `json:"failCount"`
+	PassCount int         `json:"passCount"`
+	SkipCount int         `json:"skipCount"`
+	Suites    []testSuite `json:"suites"`
+}
+
+type testSuite struct {
+	Name  string     `json:"name"`
+	Cases []testCase `json:"cases"`
+}
+
+type testCase struct {
+	ClassName       string  `json:"className"`
+	Name            string  `json:"name"`
+	Status          string  `json:"status"`
+	Age             int     `json:"age"`
+	Duration        float64 `json:"duration"`
+	ErrorDetails    string  `json:"errorDetails"`
+	ErrorStackTrace string  `json:"errorStackTrace"`
+}
+
+// fullName returns the name of the test case prefixed with its class name.
+func (c *testCase) fullName() string {
+	if c.ClassName == "" {
+		return c.Name
+	}
+	return c.ClassName + "." + c.Name
+}
+
+func (c *testCase) isFailed() bool {
+	return c.Status == testCaseStatusFailed || c.Status == testCaseStatusRegressed
+}
+
+// failedCases returns the failing test cases of the report in the order of the report.
+func (r *testReport) failedCases() []testCase {
+	failed := []testCase{}
+	for _, s := range r.Suites {
+		for _, c := range s.Cases {
+			if c.isFailed() {
+				failed = append(failed, c)
+			}
+		}
+	}
+	return failed
+}
+
+// getTestReport fetches the JUnit test report of a build.
+// Returns nil without an error if the build has no test report.
+func getTestReport(build *gojenkins.Build) (*testReport, error) {
+	return fetchTestReport(build.Jenkins, build.Base)
+}
+
+// fetchTestReport fetches the JUnit test report of the build at the given base path.
+// Returns nil without an error if the build has no test report.
+func fetchTestReport(jenkins *gojenkins.Jenkins, buildBase string) (*testReport, error) {
+	var report testReport
+	resp, err := jenkins.Requester.GetJSON(buildBase+"/testReport", &report, map[string]string{"tree": testReportTreeQuery})
+	if err != nil {
+		return nil, errors.Wrap(err, "Error fetching test report")
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("Error fetching test report: %s", resp.Status)
+	}
+	return &report, nil
+}
+
+// formatTestReportSummary renders the counts of the report and details of the first failing test cases.
+// Failing tests which are known to be flaky are annotated as such.
+func formatTestReportSummary(jobName string, buildNumber int64, reportURL string, report *testReport, flaky map[string]flakyTest) string {
+	var sb strings.Builder
+	icon := ":white_check_mark:"
+	if report.FailCount > 0 {
+		icon = ":x:"
+	}
+	fmt.Fprintf(&sb, "%s Test results of the build #%d of the job '%s': %d failed, %d passed, %d skipped in %s\n",
+		icon, buildNumber, jobName, report.FailCount, report.PassCount, report.SkipCount,
+		formatDuration(time.Duration(report.Duration*float64(time.Second))))
+	fmt.Fprintf(&sb, "Full report: %s\n", reportURL)
+
+	failed := report.failedCases()
+	if len(failed) == 0 {
+		return sb.String()
+	}
+
+	shown := failed
+	if len(shown) > maxFailingTestsInPost {
+		shown = shown[:maxFailingTestsInPost]
+		fmt.Fprintf(&sb, "\n**Failing tests (first %d of %d)**\n", len(shown), len(failed))
+	} else {
+		sb.WriteString("\n**Failing tests**\n")
+	}
+	for i, c := range shown {
+		fmt.Fprintf(&sb, "%d. `%s`", i+1, c.fullName())
+		if c.ErrorDetails != "" {
+			fmt.Fprintf(&sb, ": %s", truncateString(strings.Join(strings.Fields(c.ErrorDetails), " "), maxErrorDetailsLength))
+		}
+		if f, ok := flaky[c.fullName()]; ok {
+			fmt.Fprintf(&sb, " %s", formatFlakyAnnotation(f))
+		}
+		sb.WriteString("\n")
+		if excerpt := stackTraceExcerpt(c.ErrorStackTrace, maxStackTraceLines); excerpt != "" {
+			fmt.Fprintf(&sb, "```\n%s\n```\n", excerpt)
+		}
+	}
+	return sb.String()
+}
+
+// formatFailingTests renders the complete list of failing test cases as plain text.
+func formatFailingTests(failed []testCase) string {
+	var sb strings.Builder
+	for _, c := range failed {
+		fmt.Fprintf(&sb, "%s [%s]\n", c.fullName(), c.Status)
+		if c.ErrorDetails != "" {
+			fmt.Fprintf(&sb, "%s\n", c.ErrorDetails)
+		}
+		if c.ErrorStackTrace != "" {
+			fmt.Fprintf(&sb, "%s\n", strings.TrimRight(c.ErrorStackTrace, "\n"))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// stackTraceExcerpt returns the first lines of a stack trace.
+func stackTraceExcerpt(stackTrace string, maxLines int) string {
+	lines := strings.Split(strings.TrimSpace(stackTrace), "\n")
+	if len(lines) > maxLines {
+		lines = append(lines[:maxLines], "\t...")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func truncateString(s string, maxLength int) string {
+	runes := []rune(s)
+	if len(runes) <= maxLength {
+		return s
+	}
+	return string(runes[:maxLength]) + "…"
+}
+
+// postBuildTestResults checks if the specified job and build has test results and
+// creates a post summarizing the test report. The full list of failing tests is attached
+// as a file when it doesn't fit in the post.
+// If the report can't be read, the post falls back to the URL of the test report.
+// If build number is not specified, the method checks the last build of the job for test results.
+func (p *Plugin) postBuildTestResults(userID, channelID, jobName, buildID string) error {
+	build, buildErr := p.getBuild(jobName, userID, buildID)
+	if buildErr != nil {
+		return buildErr
+	}
+
+	testReportsURL := build.GetUrl() + "testReport"
+	report, err := getTestReport(build)
+	if err != nil {
+		p.API.LogWarn("Error reading test report, falling back to the report URL", "job_name", jobName, "err", err.Error())
+		p.createPost(userID, channelID, fmt.Sprintf("Test reports for the build #%d of the job '%s': %s", build.GetBuildNumber(), jobName, testReportsURL))
+		return nil
+	}
+	if report == nil {
+		p.createPost(userID, channelID, fmt.Sprintf("Build #%d of the job '%s' doesn't have test reports.", build.GetBuildNumber(), jobName))
+		return nil
+	}
+
+	p.recordCompletedBuildReport(jobName, build, report)
+	// Error details and stack traces are log excerpts, which may contain secrets.
+	redactor := p.getBuildRedactor(userID, jobName, build)
+	msg := redactor.redact(formatTestReportSummary(jobName, build.GetBuildNumber(), testReportsURL, report, p.getKnownFlakyTests(jobName)))
+	failed := report.failedCases()
+	if len(failed) <= maxFailingTestsInPost {
+		p.createPost(userID, channelID, msg)
+		return nil
+	}
+
+	filename := fmt.Sprintf("%s-%d-failing-tests.txt", jobName, build.GetBuildNumber())
+	fileInfo, fileUploadErr := p.API.UploadFile([]byte(redactor.redact(formatFailingTests(failed))), channelID, filename)
+	if fileUploadErr != nil {
+		return errors.Wrap(fileUploadErr, "Error uploading file")
+	}
+	p.createPost(userID, channelID, msg, fileInfo.Id)
+	return nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestReport(t *testing.T, failures int) *testReport {
+	report := &testReport{Duration: 63.5, PassCount: 10, SkipCount: 1, FailCount: failures}
+	suite := testSuite{Name: "suite"}
+	suite.Cases = append(suite.Cases, testCase{ClassName: "pkg.PassingTest", Name: "testOk", Status: "PASSED"})
+	for i := 0; i < failures; i++ {
+		suite.Cases = append(suite.Cases, testCase{
+			ClassName:       "pkg.FailingTest",
+			Name:            fmt.Sprintf("test%d", i),
+			Status:          "FAILED",
+			ErrorDetails:    "expected:<1>\nbut was:<2>",
+			ErrorStackTrace: "java.lang.AssertionError\n\tat a\n\tat b\n\tat c\n\tat d\n\tat e\n\tat f\n\tat g\n\tat h\n\tat i",
+		})
+	}
+	report.Suites = append(report.Suites, suite)
+
+	// Round trip through JSON to make sure the tags match the Jenkins API.
+	data, err := json.Marshal(report)
+	require.Nil(t, err)
+	var decoded testReport
+	require.Nil(t, json.Unmarshal(data, &decoded))
+	return &decoded
+}
+
+func TestFormatTestReportSummary(t *testing.T) {
+	t.Run("all passing", func(t *testing.T) {
+		msg := formatTestReportSummary("job1", 12, "http://jenkins/job/job1/12/testReport", newTestReport(t, 0), nil)
+		assert.Equal(t, ":white_check_mark: Test results of the build #12 of the job 'job1': 0 failed, 10 passed, 1 skipped in 1m 4s\nFull report: http://jenkins/job/job1/12/testReport\n", msg)
+	})
+
+	t.Run("few failures", func(t *testing.T) {
+		msg := formatTestReportSummary("job1", 12, "url", newTestReport(t, 2), nil)
+		assert.Contains(t, msg, ":x: Test results of the build #12 of the job 'job1': 2 failed")
+		assert.Contains(t, msg, "**Failing tests**\n1. `pkg.FailingTest.test0`: expected:<1> but was:<2>\n```\njava.lang.AssertionError")
+		assert.Contains(t, msg, "2. `pkg.FailingTest.test1`")
+		assert.Contains(t, msg, "\tat g\n\t...\n```")
+		assert.NotContains(t, msg, "at h")
+	})
+
+	t.Run("many failures", func(t *testing.T) {
+		msg := formatTestReportSummary("job1", 12, "url", newTestReport(t, 8), nil)
+		assert.Contains(t, msg, "**Failing tests (first 5 of 8)**")
+		assert.Contains(t, msg, "5. `pkg.FailingTest.test4`")
+		assert.NotContains(t, msg, "test5")
+	})
+}
+
+func TestFormatFailingTests(t *testing.T) {
+	report := newTestReport(t, 8)
+	text := formatFailingTests(report.failedCases())
+	assert.Contains(t, text, "pkg.FailingTest.test7 [FAILED]\nexpected:<1>\nbut was:<2>\njava.lang.AssertionError\n")
+	assert.Contains(t, text, "\tat i\n")
+}
+
+func TestTruncateString(t *testing.T) {
+	assert.Equal(t, "short", truncateString("short", 10))
+	assert.Equal(t, "lon…", truncateString("longer", 3))
+}
@@ -10,9 +10,13 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"errors"
+	"fmt"
 	"io"
+	"path"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/mattermost/mattermost/server/public/model"
 )
@@ -108,6 +112,171 @@ func parseBuildParameters(parameters []string) (string, string, bool) {
 	return strings.TrimLeft(strings.TrimRight(submatches[0][1], `\"`), `\"`), submatches[0][2], true
 }
 
+// parseQuotedArgument splits the parameters of a slash command into the first argument and the rest.
+// The first argument, e.g. a job name or a node name, can be wrapped in double quotes if it has spaces in it.
+func parseQuotedArgument(parameters []string) (string, string, bool) {
+	if len(parameters) == 0 {
+		return "", "", false
+	}
+	if !strings.HasPrefix(parameters[0], `"`) {
+		return parameters[0], strings.Join(parameters[1:], " "), true
+	}
+	for i, param := range parameters {
+		if strings.HasSuffix(param, `"`) && (i > 0 || len(param) > 1) {
+			arg := strings.Trim(strings.Join(parameters[:i+1], " "), `"`)
+			return arg, strings.Join(parameters[i+1:], " "), arg != ""
+		}
+	}
+	return "", "", false
+}
+
+// parseFlags splits the parameters of a slash command into positional arguments and flags.
+// Flags are given as |--name value| or |--name=value|; flags listed in boolFlags don't take a value.
+// A flag value wrapped in double quotes may contain spaces.
+// An error is returned for flags which are neither in valueFlags nor in boolFlags.
+func parseFlags(parameters, valueFlags, boolFlags []string) ([]string, map[string]string, error) {
+	positional := []string{}
+	flags := make(map[string]string)
+	for i := 0; i < len(parameters); i++ {
+		param := parameters[i]
+		if !strings.HasPrefix(param, "--") {
+			positional = append(positional, param)
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimPrefix(param, "--"), "=")
+		switch {
+		case containsString(boolFlags, name):
+			if hasValue {
+				return nil, nil, fmt.Errorf("flag --%s doesn't take a value", name)
+			}
+			flags[name] = "true"
+			continue
+		case !containsString(valueFlags, name):
+			return nil, nil, fmt.Errorf("unknown flag --%s", name)
+		}
+
+		if !hasValue {
+			if i+1 >= len(parameters) {
+				return nil, nil, fmt.Errorf("flag --%s requires a value", name)
+			}
+			i++
+			value = parameters[i]
+		}
+		if strings.HasPrefix(value, "\"") {
+			for (len(value) == 1 || !strings.HasSuffix(value, "\"")) && i+1 < len(parameters) {
+				i++
+				value += " " + parameters[i]
+			}
+			value = strings.TrimSuffix(strings.TrimPrefix(value, "\""), "\"")
+		}
+		flags[name] = value
+	}
+	return positional, flags, nil
+}
+
+func containsString(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSinceDuration parses durations like 30m, 12h, 2d or 1w.
+// Units understood by time.ParseDuration are accepted as well.
+func parseSinceDuration(value string) (time.Duration, error) {
+	units := map[string]time.Duration{
+		"d": 24 * time.Hour,
+		"w": 7 * 24 * time.Hour,
+	}
+	for suffix, unit := range units {
+		if strings.HasSuffix(value, suffix) {
+			n, err := strconv.Atoi(strings.TrimSuffix(value, suffix))
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("invalid duration %q", value)
+			}
+			return time.Duration(n) * unit, nil
+		}
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return d, nil
+}
+
+// formatDuration formats a duration as a short human readable string such as 1h 2m, 3m 4s or 5s.
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	hours := int64(d / time.Hour)
+	minutes := int64(d/time.Minute) % 60
+	seconds := int64(d/time.Second) % 60
+	switch {
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	case minutes > 0:
+		return fmt.Sprintf("%dm %ds", minutes, seconds)
+	default:
+		return fmt.Sprintf("%ds", seconds)
+	}
+}
+
+// matchJobGlob reports whether the full name of a job, with folders separated by slashes,
+// matches the glob pattern. As in path.Match, * doesn't match the slashes between folders,
+// while a ** segment matches any number of nested folders.
+func matchJobGlob(pattern, jobName string) bool {
+	if !strings.Contains(pattern, "**") {
+		matched, err := path.Match(pattern, jobName)
+		return err == nil && matched
+	}
+	return matchGlobSegments(strings.Split(pattern, "/"), strings.Split(jobName, "/"))
+}
+
+func matchGlobSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchGlobSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	matched, err := path.Match(pattern[0], segments[0])
+	return err == nil && matched && matchGlobSegments(pattern[1:], segments[1:])
+}
+
+// ansiEscapePattern matches ANSI escape sequences, such as the color codes printed by build tools.
+var ansiEscapePattern = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b[@-Z\\-_]`)
+
+// stripANSI removes ANSI escape sequences from text.
+func stripANSI(text string) string {
+	return ansiEscapePattern.ReplaceAllString(text, "")
+}
+
+// recoverJenkinsPanic turns a panic of a gojenkins request into an error. gojenkins dereferences a nil
+// response when Jenkins is unreachable while it fetches the CSRF crumb of a POST request.
+// It has to be deferred by functions with a named error result.
+func recoverJenkinsPanic(err *error) {
+	if r := recover(); r != nil {
+		*err = fmt.Errorf("error sending the request to Jenkins: %v", r)
+	}
+}
+
+// escapeTableCell makes a value safe to use inside a cell of a markdown table.
+func escapeTableCell(value string) string {
+	value = strings.ReplaceAll(value, "|", "\\|")
+	return strings.Join(strings.Fields(value), " ")
+}
+
 func generateSlackAttachment(text string) *model.SlackAttachment {
 	slackAttachment := &model.SlackAttachment{
 		Text:  text,
@@ -2,6 +2,7 @@ package main
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -82,3 +83,133 @@ func TestParseBuildParameters(t *testing.T) {
 		})
 	}
 }
+
+func TestParseQuotedArgument(t *testing.T) {
+	for _, test := range []struct {
+		parameters []string
+		name       string
+		rest       string
+		ok         bool
+	}{
+		{[]string{"linux-1", "disk", "full"}, "linux-1", "disk full", true},
+		{[]string{"linux-1"}, "linux-1", "", true},
+		{[]string{`"my`, `node"`, "maintenance"}, "my node", "maintenance", true},
+		{[]string{`"node"`}, "node", "", true},
+		{[]string{`"my`, "node"}, "", "", false},
+		{[]string{}, "", "", false},
+	} {
+		name, rest, ok := parseQuotedArgument(test.parameters)
+		assert.Equal(t, test.ok, ok)
+		assert.Equal(t, test.name, name)
+		assert.Equal(t, test.rest, rest)
+	}
+}
+
+func TestParseFlags(t *testing.T) {
+	valueFlags := []string{"last", "grep"}
+	boolFlags := []string{"dry-run"}
+
+	for name, tc := range map[string]struct {
+		Input              []string
+		ExpectedPositional []string
+		ExpectedFlags      map[string]string
+		ExpectError        bool
+	}{
+		"no flags": {
+			Input:              []string{"folder/jobname", "22"},
+			ExpectedPositional: []string{"folder/jobname", "22"},
+			ExpectedFlags:      map[string]string{},
+		},
+		"value flag": {
+			Input:              []string{"jobname", "--last", "5"},
+			ExpectedPositional: []string{"jobname"},
+			ExpectedFlags:      map[string]string{"last": "5"},
+		},
+		"value flag with equals sign": {
+			Input:              []string{"--last=5", "jobname"},
+			ExpectedPositional: []string{"jobname"},
+			ExpectedFlags:      map[string]string{"last": "5"},
+		},
+		"quoted value with spaces": {
+			Input:              []string{`"job`, `name"`, "--grep", `"some`, "error", `text"`, "--dry-run"},
+			ExpectedPositional: []string{`"job`, `name"`},
+			ExpectedFlags:      map[string]string{"grep": "some error text", "dry-run": "true"},
+		},
+		"bool flag": {
+			Input:              []string{"--dry-run", "jobname"},
+			ExpectedPositional: []string{"jobname"},
+			ExpectedFlags:      map[string]string{"dry-run": "true"},
+		},
+		"missing value": {
+			Input:       []string{"jobname", "--last"},
+			ExpectError: true,
+		},
+		"unknown flag": {
+			Input:       []string{"jobname", "--unknown", "1"},
+			ExpectError: true,
+		},
+		"bool flag with value": {
+			Input:       []string{"jobname", "--dry-run=false"},
+			ExpectError: true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			positional, flags, err := parseFlags(tc.Input, valueFlags, boolFlags)
+			if tc.ExpectError {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.ExpectedPositional, positional)
+			assert.Equal(t, tc.ExpectedFlags, flags)
+		})
+	}
+}
+
+func TestParseSinceDuration(t *testing.T) {
+	for input, expected := range map[string]time.Duration{
+		"2d":  48 * time.Hour,
+		"1w":  7 * 24 * time.Hour,
+		"12h": 12 * time.Hour,
+		"90m": 90 * time.Minute,
+	} {
+		d, err := parseSinceDuration(input)
+		assert.Nil(t, err, input)
+		assert.Equal(t, expected, d, input)
+	}
+
+	for _, input := range []string{"", "d", "-1d", "two days", "-5m"} {
+		_, err := parseSinceDuration(input)
+		assert.NotNil(t, err, input)
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	assert.Equal(t, "0s", formatDuration(0))
+	assert.Equal(t, "42s", formatDuration(42*time.Second))
+	assert.Equal(t, "3m 5s", formatDuration(3*time.Minute+5*time.Second))
+	assert.Equal(t, "2h 1m", formatDuration(2*time.Hour+time.Minute+30*time.Second))
+}
+
+func TestMatchJobGlob(t *testing.T) {
+	for _, test := range []struct {
+		pattern, jobName string
+		expected         bool
+	}{
+		{"*", "job", true},
+		{"*", "folder/job", false},
+		{"folder/*", "folder/job", true},
+		{"folder/*", "folder/sub/job", false},
+		{"folder/**", "folder/sub/job", true},
+		{"folder/**", "folder/job", true},
+		{"folder/**", "other/job", false},
+		{"**/deploy-*", "deploy-prod", true},
+		{"**/deploy-*", "team/services/deploy-prod", true},
+		{"**/deploy-*", "team/services/build", false},
+		{"a/**/b", "a/b", true},
+		{"a/**/b", "a/x/y/b", true},
+		{"[", "job", false},
+	} {
+		assert.Equal(t, test.expected, matchJobGlob(test.pattern, test.jobName), "%s %s", test.pattern, test.jobName)
+	}
+}